@@ -22,6 +22,32 @@ type EditClient interface {
 	Edit() ([]string, error)
 }
 
+// ChatClient Describes an AI client capable of holding a conversation.
+type ChatClient interface {
+	// Chat Returns the model's reply to the conversation so far.
+	Chat() (string, error)
+}
+
+// StreamingChatClient Describes a chat client which can send its reply as it is generated.
+type StreamingChatClient interface {
+	ChatClient
+	// ChatStream Calls send with every part of the reply as it arrives, and returns the whole reply.
+	ChatStream(send func(text string) error) (string, error)
+}
+
+// Message Is a single message in a conversation with a chat model.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Define the roles that a message in a conversation can have.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
 // Backend Defines a type specifically for backends.
 type Backend string
 
@@ -34,6 +60,8 @@ const (
 	BLOOM Backend = "bloom"
 	// OPT Declares the OPT-175B AI Backend, created by Meta.
 	OPT Backend = "opt"
+	// OLLAMA Declares a locally-running, Ollama-compatible server hosting open models.
+	OLLAMA Backend = "ollama"
 	// Unselected Represents an empty AI backend type.
 	Unselected Backend = ""
 )
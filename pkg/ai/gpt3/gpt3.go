@@ -2,7 +2,10 @@ package gpt3
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/redhat-et/copilot-ops/pkg/ai"
 	gogpt "github.com/sashabaranov/go-openai"
@@ -17,6 +20,7 @@ const (
 	OpenAIEndpointV1        string = "/v1"
 	OpenAICodeDavinciEditV1 string = "code-davinci-edit-001"
 	OpenAICodeDavinciV2     string = "code-davinci-002"
+	OpenAIGPT35Turbo        string = gogpt.GPT3Dot5Turbo
 	CompletionEndOfSequence string = "EOF"
 )
 
@@ -31,9 +35,12 @@ type EditParams struct {
 // gpt3Client Is a wrapper struct around the go-openai
 // package.
 type gpt3Client struct {
+	// ctx Bounds the requests made by the client.
+	ctx              context.Context
 	client           gogpt.Client
 	editParams       *gogpt.EditsRequest
 	completionParams *gogpt.CompletionRequest
+	chatParams       *gogpt.ChatCompletionRequest
 }
 
 // Config Defines the values required for connecting to the GPT-3 API.
@@ -44,7 +51,7 @@ type Config struct {
 	// OrgID Is an optional value which is set by users to dictate billing information.
 	OrgID *string `json:"orgID,omitempty" yaml:"orgID,omitempty"`
 	// BaseURL Defines where the client will reach out to contact the API.
-	BaseURL string `json:"url" yaml:"url"`
+	BaseURL string `json:"url" yaml:"url" mapstructure:"url"`
 }
 
 // Generate Reaches out to the OpenAI GPT-3 Completions API and returns
@@ -54,7 +61,7 @@ func (c gpt3Client) Generate() ([]string, error) {
 		return nil, fmt.Errorf("no completions params were provided")
 	}
 	// make request
-	resp, err := c.client.CreateCompletion(context.TODO(), *c.completionParams)
+	resp, err := c.client.CreateCompletion(c.ctx, *c.completionParams)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +81,7 @@ func (c gpt3Client) Edit() ([]string, error) {
 		return nil, fmt.Errorf("no edit params were provided")
 	}
 	// editParams, ok := params.(EditParams)
-	resp, err := c.client.Edits(context.Background(), *c.editParams)
+	resp, err := c.client.Edits(c.ctx, *c.editParams)
 	if err != nil {
 		return nil, fmt.Errorf("could not request openai: %w", err)
 	}
@@ -85,9 +92,56 @@ func (c gpt3Client) Edit() ([]string, error) {
 	return edits, nil
 }
 
+// Chat Reaches out to the OpenAI Chat Completions API and returns
+// the model's reply to the conversation.
+func (c gpt3Client) Chat() (string, error) {
+	if c.chatParams == nil {
+		return "", fmt.Errorf("no chat params were provided")
+	}
+	resp, err := c.client.CreateChatCompletion(c.ctx, *c.chatParams)
+	if err != nil {
+		return "", fmt.Errorf("could not create chat completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from API")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// ChatStream Reaches out to the OpenAI Chat Completions API, calling send with
+// every part of the model's reply as it is streamed back.
+func (c gpt3Client) ChatStream(send func(text string) error) (string, error) {
+	if c.chatParams == nil {
+		return "", fmt.Errorf("no chat params were provided")
+	}
+	stream, err := c.client.CreateChatCompletionStream(c.ctx, *c.chatParams)
+	if err != nil {
+		return "", fmt.Errorf("could not create chat completion: %w", err)
+	}
+	defer stream.Close()
+
+	var reply strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return reply.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not receive chat completion: %w", err)
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		reply.WriteString(resp.Choices[0].Delta.Content)
+		if err = send(resp.Choices[0].Delta.Content); err != nil {
+			return "", err
+		}
+	}
+}
+
 // CreateGPT3GenerateClient Returns a GPT-3 client which accesses OpenAI's
-// GPT-3 endpoint to generate completions.
-func CreateGPT3GenerateClient(conf Config, prompt string, maxTokens, nCompletions int) ai.GenerateClient {
+// GPT-3 endpoint to generate completions. Its requests are canceled when ctx is done.
+func CreateGPT3GenerateClient(ctx context.Context, conf Config, prompt string, maxTokens, nCompletions int) ai.GenerateClient {
 	// create a GPT-3 Client
 	client := createGPT3Client(conf)
 	// create params for getting a completion
@@ -101,13 +155,16 @@ func CreateGPT3GenerateClient(conf Config, prompt string, maxTokens, nCompletion
 	}
 
 	return gpt3Client{
+		ctx:              ctx,
 		client:           *client,
 		completionParams: params,
 	}
 }
 
 // CreateGPT3EditClient Returns a client based on GPT-3 capable of performing edits.
+// Its requests are canceled when ctx is done.
 func CreateGPT3EditClient(
+	ctx context.Context,
 	conf Config,
 	input, instruction string,
 	numEdits int, temperature,
@@ -130,19 +187,46 @@ func CreateGPT3EditClient(
 	}
 
 	return gpt3Client{
+		ctx:        ctx,
 		client:     *client,
 		editParams: editParams,
 	}
 }
 
+// CreateGPT3ChatClient Returns a client which continues the given conversation
+// using OpenAI's chat models. An empty model selects gpt-3.5-turbo. Its requests
+// are canceled when ctx is done.
+func CreateGPT3ChatClient(ctx context.Context, conf Config, model string, messages []ai.Message) ai.ChatClient {
+	client := createGPT3Client(conf)
+	if model == "" {
+		model = OpenAIGPT35Turbo
+	}
+	chatMessages := make([]gogpt.ChatCompletionMessage, len(messages))
+	for i, message := range messages {
+		chatMessages[i] = gogpt.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: message.Content,
+		}
+	}
+
+	return gpt3Client{
+		ctx:    ctx,
+		client: *client,
+		chatParams: &gogpt.ChatCompletionRequest{
+			Model:    model,
+			Messages: chatMessages,
+		},
+	}
+}
+
 // createGPT3Client Returns a go-gpt client using the provided config.
 func createGPT3Client(conf Config) *gogpt.Client {
-	orgID := ""
+	clientConfig := gogpt.DefaultConfig(conf.APIKey)
+	if conf.BaseURL != "" {
+		clientConfig.BaseURL = conf.BaseURL
+	}
 	if conf.OrgID != nil {
-		orgID = *conf.OrgID
+		clientConfig.OrgID = *conf.OrgID
 	}
-	return gogpt.NewClientWithConfig(gogpt.ClientConfig{
-		BaseURL: conf.BaseURL,
-		OrgID:   orgID,
-	})
+	return gogpt.NewClientWithConfig(clientConfig)
 }
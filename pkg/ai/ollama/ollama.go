@@ -0,0 +1,379 @@
+// ollama implements a client for locally-running Ollama-compatible servers,
+// allowing copilot-ops to be used in air-gapped environments.
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/utils"
+)
+
+const (
+	GenerateEndpoint string = "/api/generate"
+	ChatEndpoint     string = "/api/chat"
+	// OllamaURL Is the address an Ollama server listens on by default.
+	OllamaURL string = "http://localhost:11434"
+	// DefaultModel Is the model used when none has been configured.
+	DefaultModel            string = "codellama"
+	CompletionEndOfSequence string = "EOF"
+	// OptionNumPredict Is the Ollama option which limits the number of generated tokens.
+	OptionNumPredict string = "num_predict"
+	// OptionStop Is the Ollama option which defines the stop sequences.
+	OptionStop string = "stop"
+	// DefaultTimeout Is how long a request may take when no timeout has been configured,
+	// which leaves a local model enough time to load and answer.
+	DefaultTimeout = 10 * time.Minute
+)
+
+// Define the messages which steer the model when it is used through the chat endpoint.
+const (
+	GenerateSystemPrompt = "You are a tool which writes Kubernetes YAML and other configuration files. " +
+		"Complete the document you are given, responding only with the contents of the new file(s), " +
+		"followed by '" + CompletionEndOfSequence + "'."
+	EditSystemPrompt = "You are a tool which edits Kubernetes YAML and other configuration files. " +
+		"Apply the instruction to the input and respond only with the full, edited input without any commentary."
+)
+
+// Config Defines the values required for connecting to an Ollama server.
+type Config struct {
+	// BaseURL Defines where the client will reach out to contact the API.
+	BaseURL string `json:"url" yaml:"url" mapstructure:"url"`
+	// Model Is the name of the local model which should serve the requests, e.g. codellama.
+	Model string `json:"model" yaml:"model"`
+	// KeepAlive Controls how long the model stays loaded in memory after a request, e.g. 5m.
+	KeepAlive string `json:"keepAlive,omitempty" yaml:"keepAlive,omitempty"`
+	// Timeout Is how long a request may take before it is abandoned, e.g. 2m, and how long a streamed
+	// reply may pause between two of its parts. Defaults to DefaultTimeout.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Options Are passed through to the model unchanged, e.g. temperature or num_ctx.
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Message Is a single message exchanged through the chat endpoint.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// GenerateRequest Is the body sent to the generate endpoint.
+type GenerateRequest struct {
+	Model     string                 `json:"model"`
+	Prompt    string                 `json:"prompt"`
+	System    string                 `json:"system,omitempty"`
+	Stream    bool                   `json:"stream"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+}
+
+// GenerateResponse Is the body returned by the generate endpoint when streaming is disabled.
+type GenerateResponse struct {
+	Model    string `json:"model"`
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+}
+
+// ChatRequest Is the body sent to the chat endpoint.
+type ChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []Message              `json:"messages"`
+	Stream    bool                   `json:"stream"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+}
+
+// ChatResponse Is the body returned by the chat endpoint when streaming is disabled,
+// and every line of it when streaming is enabled.
+type ChatResponse struct {
+	Model   string  `json:"model"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+}
+
+// ollamaClient Is a thin client for the Ollama REST API.
+type ollamaClient struct {
+	// ctx Bounds the requests made by the client.
+	ctx            context.Context
+	conf           Config
+	client         *http.Client
+	generateParams *GenerateRequest
+	chatParams     *ChatRequest
+	n              int
+}
+
+// Generate Reaches out to the Ollama generate endpoint and returns
+// a list of completions pertinent to the request.
+func (c ollamaClient) Generate() ([]string, error) {
+	if c.generateParams == nil {
+		return nil, fmt.Errorf("no generate params were provided")
+	}
+	// Ollama only returns a single response per request
+	responses := make([]string, 0, c.n)
+	for i := 0; i < c.n; i++ {
+		var resp GenerateResponse
+		if err := c.post(GenerateEndpoint, c.generateParams, &resp); err != nil {
+			return nil, fmt.Errorf("could not request ollama: %w", err)
+		}
+		responses = append(responses, resp.Response)
+	}
+	return responses, nil
+}
+
+// Edit Reaches out to the Ollama chat endpoint and returns a list of
+// responses which have been edited in accordance with the given instruction.
+func (c ollamaClient) Edit() ([]string, error) {
+	if c.chatParams == nil {
+		return nil, fmt.Errorf("no edit params were provided")
+	}
+	edits := make([]string, 0, c.n)
+	for i := 0; i < c.n; i++ {
+		var resp ChatResponse
+		if err := c.post(ChatEndpoint, c.chatParams, &resp); err != nil {
+			return nil, fmt.Errorf("could not request ollama: %w", err)
+		}
+		edits = append(edits, resp.Message.Content)
+	}
+	return edits, nil
+}
+
+// Chat Reaches out to the Ollama chat endpoint and returns the
+// model's reply to the conversation.
+func (c ollamaClient) Chat() (string, error) {
+	if c.chatParams == nil {
+		return "", fmt.Errorf("no chat params were provided")
+	}
+	var resp ChatResponse
+	if err := c.post(ChatEndpoint, c.chatParams, &resp); err != nil {
+		return "", fmt.Errorf("could not request ollama: %w", err)
+	}
+	return resp.Message.Content, nil
+}
+
+// ChatStream Reaches out to the Ollama chat endpoint, calling send with every
+// part of the model's reply as it is streamed back. The reply may take longer than
+// the timeout as a whole, which only bounds the wait for each of its parts.
+func (c ollamaClient) ChatStream(send func(text string) error) (string, error) {
+	if c.chatParams == nil {
+		return "", fmt.Errorf("no chat params were provided")
+	}
+	params := *c.chatParams
+	params.Stream = true
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	timeout := c.client.Timeout
+	idle := time.AfterFunc(timeout, cancel)
+	defer idle.Stop()
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, BaseURL(c.conf)+ChatEndpoint, bytes.NewReader(payload),
+	)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	streamClient := *c.client
+	streamClient.Timeout = 0
+	res, err := streamClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not request ollama: %w", idleError(idle, timeout, err))
+	}
+	defer res.Body.Close()
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("could not request ollama: error, status code: %d", res.StatusCode)
+	}
+
+	// the reply is streamed as one JSON object per line, the last one being done
+	var reply strings.Builder
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var resp ChatResponse
+		if err = decoder.Decode(&resp); err != nil {
+			return "", fmt.Errorf("could not decode the reply of ollama: %w", idleError(idle, timeout, err))
+		}
+		// the time taken to pass the part on is not the server's
+		idle.Stop()
+		if resp.Message.Content != "" {
+			reply.WriteString(resp.Message.Content)
+			if err = send(resp.Message.Content); err != nil {
+				return "", err
+			}
+		}
+		if resp.Done {
+			return reply.String(), nil
+		}
+		idle.Reset(timeout)
+	}
+}
+
+// idleError Returns err, telling that the server went quiet for too long when the idle
+// timer is the one which canceled the request.
+func idleError(idle *time.Timer, timeout time.Duration, err error) error {
+	if !idle.Stop() {
+		return fmt.Errorf("ollama sent nothing for %s: %w", timeout, err)
+	}
+	return err
+}
+
+// post Sends the given body to the endpoint as JSON and decodes the response into v.
+func (c ollamaClient) post(endpoint string, body, v interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(
+		c.ctx, http.MethodPost, BaseURL(c.conf)+endpoint, bytes.NewReader(payload),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return utils.JSONRequest(req, c.client, v)
+}
+
+// CreateOllamaGenerateClient Returns a client which generates completions using a local model.
+// Its requests are canceled when ctx is done.
+func CreateOllamaGenerateClient(ctx context.Context, conf Config, prompt string, maxTokens, nCompletions int) ai.GenerateClient {
+	options := mergeOptions(conf.Options, map[string]interface{}{
+		OptionNumPredict: maxTokens,
+		OptionStop:       []string{CompletionEndOfSequence},
+	})
+	return ollamaClient{
+		ctx:    ctx,
+		conf:   conf,
+		client: httpClient(conf),
+		n:      atLeastOne(nCompletions),
+		generateParams: &GenerateRequest{
+			Model:     Model(conf),
+			Prompt:    prompt,
+			System:    GenerateSystemPrompt,
+			KeepAlive: conf.KeepAlive,
+			Options:   options,
+		},
+	}
+}
+
+// CreateOllamaEditClient Returns a client which edits the input using a local model.
+// Its requests are canceled when ctx is done.
+func CreateOllamaEditClient(ctx context.Context, conf Config, input, instruction string, numEdits int) ai.EditClient {
+	return ollamaClient{
+		ctx:    ctx,
+		conf:   conf,
+		client: httpClient(conf),
+		n:      atLeastOne(numEdits),
+		chatParams: &ChatRequest{
+			Model: Model(conf),
+			Messages: []Message{
+				{Role: ai.RoleSystem, Content: EditSystemPrompt},
+				{Role: ai.RoleUser, Content: fmt.Sprintf("Instruction:\n%s\n\nInput:\n%s", instruction, input)},
+			},
+			KeepAlive: conf.KeepAlive,
+			Options:   mergeOptions(conf.Options, nil),
+		},
+	}
+}
+
+// CreateOllamaChatClient Returns a client which continues the given conversation
+// using a local model. An empty model selects the configured model. Its requests
+// are canceled when ctx is done.
+func CreateOllamaChatClient(ctx context.Context, conf Config, model string, messages []ai.Message) ai.ChatClient {
+	if model == "" {
+		model = Model(conf)
+	}
+	chatMessages := make([]Message, len(messages))
+	for i, message := range messages {
+		chatMessages[i] = Message{Role: message.Role, Content: message.Content}
+	}
+	return ollamaClient{
+		ctx:    ctx,
+		conf:   conf,
+		client: httpClient(conf),
+		n:      1,
+		chatParams: &ChatRequest{
+			Model:     model,
+			Messages:  chatMessages,
+			KeepAlive: conf.KeepAlive,
+			Options:   mergeOptions(conf.Options, nil),
+		},
+	}
+}
+
+// BaseURL Returns the configured server address, accepting the
+// scheme-less host:port form that OLLAMA_HOST commonly uses.
+func BaseURL(conf Config) string {
+	url := strings.TrimSuffix(conf.BaseURL, "/")
+	if url == "" {
+		return OllamaURL
+	}
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	return url
+}
+
+// Timeout Returns the configured timeout of a request, or DefaultTimeout if none is set.
+// A timeout which is not a valid duration is an error.
+func Timeout(conf Config) (time.Duration, error) {
+	if conf.Timeout == "" {
+		return DefaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ollama timeout %q, use a duration such as 5m: %w", conf.Timeout, err)
+	}
+	if timeout <= 0 {
+		return DefaultTimeout, nil
+	}
+	return timeout, nil
+}
+
+// httpClient Returns a client which gives up on requests taking longer than the
+// configured timeout, so that a stalled server cannot hang the caller. Callers check
+// the timeout with Timeout, so an invalid one falls back to DefaultTimeout here.
+func httpClient(conf Config) *http.Client {
+	timeout, err := Timeout(conf)
+	if err != nil {
+		timeout = DefaultTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// Model Returns the configured model, or the default model if none is set.
+func Model(conf Config) string {
+	if conf.Model == "" {
+		return DefaultModel
+	}
+	return conf.Model
+}
+
+// mergeOptions Returns a copy of the configured options with the defaults
+// filled in wherever the user has not set a value.
+func mergeOptions(configured, defaults map[string]interface{}) map[string]interface{} {
+	if len(configured) == 0 && len(defaults) == 0 {
+		return nil
+	}
+	options := make(map[string]interface{}, len(configured)+len(defaults))
+	for k, v := range defaults {
+		options[k] = v
+	}
+	for k, v := range configured {
+		options[k] = v
+	}
+	return options
+}
+
+// atLeastOne Ensures that at least one response is requested.
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
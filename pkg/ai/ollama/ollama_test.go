@@ -0,0 +1,173 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
+)
+
+var _ = Describe("Ollama client", func() {
+	var (
+		ts           *httptest.Server
+		conf         ollama.Config
+		generateReqs []ollama.GenerateRequest
+		chatReqs     []ollama.ChatRequest
+	)
+
+	BeforeEach(func() {
+		generateReqs = nil
+		chatReqs = nil
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/stalled" + ollama.GenerateEndpoint:
+				// the server only notices the client going away once the body is read
+				_, _ = io.Copy(io.Discard, r.Body)
+				<-r.Context().Done()
+			case "/slow" + ollama.ChatEndpoint, "/stalled" + ollama.ChatEndpoint:
+				// the parts come in slower than the timeout as a whole, and the stalled reply stops halfway
+				_, _ = io.Copy(io.Discard, r.Body)
+				for i, part := range []string{"kind: ", "Ser", "vice"} {
+					if i == 2 && r.URL.Path == "/stalled"+ollama.ChatEndpoint {
+						<-r.Context().Done()
+						return
+					}
+					time.Sleep(40 * time.Millisecond)
+					_ = json.NewEncoder(w).Encode(ollama.ChatResponse{Message: ollama.Message{Content: part}})
+					w.(http.Flusher).Flush()
+				}
+				_ = json.NewEncoder(w).Encode(ollama.ChatResponse{Done: true})
+			case ollama.GenerateEndpoint:
+				var req ollama.GenerateRequest
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				generateReqs = append(generateReqs, req)
+				_ = json.NewEncoder(w).Encode(ollama.GenerateResponse{Model: req.Model, Response: "kind: Pod", Done: true})
+			case ollama.ChatEndpoint:
+				var req ollama.ChatRequest
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				chatReqs = append(chatReqs, req)
+				if req.Stream {
+					for _, part := range []string{"kind: ", "Service"} {
+						_ = json.NewEncoder(w).Encode(ollama.ChatResponse{Message: ollama.Message{Content: part}})
+					}
+					_ = json.NewEncoder(w).Encode(ollama.ChatResponse{Done: true})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(ollama.ChatResponse{
+					Model:   req.Model,
+					Message: ollama.Message{Role: "assistant", Content: "kind: Service"},
+					Done:    true,
+				})
+			default:
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+		conf = ollama.Config{
+			BaseURL:   ts.URL,
+			Model:     "llama3",
+			KeepAlive: "10m",
+			Options:   map[string]interface{}{"temperature": 0.2},
+		}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("generates one completion per request", func() {
+		client := ollama.CreateOllamaGenerateClient(context.Background(), conf, "a pod", 64, 2)
+		responses, err := client.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responses).To(Equal([]string{"kind: Pod", "kind: Pod"}))
+
+		Expect(generateReqs).To(HaveLen(2))
+		req := generateReqs[0]
+		Expect(req.Model).To(Equal("llama3"))
+		Expect(req.Stream).To(BeFalse())
+		Expect(req.KeepAlive).To(Equal("10m"))
+		Expect(req.Options).To(HaveKeyWithValue("temperature", 0.2))
+		Expect(req.Options).To(HaveKeyWithValue(ollama.OptionNumPredict, BeNumerically("==", 64)))
+	})
+
+	It("edits using the chat endpoint", func() {
+		client := ollama.CreateOllamaEditClient(context.Background(), conf, "kind: Pod", "make it a service", 1)
+		responses, err := client.Edit()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responses).To(Equal([]string{"kind: Service"}))
+
+		Expect(chatReqs).To(HaveLen(1))
+		Expect(chatReqs[0].Messages).To(HaveLen(2))
+		Expect(chatReqs[0].Messages[1].Content).To(ContainSubstring("make it a service"))
+		Expect(chatReqs[0].Messages[1].Content).To(ContainSubstring("kind: Pod"))
+	})
+
+	It("streams the reply to a conversation", func() {
+		client := ollama.CreateOllamaChatClient(context.Background(), conf, "", []ai.Message{{Role: ai.RoleUser, Content: "what is this?"}})
+		streaming, ok := client.(ai.StreamingChatClient)
+		Expect(ok).To(BeTrue())
+
+		var parts []string
+		reply, err := streaming.ChatStream(func(text string) error {
+			parts = append(parts, text)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reply).To(Equal("kind: Service"))
+		Expect(parts).To(Equal([]string{"kind: ", "Service"}))
+		Expect(chatReqs[0].Stream).To(BeTrue())
+	})
+
+	It("fails when the server returns an error", func() {
+		conf.BaseURL = ts.URL + "/missing"
+		_, err := ollama.CreateOllamaGenerateClient(context.Background(), conf, "a pod", 64, 1).Generate()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("gives up on a stalled server after the timeout", func() {
+		conf.BaseURL = ts.URL + "/stalled"
+		conf.Timeout = "50ms"
+		start := time.Now()
+		_, err := ollama.CreateOllamaGenerateClient(context.Background(), conf, "a pod", 64, 1).Generate()
+		Expect(err).To(MatchError(ContainSubstring("Timeout")))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+
+		Expect(ollama.Timeout(ollama.Config{})).To(Equal(ollama.DefaultTimeout))
+		Expect(ollama.Timeout(ollama.Config{Timeout: "2m"})).To(Equal(2 * time.Minute))
+		_, err = ollama.Timeout(ollama.Config{Timeout: "soon"})
+		Expect(err).To(MatchError(ContainSubstring(`invalid ollama timeout "soon"`)))
+	})
+
+	It("streams replies longer than the timeout, as long as the server keeps sending", func() {
+		conf.Timeout = "100ms"
+		messages := []ai.Message{{Role: ai.RoleUser, Content: "what is this?"}}
+		stream := func() (string, error) {
+			client := ollama.CreateOllamaChatClient(context.Background(), conf, "", messages)
+			return client.(ai.StreamingChatClient).ChatStream(func(string) error { return nil })
+		}
+
+		conf.BaseURL = ts.URL + "/slow"
+		start := time.Now()
+		reply, err := stream()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reply).To(Equal("kind: Service"))
+		Expect(time.Since(start)).To(BeNumerically(">", 100*time.Millisecond))
+
+		conf.BaseURL = ts.URL + "/stalled"
+		_, err = stream()
+		Expect(err).To(MatchError(ContainSubstring("ollama sent nothing for 100ms")))
+	})
+
+	It("accepts OLLAMA_HOST style addresses", func() {
+		Expect(ollama.BaseURL(ollama.Config{BaseURL: "127.0.0.1:11434"})).To(Equal("http://127.0.0.1:11434"))
+		Expect(ollama.BaseURL(ollama.Config{})).To(Equal(ollama.OllamaURL))
+		Expect(ollama.Model(ollama.Config{})).To(Equal(ollama.DefaultModel))
+	})
+})
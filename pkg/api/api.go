@@ -0,0 +1,42 @@
+// api defines the HTTP API which `copilot-ops serve` offers, as described by its OpenAPI spec.
+package api
+
+import (
+	_ "embed"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+// Define the paths of the API.
+const (
+	PathGenerate = "/v1/generate"
+	PathEdit     = "/v1/edit"
+	PathAsk      = "/v1/ask"
+	// PathOpenAPI Serves the OpenAPI spec of the API.
+	PathOpenAPI = "/openapi.yaml"
+)
+
+// ContentTypeJSON Is the content type of the requests and responses of the API.
+const ContentTypeJSON = "application/json"
+
+// ContentTypeYAML Is the content type of the OpenAPI spec.
+const ContentTypeYAML = "application/yaml"
+
+// OpenAPI Is the OpenAPI spec of the API.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// Request Is the body of a request to generate or edit files, or to ask a question.
+type Request struct {
+	// Request Is the requested change, or the question, in natural language.
+	Request string `json:"request"`
+	// Files Are sent along with the request. A file's name is its tag, which defaults to
+	// the base name of its path, and the request can mention it as @name.
+	Files []filemap.File `json:"files,omitempty"`
+	// NCompletions, NTokens, Backend, and Model override the defaults of the server.
+	NCompletions int32  `json:"ncompletions,omitempty"`
+	NTokens      int32  `json:"ntokens,omitempty"`
+	Backend      string `json:"backend,omitempty"`
+	Model        string `json:"model,omitempty"`
+}
@@ -0,0 +1,42 @@
+package api_test
+
+import (
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/redhat-et/copilot-ops/pkg/api"
+)
+
+var _ = Describe("OpenAPI spec", func() {
+	var spec struct {
+		Paths      map[string]interface{} `yaml:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `yaml:"properties"`
+			} `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+
+	BeforeEach(func() {
+		Expect(yaml.Unmarshal(api.OpenAPI, &spec)).To(Succeed())
+	})
+
+	It("documents every path", func() {
+		Expect(spec.Paths).To(HaveKey(api.PathGenerate))
+		Expect(spec.Paths).To(HaveKey(api.PathEdit))
+		Expect(spec.Paths).To(HaveKey(api.PathAsk))
+		Expect(spec.Paths).To(HaveKey(api.PathOpenAPI))
+	})
+
+	It("documents every field of the request", func() {
+		fields := reflect.TypeOf(api.Request{})
+		for i := 0; i < fields.NumField(); i++ {
+			name := strings.Split(fields.Field(i).Tag.Get("json"), ",")[0]
+			Expect(spec.Components.Schemas["Request"].Properties).To(HaveKey(name))
+		}
+	})
+})
@@ -0,0 +1,266 @@
+// batch runs the requests of a JSONL file with a bounded number of workers, recording
+// the outcome of every item as a line of an output JSONL file. Since the output is
+// appended to as items complete, an interrupted batch resumes by skipping the items
+// which already have an outcome.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxLineSize Is the size of the longest line read from the input and output files.
+const MaxLineSize = 10 << 20
+
+// Item Is a request read from a line of the input.
+type Item struct {
+	// ID Identifies the item in the output. It defaults to the item's line number,
+	// which changes when lines are added above it.
+	ID string `json:"id,omitempty"`
+	// Line Is the line number of the item in the input, starting from 1.
+	Line         int      `json:"-"`
+	Command      string   `json:"command,omitempty"`
+	Request      string   `json:"request"`
+	Files        []string `json:"files,omitempty"`
+	Filesets     []string `json:"filesets,omitempty"`
+	NCompletions int32    `json:"ncompletions,omitempty"`
+	NTokens      int32    `json:"ntokens,omitempty"`
+	Backend      string   `json:"backend,omitempty"`
+	Model        string   `json:"model,omitempty"`
+}
+
+// Outcome Is the line of the output recording how an item went.
+type Outcome struct {
+	ID   string `json:"id"`
+	Line int    `json:"line"`
+	// Result Is what the runner returned, unless it failed.
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	// Duration Is how long the item took, in seconds.
+	Duration float64 `json:"duration"`
+}
+
+// Runner Runs an item, returning its result.
+type Runner func(ctx context.Context, item *Item) (interface{}, error)
+
+// Summary Counts the items of a batch by how they went.
+type Summary struct {
+	Succeeded int
+	Failed    int
+	// Skipped Counts the items which already had an outcome.
+	Skipped int
+	// Pending Counts the items left when the batch was interrupted.
+	Pending int
+}
+
+// String Describes the summary.
+func (s Summary) String() string {
+	text := fmt.Sprintf("%d succeeded, %d failed, %d skipped", s.Succeeded, s.Failed, s.Skipped)
+	if s.Pending > 0 {
+		text += fmt.Sprintf(", %d pending", s.Pending)
+	}
+	return text
+}
+
+// ReadItems Reads an item from every line of the input, ignoring blank lines. Items
+// without an ID get their line number, and IDs have to be unique.
+func ReadItems(r io.Reader) ([]*Item, error) {
+	var items []*Item
+	ids := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, MaxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		item := &Item{Line: line}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		// typos in field names would otherwise be silently ignored
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if item.NCompletions < 0 || item.NTokens < 0 {
+			return nil, fmt.Errorf("line %d: ncompletions and ntokens must be positive", line)
+		}
+		if item.ID == "" {
+			item.ID = strconv.Itoa(line)
+		}
+		if previous, ok := ids[item.ID]; ok {
+			return nil, fmt.Errorf("line %d: the ID %q is already used on line %d", line, item.ID, previous)
+		}
+		ids[item.ID] = line
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the items: %w", err)
+	}
+	return items, nil
+}
+
+// ReadOutcomes Returns the last outcome of every item recorded in the output, by ID.
+// Lines which cannot be decoded, left truncated by interrupted batches, are skipped.
+func ReadOutcomes(r io.Reader) (map[string]*Outcome, error) {
+	outcomes := make(map[string]*Outcome)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, MaxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var outcome Outcome
+		if err := json.Unmarshal(text, &outcome); err != nil || outcome.ID == "" {
+			log.Printf("skipping line %d of the output, which is not an outcome\n", line)
+			continue
+		}
+		outcomes[outcome.ID] = &outcome
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the output: %w", err)
+	}
+	return outcomes, nil
+}
+
+// Batch Runs items, appending their outcomes to the output.
+type Batch struct {
+	Run     Runner
+	Workers int
+	// RetryFailed Runs the items whose last outcome is an error again.
+	RetryFailed bool
+	output      io.Writer
+}
+
+// NewBatch Returns a batch running the items with the given number of workers.
+func NewBatch(run Runner, workers int, output io.Writer) *Batch {
+	return &Batch{Run: run, Workers: workers, output: output}
+}
+
+// Process Runs the items which have no outcome yet, or failed when retrying them.
+// Once the context is done, no more items are started, and the ones running are
+// waited for so that their outcomes are recorded.
+func (b *Batch) Process(ctx context.Context, items []*Item, done map[string]*Outcome) (Summary, error) {
+	var summary Summary
+	var todo []*Item
+	for _, item := range items {
+		outcome, ok := done[item.ID]
+		if ok && (outcome.Error == "" || !b.RetryFailed) {
+			summary.Skipped++
+			continue
+		}
+		todo = append(todo, item)
+	}
+	if len(todo) == 0 {
+		return summary, nil
+	}
+
+	queue := make(chan *Item)
+	outcomes := make(chan *Outcome)
+	var wg sync.WaitGroup
+	for i := 0; i < b.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				outcomes <- b.run(ctx, item)
+			}
+		}()
+	}
+	go func() {
+		defer close(queue)
+		for _, item := range todo {
+			select {
+			case queue <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	var writeErr error
+	for outcome := range outcomes {
+		if outcome.Error != "" {
+			summary.Failed++
+			log.Printf("item %s failed: %s\n", outcome.ID, outcome.Error)
+		} else {
+			summary.Succeeded++
+			log.Printf("item %s succeeded in %.1fs\n", outcome.ID, outcome.Duration)
+		}
+		if err := b.write(outcome); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	summary.Pending = len(todo) - summary.Succeeded - summary.Failed
+	return summary, writeErr
+}
+
+// run Runs the item, recovering from panics so that a single item cannot stop the batch.
+func (b *Batch) run(ctx context.Context, item *Item) (outcome *Outcome) {
+	start := time.Now()
+	outcome = &Outcome{ID: item.ID, Line: item.Line}
+	defer func() {
+		if r := recover(); r != nil {
+			outcome.Result, outcome.Error = nil, fmt.Sprintf("panic: %v", r)
+		}
+		outcome.Duration = time.Since(start).Seconds()
+	}()
+	result, err := b.Run(ctx, item)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	outcome.Result = result
+	return outcome
+}
+
+// write Appends the outcome to the output as a line. Outcomes are only written by Process.
+func (b *Batch) write(outcome *Outcome) error {
+	line, err := json.Marshal(outcome)
+	if err != nil {
+		return fmt.Errorf("could not encode the outcome of %s: %w", outcome.ID, err)
+	}
+	if _, err = b.output.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("could not write the outcome of %s: %w", outcome.ID, err)
+	}
+	return nil
+}
+
+// OpenOutput Opens the output for appending, returning the outcomes it already holds.
+// When the last line was truncated, a newline is added so that new outcomes start on a line of their own.
+func OpenOutput(path string) (*os.File, map[string]*Outcome, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+	outcomes, err := ReadOutcomes(bytes.NewReader(content))
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		if _, err = f.WriteString("\n"); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("could not write to %s: %w", path, err)
+		}
+	}
+	return f, outcomes, nil
+}
@@ -0,0 +1,161 @@
+package batch_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/batch"
+)
+
+var _ = Describe("Batch", func() {
+	It("reads the items of the input", func() {
+		items, err := batch.ReadItems(strings.NewReader(`{"id": "pvc", "request": "resize", "files": ["pvc.yaml"]}
+
+{"command": "ask", "request": "why?", "ncompletions": 2}
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]*batch.Item{
+			{ID: "pvc", Line: 1, Request: "resize", Files: []string{"pvc.yaml"}},
+			{ID: "3", Line: 3, Command: "ask", Request: "why?", NCompletions: 2},
+		}))
+	})
+
+	It("rejects invalid items", func() {
+		_, err := batch.ReadItems(strings.NewReader(`{"request": "a"}` + "\n" + `{"reqest": "b"}`))
+		Expect(err).To(MatchError(ContainSubstring("line 2")))
+
+		_, err = batch.ReadItems(strings.NewReader(`{"id": "a", "request": "a"}` + "\n" + `{"id": "a", "request": "b"}`))
+		Expect(err).To(MatchError(ContainSubstring("already used on line 1")))
+
+		_, err = batch.ReadItems(strings.NewReader(`{"request": "a", "ntokens": -1}`))
+		Expect(err).To(MatchError(ContainSubstring("must be positive")))
+	})
+
+	It("reads the last outcome of every item, skipping truncated lines", func() {
+		outcomes, err := batch.ReadOutcomes(strings.NewReader(`{"id": "a", "line": 1, "error": "timeout"}
+{"id": "b", "line": 2, "result": {}}
+{"id": "a", "line": 1, "result": {}}
+{"id": "c", "li`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes).To(HaveLen(2))
+		Expect(outcomes["a"].Error).To(BeEmpty())
+	})
+
+	Context("processing items", func() {
+		var (
+			items  []*batch.Item
+			output *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			items = nil
+			for _, id := range []string{"a", "b", "c", "d", "e"} {
+				items = append(items, &batch.Item{ID: id, Request: "request " + id})
+			}
+			output = &bytes.Buffer{}
+		})
+
+		It("records the outcome of every item, with a bounded number of workers", func() {
+			var running, most int32
+			var mu sync.Mutex
+			run := func(_ context.Context, item *batch.Item) (interface{}, error) {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				mu.Lock()
+				if n > most {
+					most = n
+				}
+				mu.Unlock()
+				switch item.ID {
+				case "b":
+					return nil, errors.New("the backend is down")
+				case "c":
+					panic("oops")
+				}
+				return map[string]string{"answer": item.Request}, nil
+			}
+
+			summary, err := batch.NewBatch(run, 2, output).Process(context.Background(), items, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(summary).To(Equal(batch.Summary{Succeeded: 3, Failed: 2}))
+			Expect(most).To(BeNumerically("<=", 2))
+
+			outcomes, err := batch.ReadOutcomes(output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outcomes).To(HaveLen(5))
+			Expect(outcomes["a"].Result).To(Equal(map[string]interface{}{"answer": "request a"}))
+			Expect(outcomes["b"].Error).To(Equal("the backend is down"))
+			Expect(outcomes["c"].Error).To(Equal("panic: oops"))
+		})
+
+		It("skips the items which have an outcome, unless retrying failed ones", func() {
+			var ran []string
+			var mu sync.Mutex
+			run := func(_ context.Context, item *batch.Item) (interface{}, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				ran = append(ran, item.ID)
+				return "ok", nil
+			}
+			done := map[string]*batch.Outcome{
+				"a": {ID: "a", Result: "ok"},
+				"b": {ID: "b", Error: "timeout"},
+			}
+
+			b := batch.NewBatch(run, 3, output)
+			summary, err := b.Process(context.Background(), items, done)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(summary).To(Equal(batch.Summary{Succeeded: 3, Skipped: 2}))
+			Expect(ran).To(ConsistOf("c", "d", "e"))
+
+			ran = nil
+			b.RetryFailed = true
+			summary, err = b.Process(context.Background(), items, done)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(summary.Skipped).To(Equal(1))
+			Expect(ran).To(ContainElement("b"))
+		})
+
+		It("stops starting items once interrupted, recording the running ones", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			run := func(_ context.Context, item *batch.Item) (interface{}, error) {
+				cancel()
+				return "ok", nil
+			}
+			summary, err := batch.NewBatch(run, 1, output).Process(ctx, items, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(summary.Succeeded).To(BeNumerically(">=", 1))
+			Expect(summary.Pending).To(Equal(len(items) - summary.Succeeded))
+			Expect(strings.Count(output.String(), "\n")).To(Equal(summary.Succeeded))
+		})
+	})
+
+	It("starts appending on a new line after a truncated outcome", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "results.jsonl")
+		truncated := `{"id": "a", "line": 1, "result": "ok"}` + "\n" + `{"id": "b", "li`
+		Expect(os.WriteFile(path, []byte(truncated), 0o600)).To(Succeed())
+
+		f, done, err := batch.OpenOutput(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(HaveKey("a"))
+		_, err = f.WriteString(`{"id": "b", "line": 2, "result": "ok"}` + "\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Split(string(content), "\n")).To(HaveLen(4))
+		outcomes, err := batch.ReadOutcomes(bytes.NewReader(content))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,360 @@
+// bot proposes the changes requested in issues as pull requests. The forge an issue
+// comes from only has to deliver its webhooks, and publish the changed files on a branch.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/diff"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/github"
+	"github.com/redhat-et/copilot-ops/pkg/gitlab"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+// Define the defaults of the bot.
+const (
+	// DefaultLabel Is the label which asks the bot to handle an issue when it is opened or labeled.
+	DefaultLabel = "copilot-ops"
+	// DefaultBranchPrefix Precedes the names of the branches of the proposed changes.
+	DefaultBranchPrefix = "copilot-ops/"
+	// GitHubWebhookPath Is the path which GitHub's webhooks are served on.
+	GitHubWebhookPath = "/webhooks/github"
+	// GitLabWebhookPath Is the path which GitLab's webhooks are served on.
+	GitLabWebhookPath = "/webhooks/gitlab"
+	// MaxPayloadSize Is the largest webhook payload accepted, in bytes.
+	MaxPayloadSize = 25 << 20
+)
+
+// Proposal Is the result of running the edit flow for an issue.
+type Proposal struct {
+	// Request Is the request which was sent to the backend.
+	Request string
+	// Before Holds the files as they were loaded from the checkout.
+	Before *filemap.Filemap
+	// After Holds the edited files.
+	After *filemap.Filemap
+	// Answer Is the reply to a question asked with the ask command, which proposes no changes.
+	Answer string
+}
+
+// Runner Proposes the changes requested by an issue to the files of the checkout. The
+// command is parsed from the comment which asked for the changes, and is nil when the
+// issue was labeled.
+type Runner func(ctx context.Context, iss *issue.Issue, command *chatops.Command) (*Proposal, error)
+
+// File Is a file to commit, with its path relative to the root of the repo.
+type File struct {
+	Path    string
+	Content string
+	// New Is set when the file was not loaded from the checkout.
+	New bool
+}
+
+// Change Is a set of files to commit on a branch, and to propose for merging into the base branch.
+type Change struct {
+	// Number Is the number of the issue which requested the change.
+	Number  int
+	Branch  string
+	Base    string
+	Title   string
+	Body    string
+	Message string
+	// Summary Lists the changed files, with the number of lines added and removed.
+	Summary string
+	Files   []File
+}
+
+// Forge Publishes changes to the platform which the issue comes from.
+type Forge interface {
+	// Publish Commits the change to its branch and opens a pull request for it, or
+	// updates the branch of the one already open, returning the pull request's URL.
+	Publish(ctx context.Context, change Change) (string, error)
+	// Comment Replies on the issue.
+	Comment(ctx context.Context, number int, body string) error
+}
+
+// Job Is an issue which the bot was asked to propose changes for.
+type Job struct {
+	Number int
+	// Reference Is how the forge refers to the issue in text, e.g. #7.
+	Reference string
+	// Closes Is set when merging the changes resolves the issue.
+	Closes bool
+	// Base Is the branch which the changes are proposed for.
+	Base string
+	// Branch Is the branch which the changes are committed to.
+	Branch string
+	Title  string
+	Body   string
+	// Comment Is the comment whose command triggered the bot, if any.
+	Comment string
+}
+
+// Bot Runs the edit flow for the issues it receives, and publishes the proposed changes.
+type Bot struct {
+	Run          Runner
+	Label        string
+	Trigger      string
+	BranchPrefix string
+	// Associations Are the author associations of the GitHub users who may ask the bot to handle
+	// an issue, by opening it or commenting on it. Anyone may when it is empty.
+	Associations []string
+	// AccessLevel Is the minimum access level to the project of the GitLab users who may ask the
+	// bot to handle an issue, by opening it, labeling it, or commenting on it. Anyone may when it is zero.
+	AccessLevel int
+	// AllowCI Lets the proposed changes touch the definitions of CI pipelines, which run with
+	// the secrets of the project, so that a request could otherwise have them leaked.
+	AllowCI bool
+	// mu Serializes the jobs, since they share the checkout.
+	mu sync.Mutex
+	wg sync.WaitGroup
+}
+
+// New Returns a bot which uses the default label, trigger, branch prefix, associations, and
+// access level: only the users who can push to the repository may ask it for changes.
+func New(run Runner) *Bot {
+	return &Bot{
+		Run:          run,
+		Label:        DefaultLabel,
+		Trigger:      chatops.DefaultTrigger,
+		BranchPrefix: DefaultBranchPrefix,
+		Associations: DefaultAssociations(),
+		AccessLevel:  gitlab.AccessDeveloper,
+	}
+}
+
+// DefaultAssociations Returns the author associations of the users who can push to a
+// GitHub repository, the only ones the bot acts for by default.
+func DefaultAssociations() []string {
+	return []string{github.AssociationOwner, github.AssociationMember, github.AssociationCollaborator}
+}
+
+// Trusts Reports whether the bot acts for users with the given author association.
+func (b *Bot) Trusts(association string) bool {
+	if len(b.Associations) == 0 {
+		return true
+	}
+	for _, trusted := range b.Associations {
+		if strings.EqualFold(trusted, association) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrCIDefinition Is returned for the changes to the definitions of CI pipelines, unless they are allowed.
+var ErrCIDefinition = errors.New("the definitions of CI pipelines may not be changed")
+
+// CIDefinitions Returns the files, and the directories ending with a slash, which define
+// the CI pipelines of the forges and CI services in common use.
+func CIDefinitions() []string {
+	return []string{
+		".github/workflows/", ".github/actions/", ".gitlab-ci.yml", ".gitlab-ci.yaml", ".gitlab/",
+		".circleci/", ".tekton/", ".travis.yml", ".drone.yml", "azure-pipelines.yml", "Jenkinsfile",
+	}
+}
+
+// IsCIDefinition Reports whether the path, relative to the root of the repo, defines a CI pipeline.
+func IsCIDefinition(path string) bool {
+	path = strings.ToLower(filepath.ToSlash(filepath.Clean(path)))
+	for _, definition := range CIDefinitions() {
+		definition = strings.ToLower(definition)
+		if path == definition || strings.HasSuffix(definition, "/") && strings.HasPrefix(path, definition) {
+			return true
+		}
+	}
+	return false
+}
+
+// Go Runs fn in the background, once every job started before it is done.
+// Webhooks have to be answered within seconds, while backends take longer than that.
+func (b *Bot) Go(name string, fn func(ctx context.Context) error) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if err := fn(context.Background()); err != nil {
+			log.Printf("%s: %s\n", name, err)
+		}
+	}()
+}
+
+// Wait Blocks until every job started with Go is done.
+func (b *Bot) Wait() {
+	b.wg.Wait()
+}
+
+// readPayload Reads the payload of a webhook, answering the request when it cannot be read.
+func readPayload(w http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "webhooks must be sent with POST", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, req.Body, MaxPayloadSize))
+	if err != nil {
+		http.Error(w, "could not read the payload", http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	return payload, true
+}
+
+// IssueBranch Returns the branch of the changes proposed for an issue.
+func (b *Bot) IssueBranch(number int) string {
+	return fmt.Sprintf("%sissue-%d", b.BranchPrefix, number)
+}
+
+// Process Runs the edit flow for the job and publishes the proposed changes,
+// reporting the outcome on the issue. Questions are answered with a comment. Changes to
+// the definitions of CI pipelines are refused, unless they are allowed.
+func (b *Bot) Process(ctx context.Context, forge Forge, job Job) error {
+	proposal, err := b.propose(ctx, job)
+	if err != nil {
+		return b.reply(ctx, forge, job, fmt.Sprintf("copilot-ops could not propose changes for this issue: %s", err), err)
+	}
+	if proposal.Answer != "" {
+		return b.reply(ctx, forge, job, proposal.Answer, nil)
+	}
+	change := b.change(job, proposal)
+	if len(change.Files) == 0 {
+		return b.reply(ctx, forge, job, "copilot-ops found no changes to propose for this issue.", nil)
+	}
+	if err = b.checkCI(change.Files); err != nil {
+		return b.reply(ctx, forge, job, fmt.Sprintf("copilot-ops could not propose changes for this issue: %s", err), err)
+	}
+	url, err := forge.Publish(ctx, change)
+	if err != nil {
+		return b.reply(ctx, forge, job, "copilot-ops could not open a pull request with the proposed changes.", err)
+	}
+	return b.reply(ctx, forge, job, fmt.Sprintf("copilot-ops proposed changes to %d file(s) in %s\n\n%s",
+		len(change.Files), url, change.Summary), nil)
+}
+
+// propose Runs the command of the job's comment, or the edit flow when it has none.
+func (b *Bot) propose(ctx context.Context, job Job) (*Proposal, error) {
+	iss, err := issue.Parse(job.Title, job.Body)
+	if err != nil {
+		return nil, err
+	}
+	var command *chatops.Command
+	if job.Comment != "" {
+		if command, err = chatops.Parse(job.Comment, b.Trigger); err != nil {
+			return nil, err
+		}
+	}
+	return b.Run(ctx, iss, command)
+}
+
+// change Returns the change which publishes the proposal.
+func (b *Bot) change(job Job, proposal *Proposal) Change {
+	title := "copilot-ops: changes for " + job.Reference
+	if job.Title != "" {
+		title = "copilot-ops: " + job.Title
+	}
+	return Change{
+		Number:  job.Number,
+		Branch:  job.Branch,
+		Base:    job.Base,
+		Title:   title,
+		Body:    Description(job, proposal),
+		Summary: Summary(proposal),
+		Message: fmt.Sprintf("%s\n\nProposed by copilot-ops for %s.", title, job.Reference),
+		Files:   ChangedFiles(proposal.Before, proposal.After),
+	}
+}
+
+// checkCI Refuses the changes to the definitions of CI pipelines, unless they are allowed.
+func (b *Bot) checkCI(files []File) error {
+	if b.AllowCI {
+		return nil
+	}
+	var paths []string
+	for _, file := range files {
+		if IsCIDefinition(file.Path) {
+			paths = append(paths, file.Path)
+		}
+	}
+	if len(paths) > 0 {
+		return fmt.Errorf("%w: %s", ErrCIDefinition, strings.Join(paths, ", "))
+	}
+	return nil
+}
+
+// reply Comments on the issue, and returns err, or the error of commenting.
+func (b *Bot) reply(ctx context.Context, forge Forge, job Job, body string, err error) error {
+	if commentErr := forge.Comment(ctx, job.Number, body); commentErr != nil {
+		log.Printf("could not comment on #%d: %s\n", job.Number, commentErr)
+		if err == nil {
+			return commentErr
+		}
+	}
+	return err
+}
+
+// ChangedFiles Returns the files of after whose content differs from before, ordered by path.
+func ChangedFiles(before, after *filemap.Filemap) []File {
+	original := diff.ContentByPath(before)
+	files := []File{}
+	for path, content := range diff.ContentByPath(after) {
+		if old, ok := original[path]; ok && old == content {
+			continue
+		}
+		_, ok := original[path]
+		files = append(files, File{Path: path, Content: content, New: !ok})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// Description Returns the description of the pull request: the request, the changed
+// files with the number of lines added and removed, and the diff.
+func Description(job Job, proposal *Proposal) string {
+	var out strings.Builder
+	if job.Closes {
+		fmt.Fprintf(&out, "Closes %s.\n\n", job.Reference)
+	}
+	fmt.Fprintf(&out, "These changes were proposed by copilot-ops for %s, which requested:\n\n", job.Reference)
+	for _, line := range strings.Split(strings.TrimSpace(proposal.Request), "\n") {
+		out.WriteString(strings.TrimSpace("> "+line) + "\n")
+	}
+
+	fmt.Fprintf(&out, "\n**Changed files**\n\n%s", Summary(proposal))
+	fmt.Fprintf(&out, "\n<details>\n<summary>Diff</summary>\n\n```diff\n%s```\n\n</details>\n",
+		diff.Filemaps(proposal.Before, proposal.After))
+	return out.String()
+}
+
+// Summary Lists the changed files, with the number of lines added and removed from each.
+func Summary(proposal *Proposal) string {
+	var out strings.Builder
+	original := diff.ContentByPath(proposal.Before)
+	for _, file := range ChangedFiles(proposal.Before, proposal.After) {
+		added, removed := 0, 0
+		for _, line := range diff.Lines(original[file.Path], file.Content) {
+			switch line.Op {
+			case diff.Insert:
+				added++
+			case diff.Delete:
+				removed++
+			case diff.Equal:
+			}
+		}
+		status := ""
+		if file.New {
+			status = ", new"
+		}
+		fmt.Fprintf(&out, "- `%s` (+%d -%d%s)\n", file.Path, added, removed, status)
+	}
+	return out.String()
+}
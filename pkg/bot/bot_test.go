@@ -0,0 +1,96 @@
+package bot_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/bot"
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+// resizePVC Returns a runner which records the issues and commands it receives, and
+// proposes to resize app/pvc.yaml unless err points to an error. Questions are answered
+// by repeating them.
+func resizePVC(received *[]*issue.Issue, commands *[]*chatops.Command, err *error) bot.Runner {
+	return func(_ context.Context, iss *issue.Issue, command *chatops.Command) (*bot.Proposal, error) {
+		*received = append(*received, iss)
+		*commands = append(*commands, command)
+		if *err != nil {
+			return nil, *err
+		}
+		if command != nil && command.Name == chatops.CommandAsk {
+			return &bot.Proposal{Answer: "You asked: " + command.Request}, nil
+		}
+		before := filemap.NewFilemap()
+		before.Files["pvc"] = filemap.File{Path: "./app/pvc.yaml", Content: "storage: 1Gi\n"}
+		before.Files["ns"] = filemap.File{Path: "app/ns.yaml", Content: "kind: Namespace\n"}
+		after := before.Clone()
+		after.Files["pvc"] = filemap.File{Path: "./app/pvc.yaml", Content: "storage: 100Gi\n"}
+		return &bot.Proposal{Request: iss.Request(), Before: before, After: after}, nil
+	}
+}
+
+// recordingForge Records the changes and comments of the bot instead of publishing them.
+type recordingForge struct {
+	published []bot.Change
+	comments  []string
+}
+
+func (f *recordingForge) Publish(_ context.Context, change bot.Change) (string, error) {
+	f.published = append(f.published, change)
+	return "https://forge.test/pulls/1", nil
+}
+
+func (f *recordingForge) Comment(_ context.Context, _ int, body string) error {
+	f.comments = append(f.comments, body)
+	return nil
+}
+
+var _ = Describe("Bot", func() {
+	It("lists the changed and new files", func() {
+		before := filemap.NewFilemap()
+		before.Files["pvc"] = filemap.File{Path: "app/pvc.yaml", Content: "storage: 1Gi\n"}
+		after := before.Clone()
+		after.Files["pvc"] = filemap.File{Path: "app/pvc.yaml", Content: "storage: 2Gi\n"}
+		after.Files["pod"] = filemap.File{Path: "app/pod.yaml", Content: "kind: Pod\nmetadata: {}\n"}
+
+		Expect(bot.ChangedFiles(before, after)).To(Equal([]bot.File{
+			{Path: "app/pod.yaml", Content: "kind: Pod\nmetadata: {}\n", New: true},
+			{Path: "app/pvc.yaml", Content: "storage: 2Gi\n"},
+		}))
+		Expect(bot.Summary(&bot.Proposal{Before: before, After: after})).
+			To(Equal("- `app/pod.yaml` (+2 -0, new)\n- `app/pvc.yaml` (+1 -1)\n"))
+	})
+
+	It("refuses to change the definitions of CI pipelines unless they are allowed", func() {
+		forge := &recordingForge{}
+		b := bot.New(func(context.Context, *issue.Issue, *chatops.Command) (*bot.Proposal, error) {
+			after := filemap.NewFilemap()
+			after.Files[".github/workflows/leak.yml"] = filemap.File{Path: ".github/workflows/leak.yml", Content: "on: push\n"}
+			after.Files["app/pod.yaml"] = filemap.File{Path: "app/pod.yaml", Content: "kind: Pod\n"}
+			return &bot.Proposal{Request: "add a pod", Before: filemap.NewFilemap(), After: after}, nil
+		})
+		job := bot.Job{Number: 7, Reference: "#7", Title: "Add a pod", Body: "add a pod"}
+
+		err := b.Process(context.Background(), forge, job)
+		Expect(err).To(MatchError(bot.ErrCIDefinition))
+		Expect(err).To(MatchError(ContainSubstring(".github/workflows/leak.yml")))
+		Expect(forge.published).To(BeEmpty())
+		Expect(forge.comments).To(ConsistOf(ContainSubstring("CI pipelines may not be changed")))
+
+		b.AllowCI = true
+		Expect(b.Process(context.Background(), forge, job)).To(Succeed())
+		Expect(forge.published).To(HaveLen(1))
+		Expect(forge.published[0].Files).To(HaveLen(2))
+
+		for _, path := range []string{".gitlab-ci.yml", "./.GitHub/Workflows/ci.yaml", ".gitlab/ci/deploy.yml", "Jenkinsfile"} {
+			Expect(bot.IsCIDefinition(path)).To(BeTrue(), path)
+		}
+		Expect(bot.IsCIDefinition("app/.gitlab-ci.yml")).To(BeFalse())
+		Expect(bot.IsCIDefinition(".github/CODEOWNERS")).To(BeFalse())
+	})
+})
@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/github"
+)
+
+// GitHub Publishes changes to a GitHub repository. The files are committed through
+// the git data API on top of the base branch, so no clone or push is needed.
+type GitHub struct {
+	Client *github.Client
+	Owner  string
+	Repo   string
+}
+
+// Publish Commits the change to its branch, replacing what the branch held before,
+// and opens a pull request unless one is already open for the branch.
+func (g *GitHub) Publish(ctx context.Context, change Change) (string, error) {
+	head, err := g.Client.GetBranch(ctx, g.Owner, g.Repo, change.Base)
+	if err != nil {
+		return "", fmt.Errorf("could not read branch %s: %w", change.Base, err)
+	}
+	parent, err := g.Client.GetCommit(ctx, g.Owner, g.Repo, head.Object.SHA)
+	if err != nil {
+		return "", fmt.Errorf("could not read commit %s: %w", head.Object.SHA, err)
+	}
+
+	entries := make([]github.TreeEntry, 0, len(change.Files))
+	for _, file := range change.Files {
+		entries = append(entries, github.TreeEntry{Path: file.Path, Mode: github.FileMode, Type: "blob", Content: file.Content})
+	}
+	tree, err := g.Client.CreateTree(ctx, g.Owner, g.Repo, parent.Tree.SHA, entries)
+	if err != nil {
+		return "", fmt.Errorf("could not create tree: %w", err)
+	}
+	commit, err := g.Client.CreateCommit(ctx, g.Owner, g.Repo, change.Message, tree, parent.SHA)
+	if err != nil {
+		return "", fmt.Errorf("could not create commit: %w", err)
+	}
+
+	err = g.Client.CreateBranch(ctx, g.Owner, g.Repo, change.Branch, commit.SHA)
+	if github.IsStatus(err, http.StatusUnprocessableEntity) {
+		// the issue was handled before, so its branch is replaced by the new proposal
+		err = g.Client.UpdateBranch(ctx, g.Owner, g.Repo, change.Branch, commit.SHA)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not update branch %s: %w", change.Branch, err)
+	}
+
+	prs, err := g.Client.ListPullRequests(ctx, g.Owner, g.Repo, change.Branch)
+	if err != nil {
+		return "", fmt.Errorf("could not list pull requests: %w", err)
+	}
+	if len(prs) > 0 {
+		return prs[0].HTMLURL, nil
+	}
+	pr, err := g.Client.CreatePullRequest(ctx, g.Owner, g.Repo, github.PullRequest{
+		Title: change.Title,
+		Body:  change.Body,
+		Head:  change.Branch,
+		Base:  change.Base,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not create pull request: %w", err)
+	}
+	return pr.HTMLURL, nil
+}
+
+// Comment Comments on the issue.
+func (g *GitHub) Comment(ctx context.Context, number int, body string) error {
+	return g.Client.CreateComment(ctx, g.Owner, g.Repo, number, body)
+}
+
+// GitHubHandler Handles the issues and issue_comment webhooks of GitHub.
+type GitHubHandler struct {
+	Bot *Bot
+	// Secret Is the secret of the webhook, which every payload must be signed with.
+	Secret []byte
+	// Client Returns the client which acts on behalf of the installation which
+	// received the event. The installation ID is zero for webhooks of a repository.
+	Client func(ctx context.Context, installationID int64) (*github.Client, error)
+}
+
+// ServeHTTP Validates the webhook, and processes the issue in the background if the bot was asked to.
+func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, ok := readPayload(w, req)
+	if !ok {
+		return
+	}
+	if err := github.ValidateSignature(h.Secret, payload, req.Header.Get(github.HeaderSignature)); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	name := req.Header.Get(github.HeaderEvent)
+	if name == github.EventPing {
+		fmt.Fprintln(w, "pong")
+		return
+	}
+	var event github.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "could not decode the payload", http.StatusBadRequest)
+		return
+	}
+	job, reason := h.Bot.GitHubJob(name, &event)
+	if job == nil {
+		fmt.Fprintln(w, reason)
+		return
+	}
+
+	var installationID int64
+	if event.Installation != nil {
+		installationID = event.Installation.ID
+	}
+	owner, repo := event.Repository.Owner.Login, event.Repository.Name
+	log.Printf("proposing changes for %s/%s#%d\n", owner, repo, job.Number)
+	h.Bot.Go(fmt.Sprintf("%s/%s#%d", owner, repo, job.Number), func(ctx context.Context) error {
+		client, clientErr := h.Client(ctx, installationID)
+		if clientErr != nil {
+			return clientErr
+		}
+		return h.Bot.Process(ctx, &GitHub{Client: client, Owner: owner, Repo: repo}, *job)
+	})
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "accepted")
+}
+
+// GitHubJob Returns the job requested by the event, or the reason why it requests none.
+// Issues are handled when they are opened with the bot's label or labeled with it, and
+// when a line of a comment on them starts with the trigger. Only the issues and comments of
+// trusted authors are handled, while labeling already takes triage access to the repository.
+func (b *Bot) GitHubJob(name string, event *github.Event) (*Job, string) {
+	if event.Sender.Type == github.UserTypeBot {
+		return nil, "ignored events sent by apps"
+	}
+	job := &Job{
+		Number:    event.Issue.Number,
+		Reference: fmt.Sprintf("#%d", event.Issue.Number),
+		Closes:    true,
+		Base:      event.Repository.DefaultBranch,
+		Branch:    b.IssueBranch(event.Issue.Number),
+		Title:     event.Issue.Title,
+		Body:      event.Issue.Body,
+	}
+	switch name {
+	case github.EventIssues:
+		switch {
+		case event.Action == github.ActionOpened && (b.Label == "" || event.Issue.HasLabel(b.Label)):
+			if !b.Trusts(event.Issue.AuthorAssociation) {
+				return nil, "ignored issue opened by " + associationOf(event.Issue.AuthorAssociation)
+			}
+			return job, ""
+		case event.Action == github.ActionLabeled && event.Label != nil && b.Label != "" && event.Label.Name == b.Label:
+			return job, ""
+		}
+		return nil, "ignored issue without the " + b.Label + " label"
+	case github.EventIssueComment:
+		if event.Action != github.ActionCreated || event.Comment == nil {
+			return nil, "ignored comment which was not created"
+		}
+		if !chatops.HasCommand(event.Comment.Body, b.Trigger) {
+			return nil, "ignored comment without " + b.Trigger
+		}
+		if event.Issue.PullRequest != nil {
+			return nil, "ignored comment on a pull request"
+		}
+		if !b.Trusts(event.Comment.AuthorAssociation) {
+			return nil, "ignored comment by " + associationOf(event.Comment.AuthorAssociation)
+		}
+		job.Comment = event.Comment.Body
+		return job, ""
+	}
+	return nil, "ignored " + name + " event"
+}
+
+// associationOf Describes the author association of an untrusted user.
+func associationOf(association string) string {
+	if association == "" {
+		return "an author without association"
+	}
+	return "an author associated as " + association
+}
@@ -0,0 +1,272 @@
+package bot_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/bot"
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/github"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+// fakeGitHub Is a stand-in for the parts of the GitHub API used by the bot.
+type fakeGitHub struct {
+	mu sync.Mutex
+	// branchExists Makes creating the branch fail as if the issue was handled before.
+	branchExists bool
+	// openPR Is returned when listing pull requests, if set.
+	openPR   *github.PullRequest
+	tree     map[string]interface{}
+	commit   map[string]interface{}
+	updated  bool
+	pr       *github.PullRequest
+	comments []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	decode := func(v interface{}) {
+		Expect(json.NewDecoder(r.Body).Decode(v)).To(Succeed())
+	}
+	switch r.Method + " " + r.URL.Path {
+	case "GET /repos/octo/gitops/git/ref/heads/main":
+		fmt.Fprint(w, `{"ref":"refs/heads/main","object":{"sha":"head"}}`)
+	case "GET /repos/octo/gitops/git/commits/head":
+		fmt.Fprint(w, `{"sha":"head","tree":{"sha":"head-tree"}}`)
+	case "POST /repos/octo/gitops/git/trees":
+		decode(&f.tree)
+		fmt.Fprint(w, `{"sha":"new-tree"}`)
+	case "POST /repos/octo/gitops/git/commits":
+		decode(&f.commit)
+		fmt.Fprint(w, `{"sha":"new-commit"}`)
+	case "POST /repos/octo/gitops/git/refs":
+		if f.branchExists {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message":"Reference already exists"}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case "PATCH /repos/octo/gitops/git/refs/heads/copilot-ops/issue-7":
+		f.updated = true
+	case "GET /repos/octo/gitops/pulls":
+		Expect(r.URL.Query().Get("head")).To(Equal("octo:copilot-ops/issue-7"))
+		prs := []github.PullRequest{}
+		if f.openPR != nil {
+			prs = append(prs, *f.openPR)
+		}
+		Expect(json.NewEncoder(w).Encode(prs)).To(Succeed())
+	case "POST /repos/octo/gitops/pulls":
+		f.pr = &github.PullRequest{}
+		decode(f.pr)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number":8,"html_url":"https://github.test/octo/gitops/pull/8"}`)
+	case "POST /repos/octo/gitops/issues/7/comments":
+		var comment map[string]string
+		decode(&comment)
+		f.comments = append(f.comments, comment["body"])
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var _ = Describe("GitHub bot", func() {
+	var (
+		fake     *fakeGitHub
+		ts       *httptest.Server
+		b        *bot.Bot
+		handler  *bot.GitHubHandler
+		received []*issue.Issue
+		commands []*chatops.Command
+		runErr   error
+		secret   = []byte("s3cret")
+	)
+
+	BeforeEach(func() {
+		fake = &fakeGitHub{}
+		ts = httptest.NewServer(fake)
+		received, commands, runErr = nil, nil, nil
+		b = bot.New(resizePVC(&received, &commands, &runErr))
+		handler = &bot.GitHubHandler{
+			Bot:    b,
+			Secret: secret,
+			Client: func(context.Context, int64) (*github.Client, error) {
+				return github.NewClient(ts.URL, "t0ken"), nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	// deliver Sends a signed webhook to the handler and waits for the job it started.
+	deliver := func(event string, payload interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(payload)
+		Expect(err).NotTo(HaveOccurred())
+		req := httptest.NewRequest(http.MethodPost, bot.GitHubWebhookPath, bytes.NewReader(body))
+		req.Header.Set(github.HeaderEvent, event)
+		req.Header.Set(github.HeaderSignature, github.Sign(secret, body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		b.Wait()
+		return rec
+	}
+
+	issueEvent := func(action string, labels ...string) github.Event {
+		event := github.Event{
+			Action: action,
+			Issue: github.Issue{
+				Number: 7,
+				Title:  "Resize the PVC",
+				Body:   "`@pvc:app/pvc.yaml`\n\n@pvc needs 100Gi",
+				// the author of the issue can push to the repo
+				AuthorAssociation: github.AssociationMember,
+			},
+			Repository: github.Repository{Name: "gitops", DefaultBranch: "main", Owner: github.User{Login: "octo"}},
+			Sender:     github.User{Login: "alice", Type: "User"},
+		}
+		for _, label := range labels {
+			event.Issue.Labels = append(event.Issue.Labels, github.Label{Name: label})
+		}
+		return event
+	}
+
+	It("opens a pull request with the changes requested by a labeled issue", func() {
+		rec := deliver(github.EventIssues, issueEvent(github.ActionOpened, bot.DefaultLabel))
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(received).To(HaveLen(1))
+		Expect(received[0].References).To(Equal([]issue.Reference{{Name: "pvc", Path: "app/pvc.yaml"}}))
+
+		Expect(fake.tree).To(HaveKeyWithValue("base_tree", "head-tree"))
+		Expect(fake.tree["tree"]).To(ConsistOf(map[string]interface{}{
+			"path": "app/pvc.yaml", "mode": github.FileMode, "type": "blob", "content": "storage: 100Gi\n",
+		}))
+		Expect(fake.commit).To(HaveKeyWithValue("parents", ConsistOf("head")))
+		Expect(fake.commit["message"]).To(ContainSubstring("Resize the PVC"))
+
+		Expect(fake.pr).NotTo(BeNil())
+		Expect(fake.pr.Head).To(Equal("copilot-ops/issue-7"))
+		Expect(fake.pr.Base).To(Equal("main"))
+		Expect(fake.pr.Title).To(Equal("copilot-ops: Resize the PVC"))
+		Expect(fake.pr.Body).To(ContainSubstring("Closes #7."))
+		Expect(fake.pr.Body).To(ContainSubstring("> @pvc needs 100Gi"))
+		Expect(fake.pr.Body).To(ContainSubstring("- `app/pvc.yaml` (+1 -1)"))
+		Expect(fake.pr.Body).To(ContainSubstring("-storage: 1Gi\n+storage: 100Gi\n"))
+		Expect(fake.pr.Body).NotTo(ContainSubstring("ns.yaml"))
+
+		Expect(fake.comments).To(ConsistOf(ContainSubstring("https://github.test/octo/gitops/pull/8")))
+	})
+
+	It("updates the branch of the pull request already open for the issue", func() {
+		fake.branchExists = true
+		fake.openPR = &github.PullRequest{Number: 3, HTMLURL: "https://github.test/octo/gitops/pull/3"}
+		event := issueEvent(github.ActionLabeled, bot.DefaultLabel)
+		event.Label = &github.Label{Name: bot.DefaultLabel}
+		deliver(github.EventIssues, event)
+
+		Expect(fake.updated).To(BeTrue())
+		Expect(fake.pr).To(BeNil())
+		Expect(fake.comments).To(ConsistOf(ContainSubstring("pull/3")))
+	})
+
+	It("runs the command of triggering comments", func() {
+		event := issueEvent(github.ActionCreated)
+		event.Comment = &github.Comment{Body: "Almost!\n/copilot-ops edit -c 2 and keep the labels", User: event.Sender, AuthorAssociation: github.AssociationOwner}
+		Expect(deliver(github.EventIssueComment, event).Code).To(Equal(http.StatusAccepted))
+		Expect(received).To(HaveLen(1))
+		Expect(commands[0].Request).To(Equal("and keep the labels"))
+		Expect(commands[0].NCompletions).To(BeEquivalentTo(2))
+		Expect(fake.pr).NotTo(BeNil())
+	})
+
+	It("answers questions and reports invalid commands on the issue", func() {
+		event := issueEvent(github.ActionCreated)
+		event.Comment = &github.Comment{Body: "/copilot-ops ask why 100Gi?", User: event.Sender, AuthorAssociation: github.AssociationOwner}
+		deliver(github.EventIssueComment, event)
+		event.Comment.Body = "/copilot-ops edit --color red"
+		deliver(github.EventIssueComment, event)
+
+		Expect(received).To(HaveLen(1))
+		Expect(fake.pr).To(BeNil())
+		Expect(fake.comments).To(Equal([]string{
+			"You asked: why 100Gi?",
+			"copilot-ops could not propose changes for this issue: unknown option --color",
+		}))
+	})
+
+	It("reports failures on the issue", func() {
+		runErr = errors.New("the backend is down")
+		deliver(github.EventIssues, issueEvent(github.ActionOpened, bot.DefaultLabel))
+		Expect(fake.pr).To(BeNil())
+		Expect(fake.comments).To(ConsistOf(ContainSubstring("the backend is down")))
+	})
+
+	It("ignores issues it was not asked to handle", func() {
+		rec := deliver(github.EventIssues, issueEvent(github.ActionOpened))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring("label"))
+
+		event := issueEvent(github.ActionCreated)
+		event.Comment = &github.Comment{Body: "/copilot-opsy please"}
+		deliver(github.EventIssueComment, event)
+
+		event = issueEvent(github.ActionOpened, bot.DefaultLabel)
+		event.Sender.Type = github.UserTypeBot
+		deliver(github.EventIssues, event)
+
+		Expect(received).To(BeEmpty())
+		Expect(fake.comments).To(BeEmpty())
+	})
+
+	It("ignores the issues and comments of untrusted authors", func() {
+		event := issueEvent(github.ActionOpened, bot.DefaultLabel)
+		event.Issue.AuthorAssociation = "NONE"
+		rec := deliver(github.EventIssues, event)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring("associated as NONE"))
+
+		event = issueEvent(github.ActionCreated)
+		event.Comment = &github.Comment{Body: "/copilot-ops edit and read @key:/etc/passwd", User: event.Sender}
+		rec = deliver(github.EventIssueComment, event)
+		Expect(rec.Body.String()).To(ContainSubstring("without association"))
+		event.Comment.AuthorAssociation = "CONTRIBUTOR"
+		deliver(github.EventIssueComment, event)
+		Expect(received).To(BeEmpty())
+
+		// a maintainer labeling the issue vouches for it
+		event = issueEvent(github.ActionLabeled, bot.DefaultLabel)
+		event.Issue.AuthorAssociation = "NONE"
+		event.Label = &github.Label{Name: bot.DefaultLabel}
+		deliver(github.EventIssues, event)
+		Expect(received).To(HaveLen(1))
+
+		b.Associations = []string{"contributor"}
+		event = issueEvent(github.ActionCreated)
+		event.Comment = &github.Comment{Body: "/copilot-ops edit", User: event.Sender, AuthorAssociation: "CONTRIBUTOR"}
+		deliver(github.EventIssueComment, event)
+		Expect(received).To(HaveLen(2))
+	})
+
+	It("rejects payloads which are not signed with the secret", func() {
+		body := []byte(`{"action":"opened"}`)
+		req := httptest.NewRequest(http.MethodPost, bot.GitHubWebhookPath, bytes.NewReader(body))
+		req.Header.Set(github.HeaderEvent, github.EventIssues)
+		req.Header.Set(github.HeaderSignature, github.Sign([]byte("wrong"), body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+	})
+})
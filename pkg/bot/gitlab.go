@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/gitlab"
+)
+
+// GitLab Publishes changes to a GitLab project. The files are committed through the
+// commits API on top of the base branch, so no clone or push is needed.
+type GitLab struct {
+	Client  *gitlab.Client
+	Project int64
+	// MergeRequest Is set when the job comes from a merge request, so notes are posted there.
+	MergeRequest bool
+}
+
+// Publish Commits the change to its branch, replacing what the branch held before,
+// and opens a merge request unless one is already open for the branch.
+func (g *GitLab) Publish(ctx context.Context, change Change) (string, error) {
+	commit := gitlab.Commit{
+		Branch:      change.Branch,
+		StartBranch: change.Base,
+		Message:     change.Message,
+		Force:       true,
+	}
+	for _, file := range change.Files {
+		action := gitlab.FileActionUpdate
+		if file.New {
+			action = gitlab.FileActionCreate
+		}
+		commit.Actions = append(commit.Actions, gitlab.CommitAction{Action: action, FilePath: file.Path, Content: file.Content})
+	}
+	if err := g.Client.CreateCommit(ctx, g.Project, commit); err != nil {
+		return "", fmt.Errorf("could not commit to branch %s: %w", change.Branch, err)
+	}
+
+	mrs, err := g.Client.ListMergeRequests(ctx, g.Project, change.Branch)
+	if err != nil {
+		return "", fmt.Errorf("could not list merge requests: %w", err)
+	}
+	if len(mrs) > 0 {
+		return mrs[0].WebURL, nil
+	}
+	mr, err := g.Client.CreateMergeRequest(ctx, g.Project, gitlab.MergeRequest{
+		Title:              change.Title,
+		Description:        change.Body,
+		SourceBranch:       change.Branch,
+		TargetBranch:       change.Base,
+		RemoveSourceBranch: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not create merge request: %w", err)
+	}
+	return mr.WebURL, nil
+}
+
+// Comment Posts a note on the issue or merge request.
+func (g *GitLab) Comment(ctx context.Context, number int, body string) error {
+	if g.MergeRequest {
+		return g.Client.CreateMergeRequestNote(ctx, g.Project, number, body)
+	}
+	return g.Client.CreateIssueNote(ctx, g.Project, number, body)
+}
+
+// GitLabHandler Handles the issue and note webhooks of GitLab.
+type GitLabHandler struct {
+	Bot *Bot
+	// Secret Is the secret token of the webhook, which every request must carry.
+	Secret []byte
+	Client *gitlab.Client
+	// mu Guards self, the username of the token's user.
+	mu   sync.Mutex
+	self string
+}
+
+// ServeHTTP Validates the webhook, and processes the issue in the background if the bot was asked to.
+func (h *GitLabHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, ok := readPayload(w, req)
+	if !ok {
+		return
+	}
+	if err := gitlab.ValidateToken(h.Secret, req.Header.Get(gitlab.HeaderWebhookToken)); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var event gitlab.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "could not decode the payload", http.StatusBadRequest)
+		return
+	}
+	name := req.Header.Get(gitlab.HeaderEvent)
+	job, reason := h.Bot.GitLabJob(name, &event)
+	if job == nil {
+		fmt.Fprintln(w, reason)
+		return
+	}
+	if name == gitlab.EventNote {
+		// the bot's notes quote the commands they answer, which would trigger it again
+		self, err := h.username(req.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if event.User.Username == self {
+			fmt.Fprintln(w, "ignored note by the bot")
+			return
+		}
+	}
+	// anyone who can open issues could otherwise get changes committed to the project
+	level, err := h.accessLevel(req.Context(), &event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if level < h.Bot.AccessLevel {
+		fmt.Fprintln(w, "ignored event by a user without enough access to the project")
+		return
+	}
+
+	forge := &GitLab{
+		Client:       h.Client,
+		Project:      event.Project.ID,
+		MergeRequest: event.ObjectAttributes.NoteableType == gitlab.NoteableMergeRequest,
+	}
+	ref := event.Project.PathWithNamespace + job.Reference
+	log.Printf("proposing changes for %s\n", ref)
+	h.Bot.Go(ref, func(ctx context.Context) error {
+		return h.Bot.Process(ctx, forge, *job)
+	})
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "accepted")
+}
+
+// username Returns the username of the token's user, which is only fetched once.
+func (h *GitLabHandler) username(ctx context.Context) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.self == "" {
+		user, err := h.Client.CurrentUser(ctx)
+		if err != nil {
+			return "", fmt.Errorf("could not read the user of the token: %w", err)
+		}
+		h.self = user.Username
+	}
+	return h.self, nil
+}
+
+// accessLevel Returns the access level to the project of the user who triggered the event,
+// which is only looked up when the bot requires one.
+func (h *GitLabHandler) accessLevel(ctx context.Context, event *gitlab.Event) (int, error) {
+	if h.Bot.AccessLevel <= gitlab.AccessNone {
+		return gitlab.AccessNone, nil
+	}
+	level, err := h.Client.AccessLevel(ctx, event.Project.ID, event.User.ID)
+	if err != nil {
+		return gitlab.AccessNone, fmt.Errorf("could not read the access level of %s: %w", event.User.Username, err)
+	}
+	return level, nil
+}
+
+// GitLabJob Returns the job requested by the event, or the reason why it requests none.
+// Issues are handled when they are opened with the bot's label or labeled with it, and
+// when a line of a note on them starts with the trigger. Such a note on a merge request
+// proposes the changes requested by the merge request's description
+// in a merge request of its own.
+func (b *Bot) GitLabJob(name string, event *gitlab.Event) (*Job, string) {
+	attributes := event.ObjectAttributes
+	switch name {
+	case gitlab.EventIssue:
+		job := b.gitlabIssueJob(event, attributes.Issue)
+		switch {
+		case attributes.Action == gitlab.ActionOpen && (b.Label == "" || gitlab.HasLabel(event.Labels, b.Label)):
+			return job, ""
+		case attributes.Action == gitlab.ActionUpdate && b.Label != "" && event.Changes.Labels != nil &&
+			!gitlab.HasLabel(event.Changes.Labels.Previous, b.Label) && gitlab.HasLabel(event.Changes.Labels.Current, b.Label):
+			return job, ""
+		}
+		return nil, "ignored issue without the " + b.Label + " label"
+	case gitlab.EventNote:
+		if !chatops.HasCommand(attributes.Note, b.Trigger) {
+			return nil, "ignored note without " + b.Trigger
+		}
+		var job *Job
+		switch {
+		case attributes.NoteableType == gitlab.NoteableIssue && event.Issue != nil:
+			job = b.gitlabIssueJob(event, *event.Issue)
+		case attributes.NoteableType == gitlab.NoteableMergeRequest && event.MergeRequest != nil:
+			mr := event.MergeRequest
+			job = &Job{
+				Number:    mr.IID,
+				Reference: fmt.Sprintf("!%d", mr.IID),
+				Base:      event.Project.DefaultBranch,
+				Branch:    fmt.Sprintf("%smr-%d", b.BranchPrefix, mr.IID),
+				Title:     mr.Title,
+				Body:      mr.Description,
+			}
+		default:
+			return nil, "ignored note on a " + attributes.NoteableType
+		}
+		job.Comment = attributes.Note
+		return job, ""
+	}
+	return nil, "ignored " + name + " event"
+}
+
+// gitlabIssueJob Returns the job which proposes the changes requested by a GitLab issue.
+func (b *Bot) gitlabIssueJob(event *gitlab.Event, iss gitlab.Issue) *Job {
+	return &Job{
+		Number:    iss.IID,
+		Reference: fmt.Sprintf("#%d", iss.IID),
+		Closes:    true,
+		Base:      event.Project.DefaultBranch,
+		Branch:    b.IssueBranch(iss.IID),
+		Title:     iss.Title,
+		Body:      iss.Description,
+	}
+}
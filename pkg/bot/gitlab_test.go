@@ -0,0 +1,246 @@
+package bot_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/bot"
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/gitlab"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+// fakeGitLab Is a stand-in for the parts of the GitLab API used by the bot.
+type fakeGitLab struct {
+	mu sync.Mutex
+	// openMR Is returned when listing merge requests, if set.
+	openMR  *gitlab.MergeRequest
+	commit  *gitlab.Commit
+	mr      *gitlab.MergeRequest
+	notes   map[string][]string
+	queries []string
+	// users Counts the requests for the token's user.
+	users int
+	// members Holds the access levels of the project's members by their IDs.
+	members map[string]int
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	Expect(r.Header.Get(gitlab.HeaderToken)).To(Equal("glpat-t0ken"))
+	if id, ok := strings.CutPrefix(r.URL.Path, "/projects/5/members/all/"); ok && r.Method == http.MethodGet {
+		level, member := f.members[id]
+		if !member {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"404 Not found"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%s,"username":"user-%s","access_level":%d}`, id, id, level)
+		return
+	}
+	switch r.Method + " " + r.URL.Path {
+	case "GET /user":
+		f.users++
+		fmt.Fprint(w, `{"id":9,"username":"copilot-ops-bot"}`)
+	case "POST /projects/5/repository/commits":
+		f.commit = &gitlab.Commit{}
+		Expect(json.NewDecoder(r.Body).Decode(f.commit)).To(Succeed())
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"new-commit"}`)
+	case "GET /projects/5/merge_requests":
+		f.queries = append(f.queries, r.URL.Query().Get("source_branch"))
+		mrs := []gitlab.MergeRequest{}
+		if f.openMR != nil {
+			mrs = append(mrs, *f.openMR)
+		}
+		Expect(json.NewEncoder(w).Encode(mrs)).To(Succeed())
+	case "POST /projects/5/merge_requests":
+		f.mr = &gitlab.MergeRequest{}
+		Expect(json.NewDecoder(r.Body).Decode(f.mr)).To(Succeed())
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"iid":12,"web_url":"https://gitlab.test/ops/gitops/-/merge_requests/12"}`)
+	case "POST /projects/5/issues/7/notes", "POST /projects/5/merge_requests/3/notes":
+		var note map[string]string
+		Expect(json.NewDecoder(r.Body).Decode(&note)).To(Succeed())
+		f.notes[r.URL.Path] = append(f.notes[r.URL.Path], note["body"])
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+var _ = Describe("GitLab bot", func() {
+	var (
+		fake     *fakeGitLab
+		ts       *httptest.Server
+		b        *bot.Bot
+		handler  *bot.GitLabHandler
+		received []*issue.Issue
+		commands []*chatops.Command
+		runErr   error
+	)
+
+	BeforeEach(func() {
+		fake = &fakeGitLab{notes: map[string][]string{}, members: map[string]int{
+			"11": gitlab.AccessDeveloper,
+			"12": gitlab.AccessReporter,
+		}}
+		ts = httptest.NewServer(fake)
+		received, commands, runErr = nil, nil, nil
+		b = bot.New(resizePVC(&received, &commands, &runErr))
+		handler = &bot.GitLabHandler{Bot: b, Secret: []byte("s3cret"), Client: gitlab.NewClient(ts.URL, "glpat-t0ken")}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	// deliver Sends a webhook to the handler and waits for the job it started.
+	deliver := func(event string, payload interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(payload)
+		Expect(err).NotTo(HaveOccurred())
+		req := httptest.NewRequest(http.MethodPost, bot.GitLabWebhookPath, bytes.NewReader(body))
+		req.Header.Set(gitlab.HeaderEvent, event)
+		req.Header.Set(gitlab.HeaderWebhookToken, "s3cret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		b.Wait()
+		return rec
+	}
+
+	project := gitlab.Project{ID: 5, PathWithNamespace: "ops/gitops", DefaultBranch: "main"}
+	alice := gitlab.User{ID: 11, Username: "alice"}
+	resize := gitlab.Issue{IID: 7, Title: "Resize the PVC", Description: "`@pvc:app/pvc.yaml`\n\n@pvc needs 100Gi"}
+
+	issueEvent := func(action string, labels ...string) gitlab.Event {
+		event := gitlab.Event{ObjectKind: "issue", User: alice, Project: project}
+		event.ObjectAttributes.Issue = resize
+		event.ObjectAttributes.Action = action
+		for _, label := range labels {
+			event.Labels = append(event.Labels, gitlab.Label{Title: label})
+		}
+		return event
+	}
+
+	It("opens a merge request with the changes requested by a labeled issue", func() {
+		rec := deliver(gitlab.EventIssue, issueEvent(gitlab.ActionOpen, bot.DefaultLabel))
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(received).To(HaveLen(1))
+
+		Expect(fake.commit).NotTo(BeNil())
+		Expect(fake.commit.Branch).To(Equal("copilot-ops/issue-7"))
+		Expect(fake.commit.StartBranch).To(Equal("main"))
+		Expect(fake.commit.Force).To(BeTrue())
+		Expect(fake.commit.Actions).To(Equal([]gitlab.CommitAction{
+			{Action: gitlab.FileActionUpdate, FilePath: "app/pvc.yaml", Content: "storage: 100Gi\n"},
+		}))
+
+		Expect(fake.mr).NotTo(BeNil())
+		Expect(fake.mr.SourceBranch).To(Equal("copilot-ops/issue-7"))
+		Expect(fake.mr.TargetBranch).To(Equal("main"))
+		Expect(fake.mr.Description).To(ContainSubstring("Closes #7."))
+
+		notes := fake.notes["/projects/5/issues/7/notes"]
+		Expect(notes).To(HaveLen(1))
+		Expect(notes[0]).To(ContainSubstring("https://gitlab.test/ops/gitops/-/merge_requests/12"))
+		Expect(notes[0]).To(ContainSubstring("- `app/pvc.yaml` (+1 -1)"))
+	})
+
+	It("handles issues when the label is added", func() {
+		event := issueEvent(gitlab.ActionUpdate, bot.DefaultLabel)
+		event.Changes.Labels = &gitlab.LabelChanges{
+			Previous: []gitlab.Label{},
+			Current:  []gitlab.Label{{Title: bot.DefaultLabel}},
+		}
+		Expect(deliver(gitlab.EventIssue, event).Code).To(Equal(http.StatusAccepted))
+
+		// other updates of labeled issues are ignored
+		event.Changes.Labels.Previous = event.Changes.Labels.Current
+		Expect(deliver(gitlab.EventIssue, event).Code).To(Equal(http.StatusOK))
+		Expect(received).To(HaveLen(1))
+	})
+
+	It("proposes the changes requested on a merge request in a merge request of its own", func() {
+		fake.openMR = &gitlab.MergeRequest{IID: 4, WebURL: "https://gitlab.test/ops/gitops/-/merge_requests/4"}
+		event := gitlab.Event{ObjectKind: "note", User: alice, Project: project}
+		event.ObjectAttributes.Note = "/copilot-ops and keep the labels"
+		event.ObjectAttributes.NoteableType = gitlab.NoteableMergeRequest
+		event.MergeRequest = &gitlab.Issue{IID: 3, Title: "Resize", Description: resize.Description}
+		Expect(deliver(gitlab.EventNote, event).Code).To(Equal(http.StatusAccepted))
+
+		Expect(received).To(HaveLen(1))
+		Expect(received[0].Request()).To(HavePrefix("Resize"))
+		Expect(commands[0].Request).To(Equal("and keep the labels"))
+		Expect(fake.commit.Branch).To(Equal("copilot-ops/mr-3"))
+		Expect(fake.queries).To(ConsistOf("copilot-ops/mr-3"))
+		Expect(fake.mr).To(BeNil())
+		Expect(fake.notes["/projects/5/merge_requests/3/notes"]).To(ConsistOf(ContainSubstring("merge_requests/4")))
+	})
+
+	It("ignores its own notes", func() {
+		event := gitlab.Event{ObjectKind: "note", Project: project, Issue: &resize}
+		event.User.Username = "copilot-ops-bot"
+		event.ObjectAttributes.Note = "Did you mean:\n/copilot-ops edit and keep the labels"
+		event.ObjectAttributes.NoteableType = gitlab.NoteableIssue
+		rec := deliver(gitlab.EventNote, event)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring("ignored note by the bot"))
+		Expect(received).To(BeEmpty())
+
+		event.User = alice
+		event.ObjectAttributes.Note = "/copilot-ops ask why 100Gi?"
+		Expect(deliver(gitlab.EventNote, event).Code).To(Equal(http.StatusAccepted))
+		Expect(received).To(HaveLen(1))
+		Expect(fake.users).To(Equal(1))
+	})
+
+	It("ignores the events of users without enough access to the project", func() {
+		event := gitlab.Event{ObjectKind: "note", Project: project, Issue: &resize}
+		event.ObjectAttributes.Note = "/copilot-ops add a .gitlab-ci.yml printing the variables"
+		event.ObjectAttributes.NoteableType = gitlab.NoteableIssue
+		for _, user := range []gitlab.User{{ID: 12, Username: "reporter"}, {ID: 13, Username: "stranger"}} {
+			event.User = user
+			rec := deliver(gitlab.EventNote, event)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("ignored event by a user without enough access"))
+		}
+		opened := issueEvent(gitlab.ActionOpen, bot.DefaultLabel)
+		opened.User = gitlab.User{ID: 13, Username: "stranger"}
+		Expect(deliver(gitlab.EventIssue, opened).Code).To(Equal(http.StatusOK))
+		Expect(received).To(BeEmpty())
+		Expect(fake.commit).To(BeNil())
+
+		b.AccessLevel = gitlab.AccessReporter
+		event.User = gitlab.User{ID: 12, Username: "reporter"}
+		Expect(deliver(gitlab.EventNote, event).Code).To(Equal(http.StatusAccepted))
+		Expect(received).To(HaveLen(1))
+
+		b.AccessLevel = gitlab.AccessNone
+		Expect(deliver(gitlab.EventIssue, opened).Code).To(Equal(http.StatusAccepted))
+		Expect(received).To(HaveLen(2))
+	})
+
+	It("ignores notes without the trigger and rejects requests without the token", func() {
+		event := gitlab.Event{ObjectKind: "note", Project: project, Issue: &resize}
+		event.ObjectAttributes.Note = "looks good"
+		event.ObjectAttributes.NoteableType = gitlab.NoteableIssue
+		Expect(deliver(gitlab.EventNote, event).Code).To(Equal(http.StatusOK))
+		Expect(received).To(BeEmpty())
+
+		req := httptest.NewRequest(http.MethodPost, bot.GitLabWebhookPath, bytes.NewReader([]byte(`{}`)))
+		req.Header.Set(gitlab.HeaderEvent, gitlab.EventIssue)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+	})
+})
@@ -0,0 +1,110 @@
+// candidates decodes the several edits returned for a single request and ranks
+// them, so that the most promising one can be selected.
+package candidates
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/redhat-et/copilot-ops/pkg/diff"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/validate"
+)
+
+// Candidate Is one of the edits returned by the backend, decoded on top of the original files.
+type Candidate struct {
+	// Index Is the position of the response the candidate was decoded from.
+	Index int `json:"index"`
+	// Filemap Holds the original files with the candidate's changes applied.
+	Filemap *filemap.Filemap `json:"-"`
+	// Error Describes why the response could not be decoded, and is empty otherwise.
+	Error string `json:"error,omitempty"`
+	// Validation Holds the outcome of validating every changed file.
+	Validation []validate.Result `json:"validation"`
+	// PassRate Is the fraction of changed files which passed validation.
+	PassRate float64 `json:"passRate"`
+	// Added and Removed Are the number of lines changed by the candidate.
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// Decode Decodes every response into its own copy of the original files, then
+// validates and measures the changes.
+func Decode(original *filemap.Filemap, responses []string) []Candidate {
+	candidates := make([]Candidate, 0, len(responses))
+	for i, response := range responses {
+		c := Candidate{Index: i, Filemap: original.Clone()}
+		if err := c.Filemap.DecodeFromOutput(response); err != nil {
+			c.Error = err.Error()
+		}
+		c.Validation = validate.Filemap(changedFiles(original, c.Filemap))
+		c.PassRate = validate.PassRate(c.Validation)
+		c.Added, c.Removed = diff.Stat(original, c.Filemap)
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// changedFiles Returns the files of after whose content differs from before.
+func changedFiles(before, after *filemap.Filemap) *filemap.Filemap {
+	changed := filemap.NewFilemap()
+	for tag, file := range after.Files {
+		if original, ok := before.Files[tag]; !ok || original.Content != file.Content {
+			changed.Files[tag] = file
+		}
+	}
+	return changed
+}
+
+// Changed Returns the number of lines the candidate changes.
+func (c Candidate) Changed() int {
+	return c.Added + c.Removed
+}
+
+// Rank Orders the candidates from best to worst: candidates which could be decoded
+// come first, then those with the highest validation pass rate, then those with
+// the smallest diff. Candidates which change nothing are ranked last.
+func Rank(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		if (a.Changed() == 0) != (b.Changed() == 0) {
+			return a.Changed() != 0
+		}
+		if a.PassRate != b.PassRate {
+			return a.PassRate > b.PassRate
+		}
+		return a.Changed() < b.Changed()
+	})
+}
+
+// Output Is the JSON document listing every candidate in ranked order.
+type Output struct {
+	Candidates []CandidateOutput `json:"candidates"`
+}
+
+// CandidateOutput Is a candidate along with its rank and files.
+type CandidateOutput struct {
+	Rank int `json:"rank"`
+	Candidate
+	Files []filemap.File `json:"files"`
+}
+
+// JSON Encodes the ranked candidates, numbering them from 1.
+func JSON(candidates []Candidate) (string, error) {
+	output := Output{Candidates: make([]CandidateOutput, 0, len(candidates))}
+	for i, c := range candidates {
+		files := make([]filemap.File, 0, len(c.Filemap.Files))
+		for _, tag := range c.Filemap.Tags() {
+			files = append(files, c.Filemap.Files[tag])
+		}
+		output.Candidates = append(output.Candidates, CandidateOutput{Rank: i + 1, Candidate: c, Files: files})
+	}
+	data, err := json.MarshalIndent(output, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,13 @@
+package candidates_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCandidates(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Candidates Suite")
+}
@@ -0,0 +1,64 @@
+package candidates_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/candidates"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+var _ = Describe("Candidates", func() {
+	var original *filemap.Filemap
+
+	BeforeEach(func() {
+		original = filemap.NewFilemap()
+		original.Files["pvc.yaml"] = filemap.File{Path: "app/pvc.yaml", Content: "kind: PersistentVolumeClaim\nstorage: 1Gi\n"}
+	})
+
+	It("decodes every response on top of its own copy of the files", func() {
+		cs := candidates.Decode(original, []string{
+			"# @pvc.yaml\nkind: PersistentVolumeClaim\nstorage: 100Gi\n",
+			"# @pvc.yaml\nkind: PersistentVolumeClaim\nstorage: 200Gi\n",
+		})
+		Expect(cs).To(HaveLen(2))
+		Expect(cs[0].Filemap.Files["pvc.yaml"].Content).To(ContainSubstring("100Gi"))
+		Expect(cs[1].Filemap.Files["pvc.yaml"].Content).To(ContainSubstring("200Gi"))
+		Expect(original.Files["pvc.yaml"].Content).To(ContainSubstring("1Gi"))
+		Expect(cs[0].Added).To(Equal(1))
+		Expect(cs[0].Removed).To(Equal(1))
+		Expect(cs[0].PassRate).To(Equal(1.0))
+	})
+
+	It("ranks valid, small and non-empty changes first", func() {
+		cs := candidates.Decode(original, []string{
+			"no tag here",
+			"# @pvc.yaml\nkind: PersistentVolumeClaim\nstorage: 1Gi\n",
+			"# @pvc.yaml\nkind: [PersistentVolumeClaim\nstorage: 100Gi\n",
+			"# @pvc.yaml\nkind: PersistentVolumeClaim\nstorage: 100Gi\nlabels:\n  app: db\n",
+			"# @pvc.yaml\nkind: PersistentVolumeClaim\nstorage: 100Gi\n",
+		})
+		candidates.Rank(cs)
+		indexes := make([]int, len(cs))
+		for i, c := range cs {
+			indexes[i] = c.Index
+		}
+		Expect(indexes).To(Equal([]int{4, 3, 2, 1, 0}))
+		Expect(cs[2].PassRate).To(Equal(0.0))
+		Expect(cs[4].Error).NotTo(BeEmpty())
+	})
+
+	It("encodes every candidate with its rank and files", func() {
+		cs := candidates.Decode(original, []string{"# @pvc.yaml\nstorage: 2Gi\n"})
+		out, err := candidates.JSON(cs)
+		Expect(err).NotTo(HaveOccurred())
+
+		var decoded candidates.Output
+		Expect(json.Unmarshal([]byte(out), &decoded)).To(Succeed())
+		Expect(decoded.Candidates).To(HaveLen(1))
+		Expect(decoded.Candidates[0].Rank).To(Equal(1))
+		Expect(decoded.Candidates[0].Files).To(ConsistOf(filemap.File{Path: "app/pvc.yaml", Content: "storage: 2Gi\n"}))
+	})
+})
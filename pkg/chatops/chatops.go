@@ -0,0 +1,219 @@
+// chatops parses the slash commands which ask copilot-ops for changes from issue,
+// pull request, and merge request comments, e.g.
+//
+//	/copilot-ops edit @deploy:apps/web/deploy.yaml bump memory to 1Gi
+package chatops
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+// DefaultTrigger Starts the commands in comments.
+const DefaultTrigger = "/copilot-ops"
+
+// Commands which can follow the trigger.
+const (
+	CommandEdit     = "edit"
+	CommandGenerate = "generate"
+	CommandAsk      = "ask"
+	// DefaultCommand Is run when the trigger is not followed by a command.
+	DefaultCommand = CommandEdit
+)
+
+// Options accepted by commands, named after the flags of the CLI.
+const (
+	OptionFileFull          = "file"
+	OptionFileShort         = "f"
+	OptionFilesetFull       = "fileset"
+	OptionFilesetShort      = "s"
+	OptionNCompletionsFull  = "ncompletions"
+	OptionNCompletionsShort = "c"
+	OptionNTokensFull       = "ntokens"
+	OptionNTokensShort      = "n"
+	OptionBackendFull       = "backend"
+	OptionBackendShort      = "b"
+	OptionModelFull         = "model"
+	OptionModelShort        = "m"
+)
+
+// ErrNoCommand Is returned when no line of the comment starts with the trigger.
+var ErrNoCommand = errors.New("the comment contains no command")
+
+// ReferencePattern Matches a word which references a file as @name:path, optionally
+// in backticks. Unlike in issues, the backticks may be left out since the reference
+// is a word of its own.
+var ReferencePattern = regexp.MustCompile(
+	"^`?" + regexp.QuoteMeta(filemap.FileTagPrefix) + "([a-zA-Z0-9_\\-]+):([^`]+)`?$")
+
+// Command Is a slash command parsed from a comment.
+type Command struct {
+	// Name Is one of CommandEdit, CommandGenerate, or CommandAsk.
+	Name       string
+	References []issue.Reference
+	Files      []string
+	Filesets   []string
+	// NCompletions Is 0 unless given as an option, as is NTokens.
+	NCompletions int32
+	NTokens      int32
+	Backend      string
+	Model        string
+	// Request Is the text of the command, where every reference is replaced by its @name,
+	// followed by the lines of the comment after the command's.
+	Request string
+}
+
+// Commands Returns the commands which can follow the trigger.
+func Commands() []string {
+	return []string{CommandEdit, CommandGenerate, CommandAsk}
+}
+
+// HasCommand Returns true when a line of the comment starts with the trigger.
+func HasCommand(comment, trigger string) bool {
+	_, _, ok := findCommand(comment, trigger)
+	return ok
+}
+
+// Parse Parses the command on the first line of the comment which starts with
+// the trigger. The lines before it are ignored, the lines after it are added to the request.
+func Parse(comment, trigger string) (*Command, error) {
+	line, rest, ok := findCommand(comment, trigger)
+	if !ok {
+		return nil, ErrNoCommand
+	}
+
+	c := &Command{Name: DefaultCommand, References: []issue.Reference{}}
+	words := strings.Fields(line)
+	if len(words) > 0 && isCommand(words[0]) {
+		c.Name, words = words[0], words[1:]
+	}
+
+	var request []string
+	paths := make(map[string]string)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if match := ReferencePattern.FindStringSubmatch(word); match != nil {
+			name, path := match[1], match[2]
+			if existing, ok := paths[name]; ok && existing != path {
+				return nil, fmt.Errorf("%s%s refers to both %q and %q", filemap.FileTagPrefix, name, existing, path)
+			} else if !ok {
+				paths[name] = path
+				c.References = append(c.References, issue.Reference{Name: name, Path: path})
+			}
+			request = append(request, filemap.FileTagPrefix+name)
+			continue
+		}
+		name, value, isOption := splitOption(word)
+		if !isOption {
+			request = append(request, word)
+			continue
+		}
+		if value == "" {
+			if i+1 == len(words) {
+				return nil, fmt.Errorf("option %s needs a value", word)
+			}
+			i++
+			value = words[i]
+		}
+		if err := c.setOption(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	c.Request = strings.TrimSpace(strings.Join(request, " ") + "\n" + rest)
+	return c, nil
+}
+
+// findCommand Returns the text following the trigger on the first line which starts
+// with it, and the lines after that line.
+func findCommand(comment, trigger string) (string, string, bool) {
+	if trigger == "" {
+		return "", "", false
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, trigger) {
+			continue
+		}
+		command := line[len(trigger):]
+		// the trigger has to be a word of its own
+		if command != "" && !strings.ContainsAny(command[:1], " \t") {
+			continue
+		}
+		return strings.TrimSpace(command), strings.Join(lines[i+1:], "\n"), true
+	}
+	return "", "", false
+}
+
+// isCommand Returns true when the word names a command.
+func isCommand(word string) bool {
+	for _, command := range Commands() {
+		if word == command {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOption Returns the option's name and its value, when given as --name=value.
+// Words which start with a dash but do not look like options, e.g. -1, are not options.
+func splitOption(word string) (string, string, bool) {
+	switch {
+	case strings.HasPrefix(word, "--") && len(word) > 2:
+		name, value, _ := strings.Cut(word[2:], "=")
+		return name, value, true
+	case len(word) == 2 && word[0] == '-' && isShortOption(word[1:]):
+		return word[1:], "", true
+	default:
+		return "", "", false
+	}
+}
+
+// isShortOption Returns true when the letter is the short name of an option.
+func isShortOption(name string) bool {
+	switch name {
+	case OptionFileShort, OptionFilesetShort, OptionNCompletionsShort,
+		OptionNTokensShort, OptionBackendShort, OptionModelShort:
+		return true
+	default:
+		return false
+	}
+}
+
+// setOption Sets the option given by its full or short name.
+func (c *Command) setOption(name, value string) error {
+	var err error
+	switch name {
+	case OptionFileFull, OptionFileShort:
+		c.Files = append(c.Files, value)
+	case OptionFilesetFull, OptionFilesetShort:
+		c.Filesets = append(c.Filesets, value)
+	case OptionNCompletionsFull, OptionNCompletionsShort:
+		c.NCompletions, err = parseCount(name, value)
+	case OptionNTokensFull, OptionNTokensShort:
+		c.NTokens, err = parseCount(name, value)
+	case OptionBackendFull, OptionBackendShort:
+		c.Backend = value
+	case OptionModelFull, OptionModelShort:
+		c.Model = value
+	default:
+		return fmt.Errorf("unknown option --%s", name)
+	}
+	return err
+}
+
+// parseCount Parses the value of an option which counts something.
+func parseCount(name, value string) (int32, error) {
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("option %s must be a positive number, got %q", name, value)
+	}
+	return int32(n), nil
+}
@@ -0,0 +1,13 @@
+package chatops_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestChatops(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Chatops Suite")
+}
@@ -0,0 +1,57 @@
+package chatops_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+var _ = Describe("Chatops", func() {
+	It("parses the command, its references, and its request", func() {
+		c, err := chatops.Parse("/copilot-ops edit @deploy:apps/web/deploy.yaml bump memory to 1Gi", chatops.DefaultTrigger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name).To(Equal(chatops.CommandEdit))
+		Expect(c.References).To(Equal([]issue.Reference{{Name: "deploy", Path: "apps/web/deploy.yaml"}}))
+		Expect(c.Request).To(Equal("@deploy bump memory to 1Gi"))
+	})
+
+	It("parses options anywhere on the command's line", func() {
+		c, err := chatops.Parse("Thanks!\n\n  /copilot-ops ask -c 2 why is `@svc:svc.yaml` failing --ntokens=300 "+
+			"--fileset=web -f a.yaml --model gpt-4 -b openai\nIt returns 503s.\n", chatops.DefaultTrigger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name).To(Equal(chatops.CommandAsk))
+		Expect(c.NCompletions).To(BeEquivalentTo(2))
+		Expect(c.NTokens).To(BeEquivalentTo(300))
+		Expect(c.Filesets).To(Equal([]string{"web"}))
+		Expect(c.Files).To(Equal([]string{"a.yaml"}))
+		Expect(c.Model).To(Equal("gpt-4"))
+		Expect(c.Backend).To(Equal("openai"))
+		Expect(c.Request).To(Equal("why is @svc failing\nIt returns 503s."))
+	})
+
+	It("runs the default command and keeps words which only look like options", func() {
+		c, err := chatops.Parse("/copilot-ops set the replicas to -1", chatops.DefaultTrigger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name).To(Equal(chatops.DefaultCommand))
+		Expect(c.Request).To(Equal("set the replicas to -1"))
+	})
+
+	It("rejects comments without a command and invalid options", func() {
+		_, err := chatops.Parse("please /copilot-ops edit", chatops.DefaultTrigger)
+		Expect(err).To(MatchError(chatops.ErrNoCommand))
+		_, err = chatops.Parse("/copilot-opsy edit", chatops.DefaultTrigger)
+		Expect(err).To(MatchError(chatops.ErrNoCommand))
+		Expect(chatops.HasCommand("lgtm\n/copilot-ops", chatops.DefaultTrigger)).To(BeTrue())
+
+		_, err = chatops.Parse("/copilot-ops edit --color red", chatops.DefaultTrigger)
+		Expect(err).To(MatchError(ContainSubstring("unknown option --color")))
+		_, err = chatops.Parse("/copilot-ops edit -c many", chatops.DefaultTrigger)
+		Expect(err).To(MatchError(ContainSubstring("positive number")))
+		_, err = chatops.Parse("/copilot-ops edit -n", chatops.DefaultTrigger)
+		Expect(err).To(MatchError(ContainSubstring("needs a value")))
+		_, err = chatops.Parse("/copilot-ops edit @a:x.yaml @a:y.yaml", chatops.DefaultTrigger)
+		Expect(err).To(MatchError(ContainSubstring("refers to both")))
+	})
+})
@@ -1,72 +1,204 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"sort"
+	"strings"
 
-	"github.com/sashabaranov/go-openai"
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
 	"github.com/spf13/cobra"
 )
 
-// NewAskCmd creates a new ask command which communicates with the ChatGPT API.
+// AskResult Is the JSON output of the ask command.
+type AskResult struct {
+	Question string     `json:"question"`
+	Answer   string     `json:"answer"`
+	Backend  ai.Backend `json:"backend"`
+	Model    string     `json:"model,omitempty"`
+	Files    []string   `json:"files,omitempty"`
+}
+
+// NewAskCmd creates a new ask command which communicates with a chat model.
 func NewAskCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   CommandAsk,
-		Short: "Ask ChatGPT a question and get an answer",
-		Long:  "Ask talks to the ChatGPT API and returns the response in your terminal.",
+		Use:   CommandAsk + " QUESTION",
+		Short: "Ask a question and get an answer",
+		Long: "Ask sends a question to a chat model of the selected backend and prints the answer to STDOUT. " +
+			"Files can be attached for context, and the question is read from STDIN when given as '" + StdinArg + "'.",
 		Example: "	copilot-ops ask 'Write a BASH script that checks the weather once every 5 minutes" +
-			" and sends an email if it's raining.'",
+			" and sends an email if it's raining.'\n" +
+			"	copilot-ops ask --fileset app1 'Which storage class does the PVC use?'\n" +
+			"	cat question.txt | copilot-ops ask --output json -",
 		RunE: RunAsk,
 		Args: cobra.ExactArgs(1),
 	}
 
-	// add flags
-	// cmd.Flags().String()
+	AddFileFlags(cmd)
+	AddBackendFlags(cmd)
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, filemap.OutputPlain,
+		"How to format output: plain or json",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Chat model to use (defaults to gpt-3.5-turbo for gpt-3, and the configured model for ollama)",
+	)
+
+	cmd.Flags().String(
+		FlagSystemFull, DefaultSystemPrompt,
+		"System message which sets the behavior of the model",
+	)
+
 	return cmd
 }
 
-// RunAsk Runs the command to talk with the ChatGPT API and return the response.
+// RunAsk Runs the command to talk with the chat model and return the response.
 func RunAsk(cmd *cobra.Command, args []string) error {
-	ctx := cmd.Context()
-	var client *openai.Client
-	// request
-	request := args[0]
-	if request == "" {
-		return fmt.Errorf("no request provided")
+	question, err := readQuestion(cmd.InOrStdin(), args[0])
+	if err != nil {
+		return err
 	}
-	// grab envs
-	openaiKey := os.Getenv("OPENAI_API_KEY")
-	openaiOrgID := os.Getenv("OPENAI_ORG_ID")
-	// create OpenAI client & send request
-	if openaiOrgID != "" {
-		openaiConfig := openai.DefaultConfig(openaiKey)
-		openaiConfig.OrgID = openaiOrgID
-		client = openai.NewClientWithConfig(openaiConfig)
-	} else {
-		client = openai.NewClient(openaiKey)
+
+	r, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
 	}
-	// create chat response
-	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: "gpt-3.5-turbo",
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    "system",
-				Content: "You are a large language model trained by OpenAI.",
-			},
-			{
-				Role:    "user",
-				Content: request,
-			},
-		},
-	})
+	r.UserRequest = question
+
+	answer, err := Answer(r)
 	if err != nil {
-		return fmt.Errorf("could not create chat completion: %w", err)
+		return err
 	}
-	// print response to STDOUT
-	if len(resp.Choices) == 0 {
-		return fmt.Errorf("no response from API")
+
+	return printAnswer(cmd.OutOrStdout(), r, answer)
+}
+
+// Answer Asks the chat model the user request, with the request's files as context.
+func Answer(r *Request) (string, error) {
+	client, err := PrepareChatClient(r, PrepareAskMessages(r))
+	if err != nil {
+		return "", fmt.Errorf("could not create client: %w", err)
+	}
+	return client.Chat()
+}
+
+// StreamAnswer Asks the chat model the user request as Answer does, calling send with
+// every part of the answer as it arrives. Backends which cannot stream send the whole answer at once.
+func StreamAnswer(r *Request, send func(text string) error) (string, error) {
+	client, err := PrepareChatClient(r, PrepareAskMessages(r))
+	if err != nil {
+		return "", fmt.Errorf("could not create client: %w", err)
+	}
+	if streaming, ok := client.(ai.StreamingChatClient); ok {
+		return streaming.ChatStream(send)
+	}
+	answer, err := client.Chat()
+	if err != nil {
+		return "", err
+	}
+	return answer, send(answer)
+}
+
+// PrepareChatClient Returns a Chat client depending on which backend was selected by the user.
+func PrepareChatClient(r *Request, messages []ai.Message) (ai.ChatClient, error) {
+	var client ai.ChatClient
+	switch r.Backend {
+	case ai.GPT3:
+		if r.Config.OpenAI == nil {
+			return nil, fmt.Errorf("no config provided for gpt-3")
+		}
+		client = gpt3.CreateGPT3ChatClient(r.Context(), *r.Config.OpenAI, r.Model, messages)
+	case ai.OLLAMA:
+		conf, err := ollamaConfig(r)
+		if err != nil {
+			return nil, err
+		}
+		client = ollama.CreateOllamaChatClient(r.Context(), conf, r.Model, messages)
+	case ai.GPTJ:
+		return nil, fmt.Errorf("gpt-j does not implement the chat client")
+	case ai.BLOOM:
+		return nil, fmt.Errorf("bloom does not implement the chat client")
+	case ai.OPT:
+		return nil, fmt.Errorf("opt does not implement the chat client")
+	case ai.Unselected:
+		return nil, fmt.Errorf("no backend selected")
+	default:
+		return nil, fmt.Errorf("invalid backend selected")
+	}
+	return client, nil
+}
+
+// PrepareAskMessages Builds the conversation sent to the chat model, attaching
+// the encoded files as context when any were provided.
+func PrepareAskMessages(r *Request) []ai.Message {
+	systemPrompt := r.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+	content := r.UserRequest
+	if strings.TrimSpace(r.FilemapText) != "" {
+		content = fmt.Sprintf(
+			"The following files are provided for context. Each file begins with a '# %stagname' line, "+
+				"and files are separated by '%s'.\n\n%s\n\nQuestion:\n%s",
+			filemap.FileTagPrefix, filemap.FileDelimeter, r.FilemapText, r.UserRequest,
+		)
+	}
+	return []ai.Message{
+		{Role: ai.RoleSystem, Content: systemPrompt},
+		{Role: ai.RoleUser, Content: content},
+	}
+}
+
+// readQuestion Returns the question given on the command-line, or reads it from STDIN.
+func readQuestion(stdin io.Reader, arg string) (string, error) {
+	question := arg
+	if arg == StdinArg {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("could not read question from stdin: %w", err)
+		}
+		question = string(input)
+	}
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", fmt.Errorf("no request provided")
+	}
+	return question, nil
+}
+
+// printAnswer Writes the answer to the given writer in the requested output format.
+func printAnswer(w io.Writer, r *Request, answer string) error {
+	switch r.OutputType {
+	case filemap.OutputPlain:
+		_, err := fmt.Fprintln(w, answer)
+		return err
+	case filemap.OutputJSON:
+		result := AskResult{
+			Question: r.UserRequest,
+			Answer:   answer,
+			Backend:  r.Backend,
+			Model:    r.Model,
+		}
+		for _, file := range r.Filemap.Files {
+			result.Files = append(result.Files, file.Path)
+		}
+		sort.Strings(result.Files)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(result)
+	default:
+		return fmt.Errorf("invalid output type %q", r.OutputType)
 	}
-	chatResponse := resp.Choices[0]
-	os.Stderr.WriteString(chatResponse.Message.Content + "\n")
-	return nil
 }
@@ -0,0 +1,102 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+var _ = Describe("Ask command", func() {
+	var (
+		c      *cobra.Command
+		ts     *httptest.Server
+		stdout *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		ts = OpenAITestServer()
+		ts.Start()
+		stdout = &bytes.Buffer{}
+		c = cmd.NewAskCmd()
+		c.SetOut(stdout)
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagAIBackendFull, string(ai.GPT3))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("prints the answer to stdout", func() {
+		Expect(cmd.RunAsk(c, []string{"what is a pod?"})).To(Succeed())
+		Expect(stdout.String()).To(Equal("answer to: what is a pod?\n"))
+	})
+
+	It("reads the question from stdin", func() {
+		c.SetIn(strings.NewReader("what is a service?\n"))
+		Expect(cmd.RunAsk(c, []string{cmd.StdinArg})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("answer to: what is a service?"))
+	})
+
+	It("includes files as context and prints json", func() {
+		Expect(c.Flags().Set(cmd.FlagFilesFull, "../../examples/app1/mysql-pvc.yaml")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagOutputTypeFull, filemap.OutputJSON)).To(Succeed())
+		Expect(cmd.RunAsk(c, []string{"how big is the PVC?"})).To(Succeed())
+
+		var result cmd.AskResult
+		Expect(json.Unmarshal(stdout.Bytes(), &result)).To(Succeed())
+		Expect(result.Question).To(Equal("how big is the PVC?"))
+		Expect(result.Answer).To(ContainSubstring("# @mysql-pvc.yaml"))
+		Expect(result.Files).To(ConsistOf("../../examples/app1/mysql-pvc.yaml"))
+	})
+
+	It("uses OPENAI_URL unless the flag is given", func() {
+		flagged := false
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			flagged = true
+			http.NotFound(w, r)
+		}))
+		defer other.Close()
+
+		c = cmd.NewAskCmd()
+		c.SetOut(stdout)
+		Expect(c.Flags().Set(cmd.FlagAIBackendFull, string(ai.GPT3))).To(Succeed())
+		GinkgoT().Setenv("OPENAI_URL", ts.URL+gpt3.OpenAIEndpointV1)
+		Expect(cmd.RunAsk(c, []string{"what is a pod?"})).To(Succeed())
+		Expect(stdout.String()).To(Equal("answer to: what is a pod?\n"))
+		Expect(flagged).To(BeFalse())
+
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, other.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(cmd.RunAsk(c, []string{"what is a pod?"})).NotTo(Succeed())
+		Expect(flagged).To(BeTrue())
+	})
+
+	It("rejects empty questions", func() {
+		Expect(cmd.RunAsk(c, []string{"  "})).To(HaveOccurred())
+	})
+
+	It("rejects an invalid ollama timeout instead of using the default", func() {
+		r := &cmd.Request{Backend: ai.OLLAMA, Config: config.Config{Ollama: &ollama.Config{Timeout: "soon"}}}
+		_, err := cmd.PrepareChatClient(r, nil)
+		Expect(err).To(MatchError(ContainSubstring(`invalid ollama timeout "soon"`)))
+		_, err = cmd.PrepareEditClient(r, "kind: Pod", "rename it")
+		Expect(err).To(MatchError(ContainSubstring(`invalid ollama timeout "soon"`)))
+
+		r.Config.Ollama.Timeout = "2m"
+		_, err = cmd.PrepareChatClient(r, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/redhat-et/copilot-ops/pkg/batch"
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/spf13/cobra"
+)
+
+// NewBatchCmd Creates the `copilot-ops batch` CLI command.
+func NewBatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandBatch + " FILE",
+
+		Short: "Runs the requests of a JSONL file concurrently",
+
+		Long: "Batch runs every line of a JSONL file as a request, with --" + FlagWorkersFull + " of them at once. " +
+			"Each line holds the request, and optionally its id, its command (one of " +
+			strings.Join(chatops.Commands(), ", ") + "), the files and filesets to load, " +
+			"and the ncompletions, ntokens, backend, and model overriding the flags. The command defaults to " +
+			CommandEdit + " when files are given, and to " + CommandGenerate + " otherwise.\n\n" +
+			"The outcome of every item is appended to the --" + FlagResultsFull + " file as soon as it completes, " +
+			"holding either the result envelope the API returns, or the error. Items which already have an " +
+			"outcome are skipped, so an interrupted batch resumes when run again. Files are never written.",
+
+		Example: `  copilot-ops batch requests.jsonl --workers 8
+  echo '{"id": "pvc", "request": "Increase the size to 100Gi", "files": ["app/pvc.yaml"]}' > requests.jsonl
+  copilot-ops batch requests.jsonl --results out.jsonl --retry-failed`,
+
+		RunE: RunBatch,
+		Args: cobra.ExactArgs(1),
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the directory which the files of the items are relative to",
+	)
+
+	cmd.Flags().String(
+		FlagResultsFull, "",
+		"File to append the outcomes to (defaults to the input with the "+ResultsSuffix+" extension)",
+	)
+
+	cmd.Flags().Int(
+		FlagWorkersFull, DefaultWorkers,
+		"Number of items to run at once",
+	)
+
+	cmd.Flags().Bool(
+		FlagRetryFailedFull, false,
+		"Run the items which failed the last time again",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNCompletionsFull, FlagNCompletionsShort, DefaultCompletions,
+		"Number of edits to request, unless the item sets it",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNTokensFull, FlagNTokensShort, DefaultTokens,
+		"Max number of tokens to generate, unless the item sets it",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Model of the backend to use, unless the item sets it",
+	)
+
+	AddBackendFlags(cmd)
+
+	return cmd
+}
+
+// RunBatch Runs when the `batch` command is invoked.
+func RunBatch(cmd *cobra.Command, args []string) error {
+	input := args[0]
+	results, _ := cmd.Flags().GetString(FlagResultsFull)
+	workers, _ := cmd.Flags().GetInt(FlagWorkersFull)
+	retryFailed, _ := cmd.Flags().GetBool(FlagRetryFailedFull)
+	if workers < 1 {
+		return fmt.Errorf("--%s must be positive", FlagWorkersFull)
+	}
+	if results == "" {
+		results = strings.TrimSuffix(input, filepath.Ext(input)) + ResultsSuffix
+	}
+
+	// the input and the results are relative to where the command was run, so they are opened before changing to --path
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	items, err := batch.ReadItems(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", input, err)
+	}
+	output, done, err := batch.OpenOutput(results)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	base, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// a second interrupt stops the items which are running
+		stop()
+	}()
+
+	b := batch.NewBatch(BatchRunner(base), workers, output)
+	b.RetryFailed = retryFailed
+	log.Printf("running %d items with %d workers, appending the outcomes to %s\n", len(items), workers, results)
+	summary, err := b.Process(ctx, items, done)
+	fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", results, summary)
+	switch {
+	case err != nil:
+		return err
+	case summary.Pending > 0:
+		return fmt.Errorf("interrupted with %d items left, run the batch again to resume", summary.Pending)
+	case summary.Failed > 0:
+		return fmt.Errorf("%d items failed, see %s, or run the batch again with --%s",
+			summary.Failed, results, FlagRetryFailedFull)
+	}
+	return nil
+}
+
+// BatchRunner Returns the runner of a batch's items, which runs each item's command
+// on a copy of the base request, as a slash command would.
+func BatchRunner(base *Request) batch.Runner {
+	return func(ctx context.Context, item *batch.Item) (interface{}, error) {
+		command := &chatops.Command{
+			Name:         item.Command,
+			Files:        item.Files,
+			Filesets:     item.Filesets,
+			NCompletions: item.NCompletions,
+			NTokens:      item.NTokens,
+			Backend:      item.Backend,
+			Model:        item.Model,
+			Request:      strings.TrimSpace(item.Request),
+		}
+		if command.Name == "" {
+			command.Name = CommandGenerate
+			if len(item.Files) > 0 || len(item.Filesets) > 0 {
+				command.Name = CommandEdit
+			}
+		}
+
+		r := *base
+		r.SetContext(ctx)
+		r.UserRequest = ""
+		r.Candidates = nil
+		r.Parent, r.ParentRequest = "", ""
+		r.Filemap = filemap.NewFilemap()
+		if err := ApplyCommand(&r, command); err != nil {
+			return nil, err
+		}
+		answer, err := ProposeCommand(&r)
+		if err != nil {
+			return nil, err
+		}
+		return APIResult(&r, answer), nil
+	}
+}
@@ -0,0 +1,78 @@
+package cmd_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/batch"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+)
+
+var _ = Describe("Batch command", func() {
+	var (
+		c      *cobra.Command
+		ts     *httptest.Server
+		stdout *bytes.Buffer
+		cwd    string
+	)
+
+	BeforeEach(func() {
+		ts = OpenAITestServer()
+		ts.Start()
+		stdout = &bytes.Buffer{}
+		c = cmd.NewBatchCmd()
+		c.SetOut(stdout)
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagAIBackendFull, string(ai.GPT3))).To(Succeed())
+
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+		Expect(os.WriteFile("pod.yaml", []byte("kind: Pod\n"), 0o600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+		ts.Close()
+	})
+
+	It("is registered on the root command", func() {
+		found, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandBatch})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name()).To(Equal(cmd.CommandBatch))
+	})
+
+	It("records the outcome of every item, and skips them when run again", func() {
+		Expect(os.WriteFile("requests.jsonl", []byte(`{"id": "pod", "request": "Add a pod running nginx"}
+{"id": "ask", "command": "ask", "request": "What is @pod.yaml?", "files": ["pod.yaml"]}
+{"id": "edit", "request": "Rename the pod", "files": ["missing.yaml"]}
+`), 0o600)).To(Succeed())
+
+		err := cmd.RunBatch(c, []string{"requests.jsonl"})
+		Expect(err).To(MatchError(ContainSubstring("1 items failed")))
+		Expect(stdout.String()).To(ContainSubstring("2 succeeded, 1 failed, 0 skipped"))
+
+		f, err := os.Open("requests" + cmd.ResultsSuffix)
+		Expect(err).NotTo(HaveOccurred())
+		outcomes, err := batch.ReadOutcomes(f)
+		Expect(f.Close()).To(Succeed())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes).To(HaveLen(3))
+		Expect(outcomes["pod"].Error).To(BeEmpty())
+		Expect(outcomes["pod"].Result).To(HaveKey("result"))
+		Expect(outcomes["ask"].Result).To(HaveKeyWithValue("answer", ContainSubstring("What is @pod.yaml?")))
+		Expect(outcomes["edit"].Error).To(ContainSubstring("no files to edit"))
+
+		stdout.Reset()
+		Expect(cmd.RunBatch(c, []string{"requests.jsonl"})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("0 succeeded, 0 failed, 3 skipped"))
+	})
+})
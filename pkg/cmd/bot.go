@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/bot"
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/github"
+	"github.com/redhat-et/copilot-ops/pkg/gitlab"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+	"github.com/spf13/cobra"
+)
+
+// Define the timeouts of the bot's server.
+const (
+	BotReadHeaderTimeout = 10 * time.Second
+	BotShutdownTimeout   = 30 * time.Second
+)
+
+// NewBotCmd Creates the `copilot-ops bot` CLI command.
+func NewBotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandBot,
+
+		Short: "Serves webhooks, proposing the changes requested in issues as pull or merge requests",
+
+		Long: "Bot receives the issue and comment webhooks of GitHub at " + bot.GitHubWebhookPath +
+			", and the issue and note webhooks of GitLab at " + bot.GitLabWebhookPath + ". " +
+			"When an issue is opened with the bot's label, is labeled with it, or gets a comment with a line " +
+			"starting with the trigger, the bot runs the command of the comment as the " + CommandComment +
+			" command does, or edits the files the issue references in the checkout given with --path. " +
+			"Issues which reference no files ask for new ones, which are generated instead. The changes are " +
+			"committed to a branch, and a pull or merge request describing them is opened. On GitHub, only the " +
+			"issues and comments of the users with the author associations given with --" + FlagAssociationsFull +
+			" are handled, and on GitLab, only the events of the users with at least the access level given with --" +
+			FlagAccessLevelFull + ". The files issues and comments name must be within the checkout, and the " +
+			"definitions of CI pipelines, e.g. .github/workflows or .gitlab-ci.yml, are only changed with --" +
+			FlagAllowCIFull + ".\n\n" +
+			"GitHub's webhook secret is read from " + EnvGitHubWebhookSecret + ". The bot authenticates with " +
+			EnvGitHubToken + ", or as a GitHub App with " + EnvGitHubAppID + " and the private key file in " +
+			EnvGitHubAppPrivateKey + ". GitLab's webhook secret is read from " + EnvGitLabWebhookSecret +
+			", and the bot authenticates with " + EnvGitLabToken + ". " +
+			"The checkout should be kept up to date with the default branch.",
+
+		Example: `  GITHUB_WEBHOOK_SECRET=... GITHUB_TOKEN=... copilot-ops bot --path ./checkout
+  copilot-ops bot --listen :9000 --github-url https://github.example.com/api/v3
+  GITLAB_WEBHOOK_SECRET=... GITLAB_TOKEN=... copilot-ops bot --gitlab-url https://gitlab.example.com/api/v4`,
+
+		RunE: RunBot,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the checkout of the repo",
+	)
+
+	cmd.Flags().String(
+		FlagListenFull, DefaultListenAddress,
+		"Address to serve the webhooks on",
+	)
+
+	cmd.Flags().String(
+		FlagGitHubURLFull, github.DefaultBaseURL,
+		"URL of the GitHub API",
+	)
+
+	cmd.Flags().String(
+		FlagGitLabURLFull, gitlab.DefaultBaseURL,
+		"URL of the GitLab API",
+	)
+
+	cmd.Flags().String(
+		FlagLabelFull, bot.DefaultLabel,
+		"Label which asks the bot to handle an issue (empty handles every new issue)",
+	)
+
+	cmd.Flags().String(
+		FlagTriggerFull, chatops.DefaultTrigger,
+		"Text starting the line of the command in comments which ask the bot to handle an issue",
+	)
+
+	cmd.Flags().StringSlice(
+		FlagAssociationsFull, bot.DefaultAssociations(),
+		"Author associations of the GitHub users whose issues and comments are handled (empty handles anyone's)",
+	)
+
+	cmd.Flags().String(
+		FlagAccessLevelFull, "developer",
+		"Minimum access level to the project of the GitLab users who may open, label, or comment on "+
+			"the issues the bot handles: guest, reporter, developer, maintainer, or owner (empty handles anyone's)",
+	)
+
+	cmd.Flags().Bool(
+		FlagAllowCIFull, false,
+		"Allow the proposed changes to touch the definitions of CI pipelines, which run with the project's secrets",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNCompletionsFull, FlagNCompletionsShort, DefaultCompletions,
+		"Number of edits to request, the best one is proposed",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNTokensFull, FlagNTokensShort, DefaultTokens,
+		"Max number of tokens to generate, for issues which reference no files",
+	)
+
+	AddBackendFlags(cmd)
+
+	return cmd
+}
+
+// RunBot Runs when the `bot` command is invoked.
+func RunBot(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString(FlagListenFull)
+	githubURL, _ := cmd.Flags().GetString(FlagGitHubURLFull)
+	gitlabURL, _ := cmd.Flags().GetString(FlagGitLabURLFull)
+	label, _ := cmd.Flags().GetString(FlagLabelFull)
+	trigger, _ := cmd.Flags().GetString(FlagTriggerFull)
+	associations, _ := cmd.Flags().GetStringSlice(FlagAssociationsFull)
+	accessLevelName, _ := cmd.Flags().GetString(FlagAccessLevelFull)
+	allowCI, _ := cmd.Flags().GetBool(FlagAllowCIFull)
+	accessLevel, err := gitlab.ParseAccessLevel(accessLevelName)
+	if err != nil {
+		return err
+	}
+
+	base, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	b := bot.New(IssueRunner(base))
+	b.Label, b.Trigger, b.Associations, b.AccessLevel = label, trigger, associations, accessLevel
+	b.AllowCI = allowCI
+
+	mux, err := NewBotMux(b, os.Getenv, githubURL, gitlabURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return ServeBot(ctx, listen, mux, b)
+}
+
+// IssueRunner Returns a runner which runs the command of the comment that asked
+// for changes, starting each time from a copy of the base request. Without a command,
+// the edits requested by the issue are proposed, or new files are generated when it
+// references none. Since anyone may write issues, the files they name are confined to the checkout.
+func IssueRunner(base *Request) bot.Runner {
+	return func(ctx context.Context, iss *issue.Issue, command *chatops.Command) (*bot.Proposal, error) {
+		r := *base
+		r.SetContext(ctx)
+		r.Untrusted = true
+		r.Filemap = filemap.NewFilemap()
+		r.Original = filemap.NewFilemap()
+		r.Candidates = nil
+		r.UserRequest = ""
+		if command == nil {
+			command = &chatops.Command{Name: chatops.CommandEdit}
+			if len(iss.References) == 0 {
+				command.Name = chatops.CommandGenerate
+			}
+		}
+		if err := ApplyCommand(&r, command); err != nil {
+			return nil, err
+		}
+		if err := AddIssue(&r, iss); err != nil {
+			return nil, err
+		}
+		answer, err := ProposeCommand(&r)
+		if err != nil {
+			return nil, err
+		}
+		return &bot.Proposal{Request: r.UserRequest, Before: r.Original, After: r.Filemap, Answer: answer}, nil
+	}
+}
+
+// NewBotMux Returns the handler of the webhooks of every forge whose webhook secret is set.
+func NewBotMux(b *bot.Bot, getenv func(string) string, githubURL, gitlabURL string) (*http.ServeMux, error) {
+	mux := http.NewServeMux()
+	if getenv(EnvGitHubWebhookSecret) == "" && getenv(EnvGitLabWebhookSecret) == "" {
+		return nil, fmt.Errorf("set %s or %s to serve the webhooks of GitHub or GitLab",
+			EnvGitHubWebhookSecret, EnvGitLabWebhookSecret)
+	}
+	if getenv(EnvGitHubWebhookSecret) != "" {
+		handler, err := NewGitHubHandler(b, getenv, githubURL)
+		if err != nil {
+			return nil, err
+		}
+		mux.Handle(bot.GitHubWebhookPath, handler)
+	}
+	if getenv(EnvGitLabWebhookSecret) != "" {
+		token := getenv(EnvGitLabToken)
+		if token == "" {
+			return nil, fmt.Errorf("%s must be set to authenticate with GitLab", EnvGitLabToken)
+		}
+		mux.Handle(bot.GitLabWebhookPath, &bot.GitLabHandler{
+			Bot:    b,
+			Secret: []byte(getenv(EnvGitLabWebhookSecret)),
+			Client: gitlab.NewClient(gitlabURL, token),
+		})
+	}
+	return mux, nil
+}
+
+// NewGitHubHandler Returns the handler of GitHub's webhooks, authenticated with the
+// token or the GitHub App found in the environment.
+func NewGitHubHandler(b *bot.Bot, getenv func(string) string, baseURL string) (*bot.GitHubHandler, error) {
+	handler := &bot.GitHubHandler{Bot: b, Secret: []byte(getenv(EnvGitHubWebhookSecret))}
+	if len(handler.Secret) == 0 {
+		return nil, fmt.Errorf("%s must be set to validate the webhooks", EnvGitHubWebhookSecret)
+	}
+
+	if appID := getenv(EnvGitHubAppID); appID != "" {
+		id, err := strconv.ParseInt(appID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a number: %w", EnvGitHubAppID, err)
+		}
+		pem, err := os.ReadFile(getenv(EnvGitHubAppPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("could not read the file in %s: %w", EnvGitHubAppPrivateKey, err)
+		}
+		key, err := github.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, err
+		}
+		app := &github.App{ID: id, Key: key, BaseURL: baseURL}
+		handler.Client = func(ctx context.Context, installationID int64) (*github.Client, error) {
+			if installationID == 0 {
+				return nil, errors.New("the webhook was not sent by an installation of the app")
+			}
+			return app.InstallationClient(ctx, installationID)
+		}
+		return handler, nil
+	}
+
+	token := getenv(EnvGitHubToken)
+	if token == "" {
+		return nil, fmt.Errorf("set %s, or %s and %s to authenticate with GitHub",
+			EnvGitHubToken, EnvGitHubAppID, EnvGitHubAppPrivateKey)
+	}
+	client := github.NewClient(baseURL, token)
+	handler.Client = func(context.Context, int64) (*github.Client, error) {
+		return client, nil
+	}
+	return handler, nil
+}
+
+// ServeBot Serves the handler until ctx is done, then waits for the jobs in progress.
+func ServeBot(ctx context.Context, addr string, handler http.Handler, b *bot.Bot) error {
+	log.Printf("serving webhooks on %s\n", addr)
+	err := ListenAndServe(ctx, &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: BotReadHeaderTimeout},
+		BotShutdownTimeout)
+	log.Println("waiting for the jobs in progress")
+	b.Wait()
+	return err
+}
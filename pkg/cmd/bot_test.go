@@ -0,0 +1,167 @@
+package cmd_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/bot"
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+var _ = Describe("Bot command", func() {
+	It("is registered on the root command", func() {
+		c, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandBot})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name()).To(Equal(cmd.CommandBot))
+	})
+
+	It("requires a webhook secret and credentials", func() {
+		env := map[string]string{}
+		getenv := func(key string) string { return env[key] }
+		b := bot.New(nil)
+
+		_, err := cmd.NewGitHubHandler(b, getenv, "")
+		Expect(err).To(MatchError(ContainSubstring(cmd.EnvGitHubWebhookSecret)))
+
+		env[cmd.EnvGitHubWebhookSecret] = "s3cret"
+		_, err = cmd.NewGitHubHandler(b, getenv, "")
+		Expect(err).To(MatchError(ContainSubstring(cmd.EnvGitHubToken)))
+
+		env[cmd.EnvGitHubAppID] = "42"
+		env[cmd.EnvGitHubAppPrivateKey] = filepath.Join(GinkgoT().TempDir(), "missing.pem")
+		_, err = cmd.NewGitHubHandler(b, getenv, "")
+		Expect(err).To(MatchError(ContainSubstring(cmd.EnvGitHubAppPrivateKey)))
+
+		delete(env, cmd.EnvGitHubAppID)
+		env[cmd.EnvGitHubToken] = "t0ken"
+		handler, err := cmd.NewGitHubHandler(b, getenv, "https://github.example.com/api/v3")
+		Expect(err).NotTo(HaveOccurred())
+		client, err := handler.Client(context.Background(), 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.BaseURL).To(Equal("https://github.example.com/api/v3"))
+		Expect(client.Token).To(Equal("t0ken"))
+	})
+
+	It("serves the webhooks of the forges whose secret is set", func() {
+		env := map[string]string{}
+		getenv := func(key string) string { return env[key] }
+		b := bot.New(nil)
+
+		_, err := cmd.NewBotMux(b, getenv, "", "")
+		Expect(err).To(MatchError(ContainSubstring(cmd.EnvGitLabWebhookSecret)))
+
+		env[cmd.EnvGitLabWebhookSecret] = "s3cret"
+		_, err = cmd.NewBotMux(b, getenv, "", "")
+		Expect(err).To(MatchError(ContainSubstring(cmd.EnvGitLabToken)))
+
+		env[cmd.EnvGitLabToken] = "glpat-t0ken"
+		mux, err := cmd.NewBotMux(b, getenv, "", "")
+		Expect(err).NotTo(HaveOccurred())
+		_, pattern := mux.Handler(httptest.NewRequest(http.MethodPost, bot.GitLabWebhookPath, nil))
+		Expect(pattern).To(Equal(bot.GitLabWebhookPath))
+		_, pattern = mux.Handler(httptest.NewRequest(http.MethodPost, bot.GitHubWebhookPath, nil))
+		Expect(pattern).To(BeEmpty())
+	})
+
+	Describe("IssueRunner", func() {
+		var (
+			cwd string
+			ts  *httptest.Server
+		)
+
+		BeforeEach(func() {
+			var err error
+			cwd, err = os.Getwd()
+			Expect(err).NotTo(HaveOccurred())
+			// the bot runs in the checkout
+			Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+			ts = OpenAITestServer()
+			ts.Start()
+		})
+
+		AfterEach(func() {
+			ts.Close()
+			Expect(os.Chdir(cwd)).To(Succeed())
+		})
+
+		It("runs the edit flow for every issue on a copy of the base request", func() {
+			Expect(os.WriteFile("pod.yaml", []byte("kind: Pod\n"), 0o600)).To(Succeed())
+			base := &cmd.Request{
+				Backend: ai.GPT3,
+				Config:  config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+			}
+			run := cmd.IssueRunner(base)
+
+			iss, err := issue.Parse("Rename the pod", "`@pod:pod.yaml`")
+			Expect(err).NotTo(HaveOccurred())
+			proposal, err := run(context.Background(), iss, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proposal.Request).To(Equal("Rename the pod"))
+			Expect(proposal.Before.Files).To(HaveKey("pod"))
+			Expect(proposal.After.Files).To(HaveKey("path/to/kubernetes.yaml"))
+			Expect(base.Filemap).To(BeNil())
+			Expect(base.UserRequest).To(BeEmpty())
+			Expect(base.Untrusted).To(BeFalse())
+		})
+
+		It("refuses issues which reference files outside of the checkout", func() {
+			secret := filepath.Join(GinkgoT().TempDir(), "id_rsa")
+			Expect(os.WriteFile(secret, []byte("PRIVATE KEY\n"), 0o600)).To(Succeed())
+			Expect(os.WriteFile(config.ConfigFile, []byte("openai:\n  apiKey: sk-s3cret\n"), 0o600)).To(Succeed())
+			base := &cmd.Request{
+				Backend: ai.GPT3,
+				Config:  config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+			}
+
+			for _, path := range []string{secret, "../" + filepath.Base(filepath.Dir(secret)) + "/id_rsa", config.ConfigFile} {
+				iss, err := issue.Parse("Leak it", "`@key:"+path+"`")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = cmd.IssueRunner(base)(context.Background(), iss, nil)
+				Expect(err).To(MatchError(filemap.ErrOutsideRoot), path)
+			}
+		})
+
+		It("generates new files for issues which reference none", func() {
+			base := &cmd.Request{
+				Backend: ai.GPT3,
+				NTokens: cmd.DefaultTokens,
+				Config:  config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+			}
+			iss, err := issue.Parse("Add a pod", "It should run nginx.")
+			Expect(err).NotTo(HaveOccurred())
+			proposal, err := cmd.IssueRunner(base)(context.Background(), iss, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proposal.Request).To(Equal("Add a pod\n\nIt should run nginx."))
+			Expect(proposal.Before.Files).To(BeEmpty())
+			Expect(proposal.After.Files).NotTo(BeEmpty())
+		})
+
+		It("runs the command of the comment which asked for changes", func() {
+			base := &cmd.Request{
+				Backend: ai.GPT3,
+				Config:  config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+			}
+			iss, err := issue.Parse("Resize the PVC", "")
+			Expect(err).NotTo(HaveOccurred())
+			command, err := chatops.Parse("/copilot-ops ask how big is it?", chatops.DefaultTrigger)
+			Expect(err).NotTo(HaveOccurred())
+
+			proposal, err := cmd.IssueRunner(base)(context.Background(), iss, command)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proposal.Request).To(Equal("Resize the PVC\n\nhow big is it?"))
+			Expect(proposal.Answer).To(HavePrefix("answer to: "))
+		})
+	})
+})
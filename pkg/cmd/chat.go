@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/journal"
+	"github.com/redhat-et/copilot-ops/pkg/session"
+	"github.com/redhat-et/copilot-ops/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// Define the commands which can be typed into the chat prompt.
+const (
+	ChatCommandHelp    = "/help"
+	ChatCommandFile    = "/file"
+	ChatCommandFileset = "/fileset"
+	ChatCommandFiles   = "/files"
+	ChatCommandApply   = "/apply"
+	ChatCommandQuit    = "/quit"
+	ChatCommandExit    = "/exit"
+	// ChatPrompt Is printed whenever the chat is waiting for input.
+	ChatPrompt = "> "
+)
+
+// chatFormatInstructions Is appended to the system prompt so that answers can be applied with /apply.
+const chatFormatInstructions = `
+
+When you propose changes to files, respond with the complete content of every changed or new file.
+Begin each file with a line of the form '# %[1]stagname', using the tag of the attached file
+or the path of a new file, and separate files with a line containing only '%[2]s'.`
+
+// chatHelp Describes the commands available in the chat.
+const chatHelp = `Commands:
+  /file GLOB      attach the files matching GLOB to the conversation
+  /fileset NAME   attach the files of a fileset defined in ` + config.ConfigFile + `
+  /files          list the attached files
+  /apply          write the files contained in the last answer to the repo
+  /help           show this help
+  /quit           save the session and exit`
+
+// NewChatCmd Creates the `copilot-ops chat` CLI command.
+func NewChatCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandChat,
+
+		Short: "Starts an interactive conversation with a chat model",
+
+		Long: "Chat holds a multi-turn conversation with a chat model of the selected backend. " +
+			"Files can be attached with /file and /fileset, and the files proposed in the last answer " +
+			"can be written to the repo with /apply. Sessions are saved under " + session.SessionsDir +
+			" and can be resumed with --" + FlagSessionFull + ".",
+
+		Example: `  copilot-ops chat --fileset app1
+  copilot-ops chat --session 20230401-101500`,
+
+		RunE: RunChat,
+		Args: cobra.NoArgs,
+	}
+
+	AddFileFlags(cmd)
+	AddBackendFlags(cmd)
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().String(
+		FlagSessionFull, "",
+		"ID of the session to resume or create (a new session is started if empty)",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Chat model to use (defaults to gpt-3.5-turbo for gpt-3, and the configured model for ollama)",
+	)
+
+	cmd.Flags().String(
+		FlagSystemFull, DefaultSystemPrompt,
+		"System message which sets the behavior of the model",
+	)
+
+	return cmd
+}
+
+// RunChat Runs when the `chat` command is invoked.
+func RunChat(cmd *cobra.Command, args []string) error {
+	r, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	id, _ := cmd.Flags().GetString(FlagSessionFull)
+	s, err := session.LoadOrCreate(session.SessionsDir, id)
+	if err != nil {
+		return fmt.Errorf("could not load session: %w", err)
+	}
+
+	// settings given on the command-line take precedence over the ones of a resumed session
+	if s.Backend == ai.Unselected || cmd.Flags().Changed(FlagAIBackendFull) {
+		s.Backend = r.Backend
+	}
+	if s.Model == "" || cmd.Flags().Changed(FlagModelFull) {
+		s.Model = r.Model
+	}
+	if s.SystemPrompt == "" || cmd.Flags().Changed(FlagSystemFull) {
+		s.SystemPrompt = r.SystemPrompt
+	}
+	for tag, file := range r.Filemap.Files {
+		s.Files.Files[tag] = file
+	}
+
+	return RunChatLoop(cmd.InOrStdin(), cmd.OutOrStdout(), r, s, session.SessionsDir)
+}
+
+// RunChatLoop Reads messages from in until it is exhausted or the user quits,
+// writing the answers to out and saving the session to dir after every turn.
+func RunChatLoop(in io.Reader, out io.Writer, r *Request, s *session.Session, dir string) error {
+	r.Backend = s.Backend
+	r.Model = s.Model
+
+	fmt.Fprintf(out, "session %s (%s), type %s for a list of commands\n", s.ID, s.Backend, ChatCommandHelp)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, ChatPrompt)
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "/") {
+			quit, err := handleChatCommand(out, r, s, line)
+			if err != nil {
+				fmt.Fprintf(out, "error: %s\n", err)
+			}
+			if quit {
+				break
+			}
+		} else if err := chatTurn(out, r, s, line); err != nil {
+			fmt.Fprintf(out, "error: %s\n", err)
+		}
+
+		if err := s.Save(dir); err != nil {
+			return fmt.Errorf("could not save session: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nsession saved as %s\n", s.ID)
+	return s.Save(dir)
+}
+
+// ChatMessages Builds the conversation sent to the chat model, with the
+// attached files included in the system message.
+func ChatMessages(s *session.Session) []ai.Message {
+	systemPrompt := s.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = DefaultSystemPrompt
+	}
+	systemPrompt += fmt.Sprintf(chatFormatInstructions, filemap.FileTagPrefix, filemap.FileDelimeter)
+	if len(s.Files.Files) > 0 {
+		systemPrompt += "\n\nThe user has attached the following files:\n\n" + s.Files.EncodeToInputText()
+	}
+	messages := []ai.Message{{Role: ai.RoleSystem, Content: systemPrompt}}
+	return append(messages, s.Messages...)
+}
+
+// chatTurn Sends the user's message along with the conversation so far and prints the answer.
+func chatTurn(out io.Writer, r *Request, s *session.Session, message string) error {
+	s.AddMessage(ai.RoleUser, message)
+	client, err := PrepareChatClient(r, ChatMessages(s))
+	if err == nil {
+		var answer string
+		if answer, err = client.Chat(); err == nil {
+			s.AddMessage(ai.RoleAssistant, answer)
+			fmt.Fprintln(out, answer)
+			return nil
+		}
+	}
+	// drop the unanswered message so that it can be retried
+	s.Messages = s.Messages[:len(s.Messages)-1]
+	return err
+}
+
+// handleChatCommand Executes a slash-command typed into the chat, and reports whether the chat should end.
+func handleChatCommand(out io.Writer, r *Request, s *session.Session, line string) (bool, error) {
+	fields := strings.Fields(line)
+	command, args := fields[0], fields[1:]
+	switch command {
+	case ChatCommandQuit, ChatCommandExit:
+		return true, nil
+	case ChatCommandHelp:
+		fmt.Fprintln(out, chatHelp)
+	case ChatCommandFile:
+		if len(args) == 0 {
+			return false, fmt.Errorf("usage: %s GLOB", ChatCommandFile)
+		}
+		before := len(s.Files.Files)
+		if err := s.Files.LoadFiles(args); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "attached %d file(s)\n", len(s.Files.Files)-before)
+	case ChatCommandFileset:
+		if len(args) == 0 {
+			return false, fmt.Errorf("usage: %s NAME", ChatCommandFileset)
+		}
+		before := len(s.Files.Files)
+		if err := s.Files.LoadFilesets(args, r.Config, config.ConfigFile); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "attached %d file(s)\n", len(s.Files.Files)-before)
+	case ChatCommandFiles:
+		tags := make([]string, 0, len(s.Files.Files))
+		for tag := range s.Files.Files {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			fmt.Fprintf(out, "%s%s: %s\n", filemap.FileTagPrefix, tag, s.Files.Files[tag].Path)
+		}
+	case ChatCommandApply:
+		return false, applyLastAnswer(out, s)
+	default:
+		return false, fmt.Errorf("unknown command %q, type %s for a list of commands", command, ChatCommandHelp)
+	}
+	return false, nil
+}
+
+// applyLastAnswer Decodes the files contained in the model's last answer and writes the ones that changed,
+// refusing to write the files attached at a git revision.
+func applyLastAnswer(out io.Writer, s *session.Session) error {
+	answer := s.LastAnswer()
+	if answer == "" {
+		return fmt.Errorf("there is no answer to apply yet")
+	}
+	updated := s.Files.Clone()
+	if err := updated.DecodeFromOutput(utils.StripCodeFences(answer)); err != nil {
+		return fmt.Errorf("could not find any files in the last answer: %w", err)
+	}
+
+	changed := filemap.NewFilemap()
+	for tag, file := range updated.Files {
+		if original, ok := s.Files.Files[tag]; !ok || original.Content != file.Content {
+			changed.Files[tag] = file
+		}
+	}
+	if len(changed.Files) == 0 {
+		fmt.Fprintln(out, "the last answer does not change any files")
+		return nil
+	}
+	// the files attached at a git revision must not overwrite the working tree
+	changed, err := withoutRefFiles(s.Files, changed)
+	if err != nil {
+		return err
+	}
+	log.Printf("applying %d file(s) from the last answer\n", len(changed.Files))
+	entry := journal.NewEntry("", CommandChat)
+	entry.Backend = s.Backend
+	entry.Model = s.Model
+	for _, message := range s.Messages {
+		if message.Role == ai.RoleUser {
+			entry.Request = message.Content
+		}
+	}
+	if err = journal.Write(journal.HistoryDir, entry, changed); err != nil {
+		return err
+	}
+	for _, file := range changed.Files {
+		fmt.Fprintf(out, "wrote %s\n", file.Path)
+	}
+	s.Files = updated
+	return nil
+}
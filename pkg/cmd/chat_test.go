@@ -0,0 +1,92 @@
+package cmd_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/session"
+)
+
+var _ = Describe("Chat command", func() {
+	var (
+		ts  *httptest.Server
+		r   *cmd.Request
+		s   *session.Session
+		dir string
+		out *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		ts = OpenAITestServer()
+		ts.Start()
+		dir = GinkgoT().TempDir()
+		out = &bytes.Buffer{}
+		r = &cmd.Request{
+			Config: config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+		}
+		s = session.NewSession("test")
+		s.Backend = ai.GPT3
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("keeps the conversation and saves the session", func() {
+		in := strings.NewReader("hello\nhow are you?\n/quit\n")
+		Expect(cmd.RunChatLoop(in, out, r, s, dir)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("answer to: how are you?"))
+
+		saved, err := session.Load(dir, "test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(saved.Messages).To(HaveLen(4))
+		Expect(saved.LastAnswer()).To(Equal("answer to: how are you?"))
+	})
+
+	It("attaches files to the conversation", func() {
+		in := strings.NewReader("/file ../../examples/app1/*.yaml\n/files\n")
+		Expect(cmd.RunChatLoop(in, out, r, s, dir)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("attached 2 file(s)"))
+		Expect(out.String()).To(ContainSubstring("@mysql-pvc.yaml"))
+
+		messages := cmd.ChatMessages(s)
+		Expect(messages[0].Content).To(ContainSubstring("kind: PersistentVolumeClaim"))
+	})
+
+	It("applies the files proposed in the last answer", func() {
+		// the write is journaled under the working directory
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(dir)).To(Succeed())
+		DeferCleanup(os.Chdir, cwd)
+
+		target := filepath.Join(dir, "pod.yaml")
+		Expect(os.WriteFile(target, []byte("kind: Pod\nmetadata:\n  name: a-very-long-name\n"), 0600)).To(Succeed())
+		s.Files.Files["pod"] = filemap.File{Path: target, Content: "kind: Pod\n"}
+		s.AddMessage(ai.RoleUser, "rename the pod")
+		s.AddMessage(ai.RoleAssistant, "Here you go:\n```yaml\n# @pod\nkind: Pod\nmetadata:\n  name: b\n```\n")
+
+		Expect(cmd.RunChatLoop(strings.NewReader("/apply\n"), out, r, s, dir)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("wrote " + target))
+		content, err := os.ReadFile(target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("kind: Pod\nmetadata:\n  name: b\n"))
+	})
+
+	It("reports unknown commands without exiting", func() {
+		Expect(cmd.RunChatLoop(strings.NewReader("/nope\nhi\n"), out, r, s, dir)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("unknown command"))
+		Expect(out.String()).To(ContainSubstring("answer to: hi"))
+	})
+})
@@ -37,6 +37,22 @@ func NewRootCmd() *cobra.Command {
 	cmd.AddCommand(NewGenerateCmd())
 	cmd.AddCommand(NewEditCmd())
 	cmd.AddCommand(NewAskCmd())
+	cmd.AddCommand(NewChatCmd())
+	cmd.AddCommand(NewRefineCmd())
+	cmd.AddCommand(NewHistoryCmd())
+	cmd.AddCommand(NewUndoCmd())
+	cmd.AddCommand(NewExplainCmd())
+	cmd.AddCommand(NewReviewCmd())
+	cmd.AddCommand(NewInitCmd())
+	cmd.AddCommand(NewConfigCmd())
+	cmd.AddCommand(NewFilesetCmd())
+	cmd.AddCommand(NewIssueCmd())
+	cmd.AddCommand(NewCommentCmd())
+	cmd.AddCommand(NewBatchCmd())
+	cmd.AddCommand(NewEvalCmd())
+	cmd.AddCommand(NewServeCmd())
+	cmd.AddCommand(NewLSPCmd())
+	cmd.AddCommand(NewBotCmd())
 
 	return cmd
 }
@@ -74,6 +74,47 @@ spec:
 			resBytes, _ = json.Marshal(res)
 			fmt.Fprintln(w, string(resBytes))
 			return
+		case r.URL.Path == "/v1/chat/completions":
+			var req gogpt.ChatCompletionRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			// echo the last message so that tests can inspect what was sent
+			answer := req.Messages[len(req.Messages)-1].Content
+			if req.Stream {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, part := range []string{"answer to: ", answer} {
+					chunk := gogpt.ChatCompletionStreamResponse{
+						ID:      "test-id",
+						Object:  "test-object",
+						Created: time.Now().Unix(),
+						Model:   req.Model,
+						Choices: []gogpt.ChatCompletionStreamChoice{
+							{Index: 0, Delta: gogpt.ChatCompletionStreamChoiceDelta{Content: part}},
+						},
+					}
+					resBytes, _ = json.Marshal(chunk)
+					fmt.Fprintf(w, "data: %s\n\n", resBytes)
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+			res := gogpt.ChatCompletionResponse{
+				ID:      "test-id",
+				Object:  "test-object",
+				Created: time.Now().Unix(),
+				Model:   req.Model,
+				Choices: []gogpt.ChatCompletionChoice{
+					{
+						Index: 0,
+						Message: gogpt.ChatCompletionMessage{
+							Role:    gogpt.ChatMessageRoleAssistant,
+							Content: "answer to: " + answer,
+						},
+					},
+				},
+			}
+			resBytes, _ = json.Marshal(res)
+			fmt.Fprint(w, string(resBytes))
+			return
 		default:
 			// the endpoint doesn't exist
 			log.Println("test server was accessed, but no endpoint was found")
@@ -82,3 +123,27 @@ spec:
 		}
 	}))
 }
+
+// ChatTestServer Creates a mocked OpenAI server which gives the same answer to every chat request,
+// for the commands which expect the model to answer in a given format.
+func ChatTestServer(answer string) *httptest.Server {
+	return httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.Error(w, "the resource path doesn't exist", http.StatusNotFound)
+			return
+		}
+		res := gogpt.ChatCompletionResponse{
+			ID:      "test-id",
+			Object:  "test-object",
+			Created: time.Now().Unix(),
+			Choices: []gogpt.ChatCompletionChoice{
+				{
+					Index:   0,
+					Message: gogpt.ChatCompletionMessage{Role: gogpt.ChatMessageRoleAssistant, Content: answer},
+				},
+			},
+		}
+		resBytes, _ := json.Marshal(res)
+		fmt.Fprint(w, string(resBytes))
+	}))
+}
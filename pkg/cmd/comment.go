@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+	"github.com/spf13/cobra"
+)
+
+// NewCommentCmd Creates the `copilot-ops comment` CLI command.
+func NewCommentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandComment,
+
+		Short: "Runs the slash command found in a comment",
+
+		Long: "Comment reads a comment, e.g. from an issue or a pull request, and runs the command on " +
+			"its first line starting with the trigger, as the bot does. The trigger is followed by one of " +
+			strings.Join(chatops.Commands(), ", ") + " (" + chatops.DefaultCommand + " when left out), " +
+			"the files to load as `" + filemap.FileTagPrefix + "name:path`, options named after the flags " +
+			"of the CLI such as --" + chatops.OptionNCompletionsFull + ", and the request. " +
+			"The lines of the comment after the command are added to the request. Since anyone may write " +
+			"comments, the files they name must be within --" + FlagPathFull + ", and the backend and model " +
+			"are only set with the flags.",
+
+		Example: `  copilot-ops comment --body-file comment.md
+  echo '/copilot-ops edit @deploy:apps/web/deploy.yaml bump memory to 1Gi' | copilot-ops comment --body-file - --write
+  copilot-ops comment --body-file comment.md --trigger /ops`,
+
+		RunE: RunComment,
+		Args: cobra.NoArgs,
+	}
+
+	AddRequestFlags(cmd)
+	AddInteractiveFlag(cmd)
+	AddFileFlags(cmd)
+
+	cmd.Flags().String(
+		FlagBodyFileFull, "",
+		"File containing the comment, or - to read it from STDIN",
+	)
+
+	cmd.Flags().String(
+		FlagTriggerFull, chatops.DefaultTrigger,
+		"Text starting the line of the command",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNCompletionsFull, FlagNCompletionsShort, DefaultCompletions,
+		"Number of edits to request, unless the command sets it",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNTokensFull, FlagNTokensShort, DefaultTokens,
+		"Max number of tokens to generate, unless the command sets it",
+	)
+
+	_ = cmd.MarkFlagRequired(FlagBodyFileFull)
+
+	return cmd
+}
+
+// RunComment Runs when the `comment` command is invoked.
+func RunComment(cmd *cobra.Command, args []string) error {
+	// the body file is relative to where the command was run, so it is read before changing to --path
+	bodyFile, _ := cmd.Flags().GetString(FlagBodyFileFull)
+	trigger, _ := cmd.Flags().GetString(FlagTriggerFull)
+	body, err := readBody(cmd.InOrStdin(), bodyFile)
+	if err != nil {
+		return err
+	}
+	command, err := chatops.Parse(body, trigger)
+	if err != nil {
+		return err
+	}
+
+	r, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	// comments may be written by anyone, as with the bot
+	r.Untrusted = true
+	if err = ApplyCommand(r, command); err != nil {
+		return err
+	}
+
+	answer, err := ProposeCommand(r)
+	if err != nil {
+		return err
+	}
+	if r.Command == CommandAsk {
+		return printAnswer(cmd.OutOrStdout(), r, answer)
+	}
+	if r.Command == CommandEdit {
+		selected, selectErr := SelectCandidate(r)
+		if selectErr != nil {
+			return selectErr
+		}
+		SaveRun(r)
+		if !selected {
+			return nil
+		}
+		return PrintOrWriteOut(r)
+	}
+	SaveRun(r)
+
+	return PrintOrWriteOut(r)
+}
+
+// ApplyCommand Applies a slash command to a request made by PrepareRequest: the files
+// the command names are loaded alongside the request's, its options override the flags,
+// and its request comes before the text given with --request. The files of untrusted
+// requests must be within the working directory, their backend and model are kept, and
+// their numbers of completions and tokens are capped at the request's.
+func ApplyCommand(r *Request, c *chatops.Command) error {
+	r.Command = c.Name
+	if r.Untrusted && (c.Backend != "" || c.Model != "") {
+		// the backend and model see the repo's files, so whoever runs copilot-ops chooses them
+		return fmt.Errorf("the --%s and --%s options may not be given in comments",
+			chatops.OptionBackendFull, chatops.OptionModelFull)
+	}
+	if err := loadCommandFiles(r, c); err != nil {
+		return err
+	}
+	r.Original = r.Filemap.Clone()
+	r.FilemapText = r.Filemap.EncodeToInputText()
+
+	// untrusted requests may ask for fewer completions and tokens than the flags allow, but not for more
+	if c.NCompletions > 0 && (!r.Untrusted || c.NCompletions < r.NCompletions) {
+		r.NCompletions = c.NCompletions
+	}
+	if c.NTokens > 0 && (!r.Untrusted || c.NTokens < r.NTokens) {
+		r.NTokens = c.NTokens
+	}
+	if c.Backend != "" {
+		r.Backend = ai.Backend(c.Backend)
+	}
+	if c.Model != "" {
+		r.Model = c.Model
+	}
+
+	request := c.Request
+	if r.UserRequest != "" {
+		request = strings.TrimSpace(request + "\n\n" + r.UserRequest)
+	}
+	r.UserRequest = request
+	return nil
+}
+
+// loadCommandFiles Loads the files and filesets named by the command, and the files it references.
+func loadCommandFiles(r *Request, c *chatops.Command) error {
+	refs := &issue.Issue{References: c.References}
+	if r.Untrusted {
+		if err := r.Filemap.LoadFilesWithin(".", c.Files); err != nil {
+			return fmt.Errorf("error loading files: %w", err)
+		}
+		if err := r.Filemap.LoadFilesetsWithin(".", c.Filesets, r.Config, config.ConfigFile); err != nil {
+			return fmt.Errorf("error loading filesets: %w", err)
+		}
+		return refs.LoadFilesWithin(r.Filemap, ".")
+	}
+	if err := r.Filemap.LoadFiles(c.Files); err != nil {
+		return fmt.Errorf("error loading files: %w", err)
+	}
+	if err := r.Filemap.LoadFilesets(c.Filesets, r.Config, config.ConfigFile); err != nil {
+		return fmt.Errorf("error loading filesets: %w", err)
+	}
+	return refs.LoadFiles(r.Filemap)
+}
+
+// ProposeCommand Runs the flow of the request's command, which is one of the commands
+// of a slash command. Edits and generated files end up in the request's filemap,
+// while the answer to a question is returned.
+func ProposeCommand(r *Request) (string, error) {
+	if err := checkCommand(r); err != nil {
+		return "", err
+	}
+	switch r.Command {
+	case CommandEdit:
+		return "", ProposeEdit(r)
+	case CommandGenerate:
+		return "", ProposeGenerate(r)
+	default:
+		return Answer(r)
+	}
+}
+
+// checkCommand Returns an error when the request's command is unknown, or lacks
+// the files or the request it needs.
+func checkCommand(r *Request) error {
+	switch {
+	case r.Command != CommandEdit && r.Command != CommandGenerate && r.Command != CommandAsk:
+		return fmt.Errorf("unknown command %q, use one of %s", r.Command, strings.Join(chatops.Commands(), ", "))
+	case r.Command == CommandEdit && len(r.Filemap.Files) == 0:
+		return fmt.Errorf("no files to edit, reference them as `%sname:path` or use --%s or --%s",
+			filemap.FileTagPrefix, chatops.OptionFileFull, chatops.OptionFilesetFull)
+	case r.Command != CommandEdit && r.UserRequest == "":
+		return fmt.Errorf("the %s command needs a request", r.Command)
+	}
+	return nil
+}
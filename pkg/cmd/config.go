@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/spf13/cobra"
+)
+
+// NewConfigCmd Creates the `copilot-ops config` CLI command, which groups the
+// commands used to inspect the configuration.
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandConfig,
+
+		Short: "Inspects the configuration in effect",
+
+		Long: "The configuration is merged from " + config.ConfigFile + " and " + config.ConfigFileLocal + ".yaml, " +
+			"searched for in /etc, $HOME and the repo, and from environment variables.",
+	}
+
+	cmd.AddCommand(NewConfigViewCmd())
+	cmd.AddCommand(NewConfigValidateCmd())
+
+	return cmd
+}
+
+// NewConfigViewCmd Creates the `copilot-ops config view` CLI command.
+func NewConfigViewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandView,
+
+		Short: "Prints the effective configuration and where each value comes from",
+
+		Long: "View prints every setting of the merged configuration along with its source: a default, " +
+			"a config file, or an environment variable. Secrets such as API keys are redacted.",
+
+		Example: `  copilot-ops config view
+  copilot-ops config view --output json`,
+
+		RunE: RunConfigView,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, OutputText,
+		"How to format output: text or json",
+	)
+
+	return cmd
+}
+
+// NewConfigValidateCmd Creates the `copilot-ops config validate` CLI command.
+func NewConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandValidate,
+
+		Short: "Checks the configuration for mistakes",
+
+		Long: "Validate checks every config file for unknown keys and values of the wrong type, " +
+			"every fileset for globs which are invalid or match nothing, and the settings of the selected backend. " +
+			"It fails if any error is found, while warnings are only reported.",
+
+		Example: `  copilot-ops config validate`,
+
+		RunE: RunConfigValidate,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, OutputText,
+		"How to format output: text or json",
+	)
+
+	return cmd
+}
+
+// loadLayers Changes to the repo given with --path and reads the config layers,
+// the same way PrepareRequest loads the config.
+func loadLayers(cmd *cobra.Command) ([]config.Layer, error) {
+	path, _ := cmd.Flags().GetString(FlagPathFull)
+	if path != "" {
+		if err := os.Chdir(path); err != nil {
+			return nil, err
+		}
+	}
+	return config.Layers(config.ConfigPaths, os.Getenv)
+}
+
+// RunConfigView Runs when the `config view` command is invoked.
+func RunConfigView(cmd *cobra.Command, args []string) error {
+	layers, err := loadLayers(cmd)
+	if err != nil {
+		return err
+	}
+	outputType, _ := cmd.Flags().GetString(FlagOutputTypeFull)
+	return PrintSettings(cmd.OutOrStdout(), outputType, config.Redact(config.Effective(layers)))
+}
+
+// RunConfigValidate Runs when the `config validate` command is invoked.
+func RunConfigValidate(cmd *cobra.Command, args []string) error {
+	layers, err := loadLayers(cmd)
+	if err != nil {
+		return err
+	}
+	outputType, _ := cmd.Flags().GetString(FlagOutputTypeFull)
+	issues := config.Validate(layers, ".")
+	if err = PrintIssues(cmd.OutOrStdout(), outputType, issues); err != nil {
+		return err
+	}
+	errors := 0
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errors++
+		}
+	}
+	if errors > 0 {
+		return fmt.Errorf("the configuration has %d error(s)", errors)
+	}
+	return nil
+}
+
+// PrintSettings Writes the settings to the given writer in the requested output format.
+func PrintSettings(w io.Writer, outputType string, settings []config.Setting) error {
+	switch outputType {
+	case OutputText:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+		for _, s := range settings {
+			// lists such as filesets are printed one element per row to keep the table narrow
+			rows := map[string]interface{}{s.Key: s.Value}
+			keys := []string{s.Key}
+			if list, ok := s.Value.([]interface{}); ok && len(list) > 0 {
+				rows, keys = make(map[string]interface{}), nil
+				for i, element := range list {
+					key := fmt.Sprintf("%s[%d]", s.Key, i)
+					rows[key] = element
+					keys = append(keys, key)
+				}
+			}
+			for _, key := range keys {
+				value, err := formatValue(rows[key])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", key, value, s.Source)
+			}
+		}
+		return tw.Flush()
+	case filemap.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(settings)
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
+
+// formatValue Formats scalars as they are, and other values as compact JSON.
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool, int, int64, float64:
+		return fmt.Sprint(v), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
+
+// PrintIssues Writes the issues found by validation in the requested output format.
+func PrintIssues(w io.Writer, outputType string, issues []config.Issue) error {
+	switch outputType {
+	case OutputText:
+		if len(issues) == 0 {
+			_, err := io.WriteString(w, "the configuration is valid\n")
+			return err
+		}
+		for _, issue := range issues {
+			location := issue.Source
+			if issue.Key != "" {
+				if location != "" {
+					location += ": "
+				}
+				location += issue.Key
+			}
+			if location != "" {
+				location += ": "
+			}
+			fmt.Fprintf(w, "%s: %s%s\n", issue.Severity, location, issue.Message)
+		}
+		return nil
+	case filemap.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if issues == nil {
+			issues = []config.Issue{}
+		}
+		return enc.Encode(issues)
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
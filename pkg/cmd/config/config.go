@@ -5,6 +5,7 @@ import (
 
 	"github.com/redhat-et/copilot-ops/pkg/ai"
 	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
 	"github.com/spf13/viper"
 )
 
@@ -12,16 +13,35 @@ const (
 	ConfigName      = ".copilot-ops"
 	ConfigFile      = ".copilot-ops.yaml"
 	ConfigFileLocal = ".copilot-ops.local"
+	// StateDir Is the directory, relative to the repo root, where copilot-ops keeps its state.
+	StateDir = ".copilot-ops"
 )
 
+// ConfigPaths Are the directories searched for the config files, in order.
+// Only the first config file found is used, and likewise for the local config file.
+var ConfigPaths = []string{"/etc", "$HOME", "."}
+
+// EnvBindings Maps config keys to the environment variables which override them.
+// The backend can be selected without a config file, e.g. COPILOT_OPS_BACKEND=ollama.
+var EnvBindings = map[string]string{
+	"openai.apikey": "OPENAI_API_KEY",
+	"openai.orgid":  "OPENAI_ORG_ID",
+	"openai.url":    "OPENAI_URL",
+	"ollama.url":    "OLLAMA_HOST",
+	"ollama.model":  "OLLAMA_MODEL",
+	"backend":       "COPILOT_OPS_BACKEND",
+}
+
 // Config Defines the struct into which the config-file will be parsed.
 type Config struct {
 	Filesets []Filesets `json:"filesets,omitempty" yaml:"filesets,omitempty"`
 	// OpenAI Defines the settings necessary for the OpenAI GPT-3 backend.
 	// FIXME: rename to GPT-3
 	OpenAI *gpt3.Config `json:"openAI,omitempty" yaml:"openAI,omitempty"`
+	// Ollama Defines the settings necessary for a local Ollama-compatible server.
+	Ollama *ollama.Config `json:"ollama,omitempty" yaml:"ollama,omitempty"`
 	// Backend Defines which AI backend should be used in order to generate completions.
-	// Valid models include: gpt-3, gpt-j, opt, and bloom.
+	// Valid models include: gpt-3, gpt-j, opt, bloom, and ollama.
 	Backend ai.Backend `json:"backend"`
 }
 
@@ -41,12 +61,7 @@ type GPTJ struct {
 // Errors here might return if the file exists but is invalid.
 func (c *Config) Load() error {
 	// bind to environment variables
-	openAIEnvs := map[string]string{
-		"openai.apikey": "OPENAI_API_KEY",
-		"openai.orgid":  "OPENAI_ORG_ID",
-		"openai.url":    "OPENAI_URL",
-	}
-	for k, v := range openAIEnvs {
+	for k, v := range EnvBindings {
 		if err := viper.BindEnv(k, v); err != nil {
 			return err
 		}
@@ -55,10 +70,10 @@ func (c *Config) Load() error {
 	viper.AutomaticEnv()
 
 	// paths to look for the config file in
-	viper.AddConfigPath("/etc")
-	viper.AddConfigPath("$HOME")
 	// viper.AddConfigPath("..") // parent? grandparent? grandgrandparent?
-	viper.AddConfigPath(".")
+	for _, path := range ConfigPaths {
+		viper.AddConfigPath(path)
+	}
 
 	viper.SetConfigType("yaml")     // REQUIRED if the config file does not have the extension in the name
 	viper.SetConfigName(ConfigName) // name of config file (without extension)
@@ -90,9 +105,19 @@ func (c *Config) Load() error {
 // SetDefaults Sets default values for the given config object.
 func (c *Config) SetDefaults() {
 	if c.OpenAI == nil {
-		c.OpenAI = &gpt3.Config{
-			BaseURL: gpt3.OpenAIURL + gpt3.OpenAIEndpointV1,
-		}
+		c.OpenAI = &gpt3.Config{}
+	}
+	if c.OpenAI.BaseURL == "" {
+		c.OpenAI.BaseURL = gpt3.OpenAIURL + gpt3.OpenAIEndpointV1
+	}
+	if c.Ollama == nil {
+		c.Ollama = &ollama.Config{}
+	}
+	if c.Ollama.BaseURL == "" {
+		c.Ollama.BaseURL = ollama.OllamaURL
+	}
+	if c.Ollama.Model == "" {
+		c.Ollama.Model = ollama.DefaultModel
 	}
 }
 
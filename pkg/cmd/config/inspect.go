@@ -0,0 +1,384 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
+	"github.com/spf13/viper"
+)
+
+// Define the sources a setting can come from, other than config files.
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+)
+
+// Redacted Replaces the value of secrets when settings are displayed.
+const Redacted = "<redacted>"
+
+// Define the severities of the issues reported when validating the config.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Layer Is one of the sources which are merged into the effective config, with
+// its settings flattened into dotted, lower-case keys as used by viper.
+type Layer struct {
+	Source   string
+	Settings map[string]interface{}
+}
+
+// Setting Is a single value of the effective config along with where it came from.
+type Setting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
+// Issue Is a problem found while validating the config.
+type Issue struct {
+	Severity string `json:"severity"`
+	Source   string `json:"source,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Message  string `json:"message"`
+}
+
+// FindConfigFile Returns the first file with the given name found in the paths,
+// trying every extension supported by viper, or an empty string if there is none.
+func FindConfigFile(paths []string, name string) string {
+	for _, dir := range paths {
+		dir = os.ExpandEnv(dir)
+		candidates := make([]string, 0, len(viper.SupportedExts)+1)
+		for _, ext := range viper.SupportedExts {
+			candidates = append(candidates, filepath.Join(dir, name+"."+ext))
+		}
+		candidates = append(candidates, filepath.Join(dir, name))
+		for _, candidate := range candidates {
+			if stat, err := os.Stat(candidate); err == nil && !stat.IsDir() {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// Layers Returns the sources merged by Load in order of precedence, lowest first:
+// the defaults, the config file, the local config file, and the environment.
+func Layers(paths []string, getenv func(string) string) ([]Layer, error) {
+	defaults := &Config{}
+	defaults.SetDefaults()
+	settings, err := flattenStruct(defaults)
+	if err != nil {
+		return nil, err
+	}
+	layers := []Layer{{Source: SourceDefault, Settings: settings}}
+
+	for _, name := range []string{ConfigName, ConfigFileLocal} {
+		file := FindConfigFile(paths, name)
+		if file == "" {
+			continue
+		}
+		v := viper.New()
+		v.SetConfigFile(file)
+		v.SetConfigType("yaml")
+		if err = v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+		settings = make(map[string]interface{})
+		flatten("", v.AllSettings(), settings)
+		layers = append(layers, Layer{Source: file, Settings: settings})
+	}
+
+	keys := make([]string, 0, len(EnvBindings))
+	for key := range EnvBindings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env := EnvBindings[key]
+		if value := getenv(env); value != "" {
+			layers = append(layers, Layer{Source: SourceEnv + " " + env, Settings: map[string]interface{}{key: value}})
+		}
+	}
+	return layers, nil
+}
+
+// flattenStruct Flattens the non-empty fields of the value, keyed like viper keys.
+func flattenStruct(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var nested map[string]interface{}
+	if err = json.Unmarshal(data, &nested); err != nil {
+		return nil, err
+	}
+	settings := make(map[string]interface{})
+	flatten("", nested, settings)
+	for key, v := range settings {
+		if v == nil || v == "" {
+			delete(settings, key)
+		}
+	}
+	return settings, nil
+}
+
+// flatten Adds the leaves of the nested maps to settings, joining their keys with dots.
+func flatten(prefix string, nested map[string]interface{}, settings map[string]interface{}) {
+	for key, value := range nested {
+		key = strings.ToLower(key)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if m, ok := value.(map[string]interface{}); ok && len(m) > 0 {
+			flatten(key, m, settings)
+			continue
+		}
+		settings[key] = value
+	}
+}
+
+// Effective Merges the layers, returning every setting in effect along with the
+// layer it came from, ordered by key.
+func Effective(layers []Layer) []Setting {
+	merged := make(map[string]Setting)
+	for _, layer := range layers {
+		for key, value := range layer.Settings {
+			// a nested value replaces the values below it, and the other way around
+			for existing := range merged {
+				if strings.HasPrefix(existing, key+".") || strings.HasPrefix(key, existing+".") {
+					delete(merged, existing)
+				}
+			}
+			merged[key] = Setting{Key: key, Value: value, Source: layer.Source}
+		}
+	}
+	settings := make([]Setting, 0, len(merged))
+	for _, s := range merged {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// IsSecret Reports whether the setting holds a secret which should not be displayed.
+func IsSecret(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, secret := range []string{"apikey", "token", "secret", "password"} {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact Returns the settings with the values of secrets replaced.
+func Redact(settings []Setting) []Setting {
+	redacted := make([]Setting, len(settings))
+	for i, s := range settings {
+		if IsSecret(s.Key) && s.Value != "" {
+			s.Value = Redacted
+		}
+		redacted[i] = s
+	}
+	return redacted
+}
+
+// Decode Builds the config from the merged settings, as Load would.
+func Decode(settings []Setting) (*Config, error) {
+	v := viper.New()
+	for _, s := range settings {
+		v.Set(s.Key, s.Value)
+	}
+	conf := &Config{}
+	if err := v.Unmarshal(conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// KnownKeys Returns the keys understood by the config, as viper keys. Keys ending
+// in ".*" accept any key below them.
+func KnownKeys() []string {
+	var keys []string
+	collectKeys("", reflect.TypeOf(Config{}), &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+// collectKeys Adds the keys of the struct's fields, named as mapstructure names them.
+func collectKeys(prefix string, t reflect.Type, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.ToLower(field.Name)
+		if tag := strings.Split(field.Tag.Get("mapstructure"), ",")[0]; tag != "" {
+			name = tag
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			collectKeys(name, ft, keys)
+		case reflect.Map:
+			*keys = append(*keys, name+".*")
+		default:
+			*keys = append(*keys, name)
+		}
+	}
+}
+
+// isKnown Reports whether the key is understood by the config.
+func isKnown(key string, known []string) bool {
+	for _, k := range known {
+		if k == key || (strings.HasSuffix(k, ".*") && strings.HasPrefix(key, strings.TrimSuffix(k, "*"))) {
+			return true
+		}
+		// maps which are set to empty values are flattened onto their own key
+		if strings.HasSuffix(k, ".*") && key == strings.TrimSuffix(k, ".*") {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate Checks the layers for unknown keys and values of the wrong type, and
+// the effective config for filesets whose globs are invalid or match nothing
+// relative to root, and for missing or invalid backend settings.
+func Validate(layers []Layer, root string) []Issue {
+	var issues []Issue
+	known := KnownKeys()
+	for _, layer := range layers {
+		keys := make([]string, 0, len(layer.Settings))
+		for key := range layer.Settings {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !isKnown(key, known) {
+				issues = append(issues, Issue{Severity: SeverityWarning, Source: layer.Source, Key: key, Message: "unknown key"})
+			}
+		}
+		settings := make([]Setting, 0, len(keys))
+		for _, key := range keys {
+			settings = append(settings, Setting{Key: key, Value: layer.Settings[key]})
+		}
+		if _, err := Decode(settings); err != nil {
+			issues = append(issues, Issue{Severity: SeverityError, Source: layer.Source, Message: err.Error()})
+		}
+	}
+
+	effective := Effective(layers)
+	conf, err := Decode(effective)
+	if err != nil {
+		return issues
+	}
+	sources := make(map[string]string)
+	for _, s := range effective {
+		sources[s.Key] = s.Source
+	}
+	issues = append(issues, validateFilesets(conf, root, sources["filesets"])...)
+	issues = append(issues, validateBackend(conf, sources)...)
+	return issues
+}
+
+// validateFilesets Checks that every fileset has a unique name and globs which match files.
+func validateFilesets(conf *Config, root, source string) []Issue {
+	var issues []Issue
+	seen := make(map[string]bool)
+	for i, fileset := range conf.Filesets {
+		key := fmt.Sprintf("filesets[%d]", i)
+		issue := func(severity, format string, args ...interface{}) {
+			issues = append(issues, Issue{Severity: severity, Source: source, Key: key, Message: fmt.Sprintf(format, args...)})
+		}
+		switch {
+		case fileset.Name == "":
+			issue(SeverityError, "fileset has no name")
+		case seen[fileset.Name]:
+			issue(SeverityError, "fileset %q is defined more than once", fileset.Name)
+		}
+		seen[fileset.Name] = true
+		if len(fileset.Files) == 0 {
+			issue(SeverityWarning, "fileset %q has no files", fileset.Name)
+		}
+		for _, glob := range fileset.Files {
+			matches, err := filepath.Glob(filepath.Join(root, glob))
+			if err != nil {
+				issue(SeverityError, "fileset %q has an invalid glob %q: %s", fileset.Name, glob, err)
+			} else if len(matches) == 0 {
+				issue(SeverityWarning, "glob %q of fileset %q matches no files", glob, fileset.Name)
+			}
+		}
+	}
+	return issues
+}
+
+// validateBackend Checks that the selected backend exists and that its settings are usable: the
+// API key, URL, model and timeout. The backend is not contacted, so that validating works offline.
+func validateBackend(conf *Config, sources map[string]string) []Issue {
+	var issues []Issue
+	issue := func(severity, key, format string, args ...interface{}) {
+		issues = append(issues, Issue{Severity: severity, Source: sources[key], Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+	backend := conf.Backend
+	if backend == ai.Unselected {
+		backend = ai.GPT3
+	}
+	switch backend {
+	case ai.GPT3:
+		if conf.OpenAI == nil || conf.OpenAI.APIKey == "" {
+			issue(SeverityError, "openai.apikey", "no OpenAI API key, set %s or openAI.apiKey in %s",
+				EnvBindings["openai.apikey"], ConfigFileLocal+".yaml")
+		}
+		if conf.OpenAI != nil {
+			if err := validateURL(conf.OpenAI.BaseURL); err != nil {
+				issue(SeverityError, "openai.url", "%s", err)
+			}
+		}
+	case ai.OLLAMA:
+		if conf.Ollama != nil {
+			if err := validateURL(ollama.BaseURL(*conf.Ollama)); err != nil {
+				issue(SeverityError, "ollama.url", "%s", err)
+			}
+			if conf.Ollama.Model == "" {
+				issue(SeverityError, "ollama.model", "no Ollama model is set")
+			}
+			if conf.Ollama.Timeout != "" {
+				if _, err := time.ParseDuration(conf.Ollama.Timeout); err != nil {
+					issue(SeverityError, "ollama.timeout", "invalid timeout %q, use a duration such as 5m", conf.Ollama.Timeout)
+				}
+			}
+		}
+	case ai.GPTJ, ai.BLOOM, ai.OPT:
+		issue(SeverityWarning, "backend", "backend %q only supports generate", backend)
+	default:
+		issue(SeverityError, "backend", "unknown backend %q, use %s or %s", backend, ai.GPT3, ai.OLLAMA)
+	}
+	return issues
+}
+
+// validateURL Checks that the URL is absolute.
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", raw, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid URL %q: it must include a scheme and a host", raw)
+	}
+	return nil
+}
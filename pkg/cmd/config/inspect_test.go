@@ -0,0 +1,123 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+)
+
+var _ = Describe("Inspecting the config", func() {
+	var (
+		home, repo string
+		env        map[string]string
+	)
+	getenv := func(key string) string { return env[key] }
+
+	// write Creates the file in the given directory.
+	write := func(dir, name, content string) {
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(Succeed())
+	}
+	layers := func() []config.Layer {
+		l, err := config.Layers([]string{home, repo}, getenv)
+		Expect(err).NotTo(HaveOccurred())
+		return l
+	}
+	// find Returns the effective setting with the given key.
+	find := func(settings []config.Setting, key string) config.Setting {
+		for _, s := range settings {
+			if s.Key == key {
+				return s
+			}
+		}
+		return config.Setting{}
+	}
+
+	BeforeEach(func() {
+		home, repo = GinkgoT().TempDir(), GinkgoT().TempDir()
+		env = map[string]string{}
+		Expect(os.MkdirAll(filepath.Join(repo, "app"), 0755)).To(Succeed())
+		write(filepath.Join(repo, "app"), "pod.yaml", "kind: Pod\n")
+		// the state directory shares the config's name and must not be mistaken for it
+		Expect(os.MkdirAll(filepath.Join(repo, config.StateDir), 0755)).To(Succeed())
+	})
+
+	It("uses the first config file found", func() {
+		write(home, config.ConfigFile, "backend: ollama\n")
+		write(repo, config.ConfigFile, "backend: gpt-3\n")
+		Expect(config.FindConfigFile([]string{home, repo}, config.ConfigName)).To(Equal(filepath.Join(home, config.ConfigFile)))
+		Expect(config.FindConfigFile([]string{repo}, config.ConfigFileLocal)).To(BeEmpty())
+	})
+
+	It("reports the source of every effective setting", func() {
+		write(repo, config.ConfigFile, "backend: ollama\nollama:\n  model: llama3\nfilesets:\n  - name: app\n    files: [app/*.yaml]\n")
+		write(repo, config.ConfigFileLocal+".yaml", "openAI:\n  apiKey: sk-from-file\n")
+		env["OLLAMA_HOST"] = "gpu:11434"
+
+		settings := config.Effective(layers())
+		local := filepath.Join(repo, config.ConfigFileLocal+".yaml")
+		Expect(find(settings, "backend")).To(Equal(config.Setting{Key: "backend", Value: "ollama", Source: filepath.Join(repo, config.ConfigFile)}))
+		Expect(find(settings, "ollama.model").Value).To(Equal("llama3"))
+		Expect(find(settings, "ollama.url")).To(Equal(config.Setting{Key: "ollama.url", Value: "gpu:11434", Source: "env OLLAMA_HOST"}))
+		Expect(find(settings, "openai.url").Source).To(Equal(config.SourceDefault))
+		Expect(find(settings, "openai.apikey").Source).To(Equal(local))
+
+		redacted := config.Redact(settings)
+		Expect(find(redacted, "openai.apikey").Value).To(Equal(config.Redacted))
+		Expect(find(settings, "openai.apikey").Value).To(Equal("sk-from-file"))
+
+		conf, err := config.Decode(settings)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conf.FindFileset("app").Files).To(Equal([]string{"app/*.yaml"}))
+	})
+
+	It("accepts a valid config", func() {
+		write(repo, config.ConfigFile, "filesets:\n  - name: app\n    files: [app/*.yaml]\n")
+		env["OPENAI_API_KEY"] = "sk-test"
+		Expect(config.Validate(layers(), repo)).To(BeEmpty())
+	})
+
+	It("reports unknown keys, wrong types, broken filesets and missing backend settings", func() {
+		write(repo, config.ConfigFile, `backend: gpt-3
+openAI:
+  url: not-a-url
+  temperature: 2
+ollama:
+  options:
+    num_ctx: 4096
+filesets:
+  - name: app
+    files: [app/*.yaml, missing/*.yaml, "[invalid"]
+  - name: app
+    files: []
+`)
+		issues := config.Validate(layers(), repo)
+		messages := make([]string, len(issues))
+		for i, issue := range issues {
+			messages[i] = issue.Severity + " " + issue.Key + " " + issue.Message
+		}
+		Expect(messages).To(ConsistOf(
+			"warning openai.temperature unknown key",
+			`warning filesets[0] glob "missing/*.yaml" of fileset "app" matches no files`,
+			ContainSubstring(`error filesets[0] fileset "app" has an invalid glob "[invalid"`),
+			`error filesets[1] fileset "app" is defined more than once`,
+			`warning filesets[1] fileset "app" has no files`,
+			"error openai.apikey no OpenAI API key, set OPENAI_API_KEY or openAI.apiKey in .copilot-ops.local.yaml",
+			`error openai.url invalid URL "not-a-url": it must include a scheme and a host`,
+		))
+	})
+
+	It("reports values of the wrong type and unknown backends", func() {
+		write(repo, config.ConfigFile, "backend: gpt-5\nfilesets: {name: app}\n")
+		issues := config.Validate(layers(), repo)
+		Expect(issues).To(ContainElement(HaveField("Severity", config.SeverityError)))
+		Expect(issues[0].Source).To(Equal(filepath.Join(repo, config.ConfigFile)))
+	})
+
+	It("knows the keys of the config", func() {
+		Expect(config.KnownKeys()).To(ContainElements("backend", "filesets", "openai.apikey", "openai.url", "ollama.options.*"))
+	})
+})
@@ -22,6 +22,44 @@ const (
 	FlagOutputTypeShort   = "o"
 	FlagAIBackendFull     = "backend"
 	FlagAIBackendShort    = "b"
+	FlagModelFull         = "model"
+	FlagModelShort        = "m"
+	FlagSystemFull        = "system"
+	FlagSessionFull       = "session"
+	FlagContinueFull      = "continue"
+	FlagRunFull           = "run"
+	FlagInteractiveFull   = "interactive"
+	FlagInteractiveShort  = "i"
+	FlagPickFull          = "pick"
+	FlagForceFull         = "force"
+	FlagDryRunFull        = "dry-run"
+	FlagBodyFileFull      = "body-file"
+	FlagTitleFull         = "title"
+	FlagListenFull        = "listen"
+	FlagGitHubURLFull     = "github-url"
+	FlagGitLabURLFull     = "gitlab-url"
+	FlagLabelFull         = "label"
+	FlagTriggerFull       = "trigger"
+	FlagAssociationsFull  = "associations"
+	FlagAccessLevelFull   = "gitlab-access-level"
+	FlagAllowCIFull       = "allow-ci"
+	FlagMaxBodySizeFull   = "max-body-size"
+	FlagMaxConcurrentFull = "max-concurrent"
+	FlagGRPCListenFull    = "grpc-listen"
+	FlagResultsFull       = "results"
+	FlagWorkersFull       = "workers"
+	FlagRetryFailedFull   = "retry-failed"
+	FlagDatasetFull       = "dataset"
+	FlagGitCommitFull     = "git-commit"
+	FlagGitBranchFull     = "git-branch"
+	FlagChangedSinceFull  = "changed-since"
+	FlagStagedFull        = "staged"
+	FlagRefFull           = "ref"
+	FlagTrackedOnlyFull   = "tracked-only"
+
+	// the limits of the completions and tokens requests to the server may ask for
+	FlagMaxCompletionsFull = "max-ncompletions"
+	FlagMaxTokensFull      = "max-ntokens"
 )
 
 // COMMAND Constants which define the names of commands used in the CLI.
@@ -29,10 +67,65 @@ const (
 	CommandEdit     = "edit"
 	CommandGenerate = "generate"
 	CommandAsk      = "ask"
+	CommandChat     = "chat"
+	CommandRefine   = "refine"
+	CommandExplain  = "explain"
+	CommandReview   = "review"
+	CommandInit     = "init"
+	CommandConfig   = "config"
+	CommandView     = "view"
+	CommandValidate = "validate"
+	CommandFileset  = "fileset"
+	CommandList     = "list"
+	CommandShow     = "show"
+	CommandIssue    = "issue"
+	CommandBot      = "bot"
+	CommandComment  = "comment"
+	CommandServe    = "serve"
+	CommandLSP      = "lsp"
+	CommandBatch    = "batch"
+	CommandEval     = "eval"
+	CommandHistory  = "history"
+	CommandUndo     = "undo"
+)
+
+// Output formats used by commands which do not print a filemap.
+const (
+	OutputMarkdown = "markdown"
+	OutputText     = "text"
+	OutputSARIF    = "sarif"
+	OutputDiff     = "diff"
+)
+
+// Environment variables which hold the secrets of the bot.
+const (
+	EnvGitHubWebhookSecret = "GITHUB_WEBHOOK_SECRET"
+	EnvGitHubToken         = "GITHUB_TOKEN"
+	EnvGitHubAppID         = "GITHUB_APP_ID"
+	EnvGitHubAppPrivateKey = "GITHUB_APP_PRIVATE_KEY"
+	EnvGitLabWebhookSecret = "GITLAB_WEBHOOK_SECRET"
+	EnvGitLabToken         = "GITLAB_TOKEN"
 )
 
 // Miscellaneous constants used in the CLI.
 const (
 	DefaultTokens      = 1000
 	DefaultCompletions = 1
+	// DefaultListenAddress Is the address servers listen on unless told otherwise.
+	DefaultListenAddress = ":8080"
+	// DefaultMaxBodySize Is the largest request body the API accepts unless told otherwise, in bytes.
+	DefaultMaxBodySize = 1 << 20
+	// DefaultMaxConcurrent Is the number of API requests handled at once unless told otherwise.
+	DefaultMaxConcurrent = 4
+	// DefaultWorkers Is the number of items of a batch run at once unless told otherwise.
+	DefaultWorkers = 4
+	// ResultsSuffix Replaces the extension of a batch's input to name its results unless told otherwise.
+	ResultsSuffix = ".results.jsonl"
+	// DefaultDataset Is the dataset evaluated unless told otherwise.
+	DefaultDataset = "data-lab/examples"
+	// StdinArg Is the argument used to read input from STDIN instead of the command-line.
+	StdinArg = "-"
+	// DefaultSystemPrompt Is the system message sent to chat models when none is provided.
+	DefaultSystemPrompt = "You are a helpful assistant for DevOps engineers " +
+		"working with Kubernetes YAML and other configuration files."
 )
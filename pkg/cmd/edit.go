@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"log"
+	"os"
 
 	"github.com/redhat-et/copilot-ops/pkg/ai"
 	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
+	"github.com/redhat-et/copilot-ops/pkg/candidates"
+	"github.com/redhat-et/copilot-ops/pkg/diff"
 	"github.com/redhat-et/copilot-ops/pkg/filemap"
 	"github.com/spf13/cobra"
 )
@@ -14,21 +19,32 @@ func NewEditCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use: CommandEdit,
 
-		Short: "Edits a single file provided to the CLI",
+		Short: "Edits the files provided to the CLI",
 
-		Long: "Given a file and a request, edit will pinpoint what changes are being requested and attempt to make them.",
+		Long: "Given a set of files and a request, edit will pinpoint what changes are being requested " +
+			"and attempt to make them, sending all of the files together so that related changes " +
+			"can be made across several files in one request.",
 
-		Example: `  copilot-ops edit --file examples/app1/mysql-pvc.yaml --request 'Increase the size of the PVC to 100Gi'`,
+		Example: `  copilot-ops edit --file examples/app1/mysql-pvc.yaml --request 'Increase the size of the PVC to 100Gi'
+  copilot-ops edit --file 'examples/app1/*.yaml' --fileset app2 --request 'Rename the app label to web'`,
 
 		RunE: RunEdit,
 	}
 
 	AddRequestFlags(cmd)
+	AddContinueFlag(cmd)
+	AddInteractiveFlag(cmd)
+	AddFileFlags(cmd)
+
+	cmd.Flags().Int32P(
+		FlagNCompletionsFull, FlagNCompletionsShort, DefaultCompletions,
+		"Number of candidate edits to request; without --"+FlagPickFull+
+			" or --"+FlagInteractiveFull+", JSON output lists every candidate",
+	)
 
-	// flag to add a file
-	cmd.Flags().StringP(
-		FlagFilesFull, FlagFilesShort, "",
-		"File path to the document which should be edited.",
+	cmd.Flags().Int(
+		FlagPickFull, 0,
+		"Rank of the candidate edit to use, starting at 1 (defaults to the best ranked candidate)",
 	)
 
 	return cmd
@@ -40,11 +56,34 @@ func RunEdit(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if isContinue, _ := cmd.Flags().GetBool(FlagContinueFull); isContinue {
+		if err = ContinueRun(r, ""); err != nil {
+			return err
+		}
+	}
+	if len(r.Filemap.Files) == 0 {
+		return fmt.Errorf("no files to edit, use --%s or --%s", FlagFilesFull, FlagFilesetsFull)
+	}
 
-	// trigger GPT-3 to preserve the @tagname format in the file
-	editSuffix := fmt.Sprintf("The resulting file should preserve the '# %stagname'"+
-		" format used to identify the YAML(s).", filemap.FileTagPrefix)
-	editInstruction := fmt.Sprintf("%s\n\n%s", r.UserRequest, editSuffix)
+	if err = ProposeEdit(r); err != nil {
+		return err
+	}
+	selected, err := SelectCandidate(r)
+	if err != nil {
+		return err
+	}
+	SaveRun(r)
+	if !selected {
+		return nil
+	}
+
+	return PrintOrWriteOut(r)
+}
+
+// ProposeEdit Requests the backend to edit the request's files in accordance with
+// the user request, and decodes the response into the request's filemap.
+func ProposeEdit(r *Request) error {
+	editInstruction := PrepareEditInstruction(r)
 
 	// create a client for editing
 	client, err := PrepareEditClient(r, r.FilemapText, editInstruction)
@@ -56,13 +95,108 @@ func RunEdit(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("could not edit files: %w", err)
 	}
-	output := responses[0]
-	err = r.Filemap.DecodeFromOutput(output)
-	if err != nil {
-		return err
+	if len(responses) == 0 {
+		return fmt.Errorf("no edits were returned")
+	}
+	r.Prompt = editInstruction
+	r.Responses = responses
+
+	// every response is decoded on top of the original files, then ranked
+	r.Candidates = candidates.Decode(r.Original, responses)
+	candidates.Rank(r.Candidates)
+	best := r.Candidates[0]
+	if best.Error != "" {
+		return fmt.Errorf("could not decode edit: %s", best.Error)
+	}
+	if len(r.Candidates) > 1 {
+		log.Printf("ranked %d candidates, best is response %d\n", len(r.Candidates), best.Index)
 	}
+	r.Filemap = best.Filemap
+	return nil
+}
 
-	return PrintOrWriteOut(r)
+// SelectCandidate Replaces the request's files with the candidate picked with --pick,
+// or chosen interactively. Without either, JSON output lists every candidate instead,
+// in which case false is returned since there is nothing left to print or write.
+func SelectCandidate(r *Request) (bool, error) {
+	if len(r.Candidates) < 2 && r.Pick <= 1 {
+		return true, nil
+	}
+	switch {
+	case r.Pick > 0:
+		if r.Pick > len(r.Candidates) {
+			return false, fmt.Errorf("cannot pick candidate %d, only %d were returned", r.Pick, len(r.Candidates))
+		}
+		return true, useCandidate(r, r.Pick-1)
+	case r.IsInteractive:
+		options := make([]string, len(r.Candidates))
+		for i, c := range r.Candidates {
+			options[i] = describeCandidate(c)
+		}
+		picked, err := r.Reviewer().Choose(options, func(i int) string {
+			return diff.Filemaps(r.Original, r.Candidates[i].Filemap)
+		})
+		if err != nil || picked < 0 {
+			return false, err
+		}
+		return true, useCandidate(r, picked)
+	case !r.IsWrite && r.OutputType == filemap.OutputJSON:
+		out, err := candidates.JSON(r.Candidates)
+		if err != nil {
+			return false, err
+		}
+		if _, err = os.Stdout.WriteString(out); err != nil {
+			return false, fmt.Errorf("could not write to stdout: %w", err)
+		}
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+// useCandidate Replaces the request's files with the candidate at the given index.
+func useCandidate(r *Request, i int) error {
+	c := r.Candidates[i]
+	if c.Error != "" {
+		return fmt.Errorf("candidate %d could not be decoded: %s", i+1, c.Error)
+	}
+	log.Printf("using candidate %d (response %d)\n", i+1, c.Index)
+	r.Filemap = c.Filemap
+	return nil
+}
+
+// describeCandidate Summarizes a candidate for the interactive selection.
+func describeCandidate(c candidates.Candidate) string {
+	if c.Error != "" {
+		return "invalid: " + c.Error
+	}
+	valid := 0
+	for _, result := range c.Validation {
+		if result.Valid() {
+			valid++
+		}
+	}
+	return fmt.Sprintf("+%d -%d lines, %d/%d changed files valid", c.Added, c.Removed, valid, len(c.Validation))
+}
+
+// PrepareEditInstruction Returns the instruction sent to the edit backend, which
+// includes the request being refined when continuing a previous run.
+func PrepareEditInstruction(r *Request) string {
+	instruction := r.UserRequest
+	if r.ParentRequest != "" {
+		instruction = fmt.Sprintf("%s\n\nThe files are the result of a previous request, "+
+			"whose changes should be kept: %q", r.UserRequest, r.ParentRequest)
+	}
+
+	// trigger GPT-3 to preserve the @tagname format in the file
+	editSuffix := fmt.Sprintf("The resulting file should preserve the '# %stagname'"+
+		" format used to identify the YAML(s).", filemap.FileTagPrefix)
+	// every file has to come back under its own tag for changes to be applied across files
+	if r.Filemap != nil && len(r.Filemap.Files) > 1 {
+		editSuffix += fmt.Sprintf(" Every file must be kept, and files must remain separated by '%s'.",
+			filemap.FileDelimeter)
+	}
+	return fmt.Sprintf("%s\n\n%s", instruction, editSuffix)
 }
 
 // PrepareEditClient Returns an AI Client which implements the EditClient interface.
@@ -75,7 +209,13 @@ func PrepareEditClient(r *Request, input, instruction string) (ai.EditClient, er
 		if config == nil {
 			return nil, fmt.Errorf("no openai config provided")
 		}
-		client = gpt3.CreateGPT3EditClient(*r.Config.OpenAI, input, instruction, 1, nil, nil)
+		client = gpt3.CreateGPT3EditClient(r.Context(), *r.Config.OpenAI, input, instruction, r.completions(), nil, nil)
+	case ai.OLLAMA:
+		conf, err := ollamaConfig(r)
+		if err != nil {
+			return nil, err
+		}
+		client = ollama.CreateOllamaEditClient(r.Context(), conf, input, instruction, r.completions())
 	case ai.GPTJ:
 		return nil, fmt.Errorf("editing is not implemented for gpt-j")
 	case ai.BLOOM:
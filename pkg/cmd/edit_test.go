@@ -1,7 +1,17 @@
 package cmd_test
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/candidates"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
 )
 
 var _ = Describe("Edit", func() {
@@ -36,4 +46,87 @@ var _ = Describe("Edit", func() {
 	// 	})
 
 	// })
+
+	It("accepts repeated files and filesets", func() {
+		c := cmd.NewEditCmd()
+		Expect(c.Flags().Parse([]string{
+			"--" + cmd.FlagFilesFull, "a.yaml", "--" + cmd.FlagFilesFull, "b/*.yaml",
+			"--" + cmd.FlagFilesetsFull, "app1",
+		})).To(Succeed())
+		files, err := c.Flags().GetStringArray(cmd.FlagFilesFull)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{"a.yaml", "b/*.yaml"}))
+		filesets, err := c.Flags().GetStringArray(cmd.FlagFilesetsFull)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filesets).To(Equal([]string{"app1"}))
+	})
+
+	It("sends a file matched by several globs and filesets once", func() {
+		cwd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.Chdir, cwd)
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "pvc.yaml"), []byte("kind: PersistentVolumeClaim\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "pod.yaml"), []byte("kind: Pod\n"), 0o600)).To(Succeed())
+
+		c := cmd.NewEditCmd()
+		Expect(c.Flags().Parse([]string{
+			"--" + cmd.FlagPathFull, dir,
+			"--" + cmd.FlagFilesFull, "pvc.yaml", "--" + cmd.FlagFilesFull, "*.yaml", "--" + cmd.FlagFilesFull, "./pvc.yaml",
+		})).To(Succeed())
+		r, err := cmd.PrepareRequest(c)
+		Expect(err).NotTo(HaveOccurred())
+		conf := config.Config{Filesets: []config.Filesets{
+			{Name: "app", Files: []string{"*.yaml"}},
+			{Name: "storage", Files: []string{filepath.Join(dir, "pvc.yaml")}},
+		}}
+		Expect(r.Filemap.LoadFilesets([]string{"app", "storage"}, conf, config.ConfigFile)).To(Succeed())
+
+		Expect(r.Filemap.Tags()).To(Equal([]string{"pod.yaml", "pvc.yaml"}))
+		encoded := r.Filemap.EncodeToInputText()
+		Expect(strings.Count(encoded, "kind: PersistentVolumeClaim")).To(Equal(1))
+		Expect(strings.Count(encoded, "# "+filemap.FileTagPrefix)).To(Equal(2))
+		Expect(strings.Count(r.FilemapText, "kind: PersistentVolumeClaim")).To(Equal(1))
+	})
+
+	It("asks to keep every file when editing several files", func() {
+		r := &cmd.Request{UserRequest: "rename the app", Filemap: filemap.NewFilemap()}
+		r.Filemap.Files["a.yaml"] = filemap.File{Path: "a.yaml"}
+		Expect(cmd.PrepareEditInstruction(r)).NotTo(ContainSubstring(filemap.FileDelimeter))
+
+		r.Filemap.Files["b.yaml"] = filemap.File{Path: "b.yaml"}
+		Expect(cmd.PrepareEditInstruction(r)).To(ContainSubstring("separated by '" + filemap.FileDelimeter + "'"))
+	})
+
+	Describe("selecting candidates", func() {
+		var r *cmd.Request
+
+		BeforeEach(func() {
+			original := filemap.NewFilemap()
+			original.Files["pvc.yaml"] = filemap.File{Path: "pvc.yaml", Content: "storage: 1Gi\n"}
+			r = &cmd.Request{Original: original, Filemap: original.Clone(), IsWrite: true}
+			r.Candidates = candidates.Decode(original, []string{
+				"# @pvc.yaml\nstorage: 100Gi\n",
+				"# @pvc.yaml\nstorage: 200Gi\n",
+			})
+			r.Filemap = r.Candidates[0].Filemap
+		})
+
+		It("keeps the best candidate by default", func() {
+			Expect(cmd.SelectCandidate(r)).To(BeTrue())
+			Expect(r.Filemap.Files["pvc.yaml"].Content).To(Equal("storage: 100Gi\n"))
+		})
+
+		It("uses the picked candidate", func() {
+			r.Pick = 2
+			Expect(cmd.SelectCandidate(r)).To(BeTrue())
+			Expect(r.Filemap.Files["pvc.yaml"].Content).To(Equal("storage: 200Gi\n"))
+		})
+
+		It("rejects picks beyond the returned candidates", func() {
+			r.Pick = 3
+			_, err := cmd.SelectCandidate(r)
+			Expect(err).To(MatchError(ContainSubstring("only 2 were returned")))
+		})
+	})
 })
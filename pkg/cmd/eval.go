@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/redhat-et/copilot-ops/pkg/eval"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/spf13/cobra"
+)
+
+// NewEvalCmd Creates the `copilot-ops eval` CLI command.
+func NewEvalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandEval,
+
+		Short: "Scores a backend against a dataset of issues",
+
+		Long: "Eval runs every issue of the --" + FlagDatasetFull + " through the backend, editing the files " +
+			"the issue references, or generating new ones when it references none. The proposed files are " +
+			"compared to the files each issue expects, both exactly and as YAML documents, and checked " +
+			"for validity. The scorecard lets prompts, backends, and models be compared objectively.\n\n" +
+			"The dataset holds one directory per example, with the repo under " + eval.FilesDir + "/, " +
+			"and every issue under " + eval.IssuesDir + "/<id>/" + eval.IssueFile + ", along with the " +
+			"files expected after it under " + eval.IssuesDir + "/<id>/" + eval.ExpectedDir + "/. " +
+			"Files are never written.",
+
+		Example: `  copilot-ops eval
+  copilot-ops eval --dataset data-lab/examples --backend gpt-3.5 --model gpt-4 --output json > scorecard.json`,
+
+		RunE: RunEval,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().String(
+		FlagDatasetFull, DefaultDataset,
+		"Path to the directory holding the examples to evaluate",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, OutputText,
+		"Output format of the scorecard, one of "+OutputText+" or "+filemap.OutputJSON,
+	)
+
+	cmd.Flags().Int32P(
+		FlagNCompletionsFull, FlagNCompletionsShort, DefaultCompletions,
+		"Number of edits to request for each issue",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNTokensFull, FlagNTokensShort, DefaultTokens,
+		"Max number of tokens to generate for each issue",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Model of the backend to evaluate",
+	)
+
+	AddBackendFlags(cmd)
+
+	return cmd
+}
+
+// RunEval Runs when the `eval` command is invoked.
+func RunEval(cmd *cobra.Command, args []string) error {
+	dataset, _ := cmd.Flags().GetString(FlagDatasetFull)
+	outputType, _ := cmd.Flags().GetString(FlagOutputTypeFull)
+	if outputType != OutputText && outputType != filemap.OutputJSON {
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+
+	cases, err := eval.LoadDataset(dataset)
+	if err != nil {
+		return fmt.Errorf("could not load the dataset: %w", err)
+	}
+	// the files of the cases are read relative to where the command was run
+	for _, c := range cases {
+		if c.Root, err = filepath.Abs(c.Root); err != nil {
+			return err
+		}
+	}
+
+	base, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("evaluating %d issues of %s with the %s backend\n", len(cases), dataset, base.Backend)
+	scores := eval.Evaluate(ctx, cases, EvalRunner(base))
+	scorecard := &eval.Scorecard{
+		Backend: string(base.Backend),
+		Model:   base.Model,
+		Scores:  scores,
+		Summary: eval.Summarize(scores),
+	}
+	if err = PrintScorecard(cmd.OutOrStdout(), outputType, scorecard); err != nil {
+		return err
+	}
+	if len(scores) < len(cases) {
+		return fmt.Errorf("interrupted with %d issues left", len(cases)-len(scores))
+	}
+	return nil
+}
+
+// EvalRunner Returns the runner of the cases of a dataset, which runs each case's
+// command on a copy of the base request, with the issue as the request. The calls to the
+// backend are canceled with ctx, e.g. when the evaluation is interrupted.
+func EvalRunner(base *Request) eval.Runner {
+	return func(ctx context.Context, c *eval.Case, files *filemap.Filemap) (*filemap.Filemap, error) {
+		r := *base
+		r.SetContext(ctx)
+		r.Command = c.Command()
+		r.UserRequest = c.Issue.Request()
+		r.Candidates = nil
+		r.Parent, r.ParentRequest = "", ""
+		r.Filemap = files
+		r.Original = files.Clone()
+		r.FilemapText = files.EncodeToInputText()
+		if _, err := ProposeCommand(&r); err != nil {
+			return nil, err
+		}
+		return r.Filemap, nil
+	}
+}
+
+// PrintScorecard Writes the scorecard in the requested output format.
+func PrintScorecard(w io.Writer, outputType string, scorecard *eval.Scorecard) error {
+	switch outputType {
+	case OutputText:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CASE\tCOMMAND\tEXACT\tSEMANTIC\tVALID\tTIME\tERROR")
+		for _, score := range scorecard.Scores {
+			exact, semantic := 0, 0
+			for _, file := range score.Files {
+				if file.Exact {
+					exact++
+				}
+				if file.Semantic {
+					semantic++
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%d/%d\t%.0f%%\t%.1fs\t%s\n", score.Case, score.Command,
+				exact, len(score.Files), semantic, len(score.Files), score.Valid*100, score.Duration, score.Error)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		summary := scorecard.Summary
+		_, err := fmt.Fprintf(w, "\n%s %s: %d cases, %d errors, %d unchanged, "+
+			"%.0f%% exact, %.0f%% semantic of %d expected files, %.0f%% valid\n",
+			scorecard.Backend, scorecard.Model, summary.Cases, summary.Errors, summary.Unchanged,
+			summary.Exact*100, summary.Semantic*100, summary.Expected, summary.Valid*100)
+		return err
+	case filemap.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(scorecard)
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
@@ -0,0 +1,107 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/eval"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+var _ = Describe("Eval command", func() {
+	var (
+		c      *cobra.Command
+		ts     *httptest.Server
+		stdout *bytes.Buffer
+		cwd    string
+	)
+
+	BeforeEach(func() {
+		ts = OpenAITestServer()
+		ts.Start()
+		stdout = &bytes.Buffer{}
+		c = cmd.NewEvalCmd()
+		c.SetOut(stdout)
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagAIBackendFull, string(ai.GPT3))).To(Succeed())
+
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+		for path, content := range map[string]string{
+			"dataset/app/files/pod.yaml":              "kind: Pod\n",
+			"dataset/app/issues/1/issue.md":           "Rename `@pod:pod.yaml` to cute-cats",
+			"dataset/app/issues/1/expected/pod.yaml":  "kind: Pod\nmetadata:\n  name: cute-cats\n",
+			"dataset/app/issues/2/issue.md":           "Add a pod running nginx",
+			"dataset/app/issues/2/expected/nginx.yml": "kind: Pod\n",
+		} {
+			Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		}
+		Expect(c.Flags().Set(cmd.FlagDatasetFull, "dataset")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+		ts.Close()
+	})
+
+	It("is registered on the root command", func() {
+		found, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandEval})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name()).To(Equal(cmd.CommandEval))
+	})
+
+	It("prints the scorecard of every issue", func() {
+		Expect(cmd.RunEval(c, nil)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("app/1"))
+		Expect(stdout.String()).To(ContainSubstring("2 cases, 0 errors"))
+	})
+
+	It("prints the scorecard as JSON", func() {
+		Expect(c.Flags().Set(cmd.FlagOutputTypeFull, filemap.OutputJSON)).To(Succeed())
+		Expect(cmd.RunEval(c, nil)).To(Succeed())
+
+		var scorecard eval.Scorecard
+		Expect(json.Unmarshal(stdout.Bytes(), &scorecard)).To(Succeed())
+		Expect(scorecard.Backend).To(Equal(string(ai.GPT3)))
+		Expect(scorecard.Scores).To(HaveLen(2))
+		Expect(scorecard.Scores[0].Command).To(Equal(cmd.CommandEdit))
+		Expect(scorecard.Scores[1].Command).To(Equal(cmd.CommandGenerate))
+		Expect(scorecard.Summary.Expected).To(Equal(2))
+	})
+
+	It("stops calling the backend once the evaluation is interrupted", func() {
+		cases, err := eval.LoadDataset("dataset")
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		files, err := cases[0].Files()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = cmd.EvalRunner(&cmd.Request{
+			Backend:      ai.GPT3,
+			NTokens:      cmd.DefaultTokens,
+			NCompletions: cmd.DefaultCompletions,
+			Config:       config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+		})(ctx, cases[0], files)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("rejects unknown output types", func() {
+		Expect(c.Flags().Set(cmd.FlagOutputTypeFull, "xml")).To(Succeed())
+		Expect(cmd.RunEval(c, nil)).To(MatchError(ContainSubstring("invalid output type")))
+	})
+})
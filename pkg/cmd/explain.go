@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/redhat-et/copilot-ops/pkg/explain"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/spf13/cobra"
+)
+
+// NewExplainCmd Creates the `copilot-ops explain` CLI command.
+func NewExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandExplain + " [QUESTION]",
+
+		Short: "Explains what a set of manifests does",
+
+		Long: "Explain sends the given files to a chat model and asks for a structured explanation " +
+			"of their purpose, the resources they define, how they reference each other, and their risks.",
+
+		Example: `  copilot-ops explain --fileset cluster-scope-overlays
+  copilot-ops explain --file 'examples/stale_dev_overlay/*.yaml' 'which group does this patch?' --output json`,
+
+		RunE: RunExplain,
+		Args: cobra.MaximumNArgs(1),
+	}
+
+	AddFileFlags(cmd)
+	AddBackendFlags(cmd)
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, OutputMarkdown,
+		"How to format output: markdown or json",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Chat model to use (defaults to gpt-3.5-turbo for gpt-3, and the configured model for ollama)",
+	)
+
+	return cmd
+}
+
+// RunExplain Runs when the `explain` command is invoked.
+func RunExplain(cmd *cobra.Command, args []string) error {
+	r, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	if len(r.Filemap.Files) == 0 {
+		return fmt.Errorf("no files to explain, use --%s or --%s", FlagFilesFull, FlagFilesetsFull)
+	}
+	if len(args) > 0 {
+		r.UserRequest = args[0]
+	}
+
+	client, err := PrepareChatClient(r, explain.PrepareMessages(r.Filemap, r.UserRequest))
+	if err != nil {
+		return fmt.Errorf("could not create client: %w", err)
+	}
+	answer, err := client.Chat()
+	if err != nil {
+		return err
+	}
+	explanation, err := explain.Parse(answer)
+	if err != nil {
+		return err
+	}
+
+	return printExplanation(cmd.OutOrStdout(), r.OutputType, explanation)
+}
+
+// printExplanation Writes the explanation to the given writer in the requested output format.
+func printExplanation(w io.Writer, outputType string, explanation *explain.Explanation) error {
+	switch outputType {
+	case OutputMarkdown:
+		_, err := io.WriteString(w, explanation.Markdown())
+		return err
+	case filemap.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(explanation)
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
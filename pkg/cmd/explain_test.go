@@ -0,0 +1,84 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/explain"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+var _ = Describe("Explain command", func() {
+	const answer = "Here you go:\n```json\n" + `{
+  "summary": "A claim for the storage of MySQL.",
+  "purpose": "Keeps the data of the database.",
+  "resources": [
+    {"file": "mysql-pvc.yaml", "kind": "PersistentVolumeClaim", "name": "mysql", "description": "the storage"}
+  ],
+  "risks": [{"file": "mysql-pvc.yaml", "severity": "low", "description": "no storage class is set"}]
+}` + "\n```"
+
+	var (
+		c      *cobra.Command
+		ts     *httptest.Server
+		stdout *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		ts = ChatTestServer(answer)
+		ts.Start()
+		stdout = &bytes.Buffer{}
+		c = cmd.NewExplainCmd()
+		c.SetOut(stdout)
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagAIBackendFull, string(ai.GPT3))).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagFilesFull, "../../examples/app1/mysql-pvc.yaml")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("is registered on the root command", func() {
+		found, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandExplain})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name()).To(Equal(cmd.CommandExplain))
+	})
+
+	It("prints the explanation as markdown", func() {
+		Expect(cmd.RunExplain(c, []string{"what does this claim?"})).To(Succeed())
+		Expect(stdout.String()).To(HavePrefix("# Explanation\n\nA claim for the storage of MySQL."))
+		Expect(stdout.String()).To(ContainSubstring("| mysql-pvc.yaml | PersistentVolumeClaim | mysql |"))
+		Expect(stdout.String()).To(ContainSubstring("- **low** (`mysql-pvc.yaml`): no storage class is set"))
+	})
+
+	It("prints the explanation as JSON", func() {
+		Expect(c.Flags().Set(cmd.FlagOutputTypeFull, filemap.OutputJSON)).To(Succeed())
+		Expect(cmd.RunExplain(c, nil)).To(Succeed())
+
+		var explanation explain.Explanation
+		Expect(json.Unmarshal(stdout.Bytes(), &explanation)).To(Succeed())
+		Expect(explanation.Purpose).To(Equal("Keeps the data of the database."))
+		Expect(explanation.Resources).To(HaveLen(1))
+		Expect(explanation.Resources[0].Kind).To(Equal("PersistentVolumeClaim"))
+	})
+
+	It("requires files to explain", func() {
+		c = cmd.NewExplainCmd()
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(cmd.RunExplain(c, nil)).To(MatchError(ContainSubstring("no files to explain")))
+	})
+
+	It("rejects unknown output types", func() {
+		Expect(c.Flags().Set(cmd.FlagOutputTypeFull, "xml")).To(Succeed())
+		Expect(cmd.RunExplain(c, nil)).To(MatchError(ContainSubstring("invalid output type")))
+	})
+})
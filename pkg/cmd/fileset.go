@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// FilesetFile Is a file which is loaded by a fileset.
+type FilesetFile struct {
+	Tag    string `json:"tag"`
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	Tokens int    `json:"tokens"`
+}
+
+// FilesetPreview Describes the files a fileset sends along with a request.
+type FilesetPreview struct {
+	Name  string        `json:"name"`
+	Globs []string      `json:"globs"`
+	Files []FilesetFile `json:"files"`
+	// Unmatched Lists the globs which match no files.
+	Unmatched []string `json:"unmatched,omitempty"`
+	// Tokens Is the estimated size of the files once encoded for a request.
+	Tokens int `json:"tokens"`
+}
+
+// NewFilesetCmd Creates the `copilot-ops fileset` CLI command, which groups the
+// commands used to inspect filesets.
+func NewFilesetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandFileset,
+
+		Short: "Lists and previews the filesets defined in " + config.ConfigFile,
+	}
+
+	cmd.AddCommand(NewFilesetListCmd())
+	cmd.AddCommand(NewFilesetShowCmd())
+
+	return cmd
+}
+
+// NewFilesetListCmd Creates the `copilot-ops fileset list` CLI command.
+func NewFilesetListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandList,
+
+		Short: "Lists the filesets and their globs",
+
+		Example: `  copilot-ops fileset list`,
+
+		RunE: RunFilesetList,
+		Args: cobra.NoArgs,
+	}
+	addFilesetFlags(cmd)
+	return cmd
+}
+
+// NewFilesetShowCmd Creates the `copilot-ops fileset show` CLI command.
+func NewFilesetShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandShow + " NAME",
+
+		Short: "Shows the files a fileset loads, with their sizes and estimated tokens",
+
+		Long: "Show expands the globs of the fileset exactly as they are expanded when the fileset is given " +
+			"with --" + FlagFilesetsFull + ", listing the files which would be sent along with a request.",
+
+		Example: `  copilot-ops fileset show app1`,
+
+		RunE: RunFilesetShow,
+		Args: cobra.ExactArgs(1),
+	}
+	addFilesetFlags(cmd)
+	return cmd
+}
+
+// addFilesetFlags Appends the flags shared by the fileset commands.
+func addFilesetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, OutputText,
+		"How to format output: text or json",
+	)
+}
+
+// RunFilesetList Runs when the `fileset list` command is invoked.
+func RunFilesetList(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString(FlagPathFull)
+	outputType, _ := cmd.Flags().GetString(FlagOutputTypeFull)
+	conf, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	return PrintFilesets(cmd.OutOrStdout(), outputType, conf.Filesets)
+}
+
+// RunFilesetShow Runs when the `fileset show` command is invoked.
+func RunFilesetShow(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString(FlagPathFull)
+	outputType, _ := cmd.Flags().GetString(FlagOutputTypeFull)
+	conf, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	preview, err := PreviewFileset(conf, args[0])
+	if err != nil {
+		return err
+	}
+	return PrintFilesetPreview(cmd.OutOrStdout(), outputType, preview)
+}
+
+// PreviewFileset Loads the fileset the same way requests do, and describes the loaded files.
+func PreviewFileset(conf config.Config, name string) (*FilesetPreview, error) {
+	fm := filemap.NewFilemap()
+	if err := fm.LoadFilesets([]string{name}, conf, config.ConfigFile); err != nil {
+		return nil, err
+	}
+	fileset := conf.FindFileset(name)
+	preview := &FilesetPreview{
+		Name:   name,
+		Globs:  fileset.Files,
+		Files:  []FilesetFile{},
+		Tokens: utils.EstimateTokens(fm.EncodeToInputText()),
+	}
+	for _, glob := range fileset.Files {
+		if matches, err := filepath.Glob(glob); err == nil && len(matches) == 0 {
+			preview.Unmatched = append(preview.Unmatched, glob)
+		}
+	}
+	for tag, file := range fm.Files {
+		preview.Files = append(preview.Files, FilesetFile{
+			Tag:    tag,
+			Path:   file.Path,
+			Size:   len(file.Content),
+			Tokens: utils.EstimateTokens(file.Content),
+		})
+	}
+	sort.Slice(preview.Files, func(i, j int) bool { return preview.Files[i].Path < preview.Files[j].Path })
+	return preview, nil
+}
+
+// PrintFilesets Writes the filesets in the requested output format.
+func PrintFilesets(w io.Writer, outputType string, filesets []config.Filesets) error {
+	switch outputType {
+	case OutputText:
+		if len(filesets) == 0 {
+			_, err := fmt.Fprintf(w, "no filesets are defined, run `copilot-ops %s` to create %s\n",
+				CommandInit, config.ConfigFile)
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tFILES")
+		for _, fileset := range filesets {
+			fmt.Fprintf(tw, "%s\t%s\n", fileset.Name, strings.Join(fileset.Files, " "))
+		}
+		return tw.Flush()
+	case filemap.OutputJSON:
+		if filesets == nil {
+			filesets = []config.Filesets{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(filesets)
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
+
+// PrintFilesetPreview Writes the files of a fileset in the requested output format.
+func PrintFilesetPreview(w io.Writer, outputType string, preview *FilesetPreview) error {
+	switch outputType {
+	case OutputText:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "TAG\tPATH\tSIZE\tTOKENS")
+		size := 0
+		for _, file := range preview.Files {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t~%d\n", file.Tag, file.Path, file.Size, file.Tokens)
+			size += file.Size
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\n%d file(s), %d bytes, ~%d tokens once encoded\n", len(preview.Files), size, preview.Tokens)
+		for _, glob := range preview.Unmatched {
+			fmt.Fprintf(w, "warning: %q matches no files\n", glob)
+		}
+		return nil
+	case filemap.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(preview)
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
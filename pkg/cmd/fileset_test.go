@@ -0,0 +1,74 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+var _ = Describe("Fileset command", func() {
+	var (
+		dir  string
+		conf config.Config
+		out  *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		out = &bytes.Buffer{}
+		Expect(os.WriteFile(filepath.Join(dir, "pod.yaml"), []byte(strings.Repeat("a", 10)), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "svc.yaml"), []byte("kind: Service\n"), 0600)).To(Succeed())
+		conf = config.Config{Filesets: []config.Filesets{{
+			Name:  "app",
+			Files: []string{filepath.Join(dir, "*.yaml"), filepath.Join(dir, "pod.yaml"), filepath.Join(dir, "*.json")},
+		}}}
+	})
+
+	It("lists the filesets", func() {
+		Expect(cmd.PrintFilesets(out, cmd.OutputText, conf.Filesets)).To(Succeed())
+		Expect(out.String()).To(HavePrefix("NAME"))
+		Expect(out.String()).To(ContainSubstring("app   " + filepath.Join(dir, "*.yaml")))
+
+		out.Reset()
+		Expect(cmd.PrintFilesets(out, cmd.OutputText, nil)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("no filesets are defined"))
+	})
+
+	It("previews the files exactly as they are loaded", func() {
+		preview, err := cmd.PreviewFileset(conf, "app")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(preview.Files).To(Equal([]cmd.FilesetFile{
+			{Tag: "pod.yaml", Path: filepath.Join(dir, "pod.yaml"), Size: 10, Tokens: 3},
+			{Tag: "svc.yaml", Path: filepath.Join(dir, "svc.yaml"), Size: 14, Tokens: 4},
+		}))
+		Expect(preview.Unmatched).To(Equal([]string{filepath.Join(dir, "*.json")}))
+
+		fm := filemap.NewFilemap()
+		Expect(fm.LoadFilesets([]string{"app"}, conf, config.ConfigFile)).To(Succeed())
+		Expect(preview.Files).To(HaveLen(len(fm.Files)))
+
+		Expect(cmd.PrintFilesetPreview(out, cmd.OutputText, preview)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("2 file(s), 24 bytes"))
+		Expect(out.String()).To(ContainSubstring("matches no files"))
+
+		out.Reset()
+		Expect(cmd.PrintFilesetPreview(out, filemap.OutputJSON, preview)).To(Succeed())
+		var decoded cmd.FilesetPreview
+		Expect(json.Unmarshal(out.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded.Name).To(Equal("app"))
+	})
+
+	It("fails for unknown filesets", func() {
+		_, err := cmd.PreviewFileset(conf, "missing")
+		Expect(err).To(MatchError(ContainSubstring("fileset missing not found")))
+	})
+})
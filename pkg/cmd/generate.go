@@ -8,7 +8,7 @@ import (
 
 	"github.com/redhat-et/copilot-ops/pkg/ai"
 	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
-	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
 	"github.com/redhat-et/copilot-ops/pkg/filemap"
 	"github.com/spf13/cobra"
 )
@@ -32,17 +32,11 @@ func NewGenerateCmd() *cobra.Command {
 	}
 
 	AddRequestFlags(cmd)
+	AddContinueFlag(cmd)
+	AddInteractiveFlag(cmd)
 
 	// generate-specific flags
-	cmd.Flags().StringArrayP(
-		FlagFilesFull, FlagFilesShort, []string{},
-		"File paths (glob) to be considered for the patch (can be specified multiple times)",
-	)
-
-	cmd.Flags().StringArrayP(
-		FlagFilesetsFull, FlagFilesetsShort, []string{},
-		"Fileset names (defined in "+config.ConfigFile+") to be considered for the patch (can be specified multiple times)",
-	)
+	AddFileFlags(cmd)
 
 	cmd.Flags().Int32P(
 		FlagNTokensFull, FlagNTokensShort, DefaultTokens,
@@ -63,6 +57,37 @@ func RunGenerate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	// continuing refines the previously generated files, which is an edit
+	if isContinue, _ := cmd.Flags().GetBool(FlagContinueFull); isContinue {
+		if err = ContinueRun(r, ""); err != nil {
+			return err
+		}
+		if err = ProposeEdit(r); err != nil {
+			return err
+		}
+		selected, err := SelectCandidate(r)
+		if err != nil {
+			return err
+		}
+		SaveRun(r)
+		if !selected {
+			return nil
+		}
+		return PrintOrWriteOut(r)
+	}
+
+	if err = ProposeGenerate(r); err != nil {
+		return err
+	}
+	SaveRun(r)
+
+	return PrintOrWriteOut(r)
+}
+
+// ProposeGenerate Requests the backend to generate new files in accordance with the
+// user request, using the request's files as context, and decodes the response into
+// the request's filemap.
+func ProposeGenerate(r *Request) error {
 	input := PrepareGenerateInput(r.UserRequest, r.FilemapText)
 	client, err := PrepareGenerateClient(r, input)
 	if err != nil {
@@ -72,6 +97,8 @@ func RunGenerate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("could not generate files: %w", err)
 	}
+	r.Prompt = input
+	r.Responses = choices
 
 	// decode the response
 	r.Filemap = filemap.NewFilemap()
@@ -83,17 +110,14 @@ func RunGenerate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if err == nil {
-		return PrintOrWriteOut(r)
+	if err != nil {
+		// HACK: try other way to decode the output to a fileset
+		log.Printf("decoding failed, got error: %s", err)
+		// fallback - generate new files and put the content inside
+		newFiles := generateNewFiles(choices)
+		r.Filemap.Files = newFiles
 	}
-
-	// HACK: try other way to decode the output to a fileset
-	log.Printf("decoding failed, got error: %s", err)
-	// fallback - generate new files and put the content inside
-	newFiles := generateNewFiles(choices)
-	r.Filemap.Files = newFiles
-
-	return PrintOrWriteOut(r)
+	return nil
 }
 
 // PrepareGenerateClient Returns a Generate client depending on which backend was
@@ -106,11 +130,24 @@ func PrepareGenerateClient(r *Request, prompt string) (ai.GenerateClient, error)
 			return nil, fmt.Errorf("no config provided for gpt-3")
 		}
 		client = gpt3.CreateGPT3GenerateClient(
+			r.Context(),
 			*r.Config.OpenAI,
 			prompt,
 			int(r.NTokens),
 			int(r.NCompletions),
 		)
+	case ai.OLLAMA:
+		conf, err := ollamaConfig(r)
+		if err != nil {
+			return nil, err
+		}
+		client = ollama.CreateOllamaGenerateClient(
+			r.Context(),
+			conf,
+			prompt,
+			int(r.NTokens),
+			int(r.NCompletions),
+		)
 	case ai.GPTJ:
 		return nil, fmt.Errorf("gpt-j does not implement the generate client")
 	case ai.BLOOM:
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/journal"
+	"github.com/redhat-et/copilot-ops/pkg/vcs"
+)
+
+// CommitSubjectWidth Is the number of characters of the request kept in the subject of a commit.
+const CommitSubjectWidth = 72
+
+// CommitWrite Commits the files changed by the journaled write, on a new branch when
+// --git-branch is set.
+func CommitWrite(r *Request, entry *journal.Entry) error {
+	var paths []string
+	for _, change := range entry.Files {
+		if change.Before != change.After {
+			paths = append(paths, change.Path)
+		}
+	}
+	if len(paths) == 0 {
+		log.Println("no files were changed, so nothing is committed")
+		return nil
+	}
+	hash, err := vcs.Commit(".", vcs.CommitOptions{
+		Branch:  r.GitBranch,
+		Message: CommitMessage(r, paths),
+		Paths:   paths,
+	})
+	if err != nil {
+		return fmt.Errorf("the files were written but not committed: %w", err)
+	}
+	log.Printf("committed %d file(s) as %s\n", len(paths), hash)
+	return nil
+}
+
+// CommitMessage Returns the message of the commit of the given paths: the request,
+// followed by why the model's edit was chosen, the changed files, and the run.
+func CommitMessage(r *Request, paths []string) string {
+	request := strings.TrimSpace(r.UserRequest)
+	subject, body, _ := strings.Cut(request, "\n")
+	if subject == "" {
+		subject = fmt.Sprintf("copilot-ops: %s %s", r.Command, strings.Join(paths, " "))
+	}
+	if runes := []rune(subject); len(runes) > CommitSubjectWidth {
+		body = strings.TrimSpace("..." + string(runes[CommitSubjectWidth-3:]) + "\n" + body)
+		subject = string(runes[:CommitSubjectWidth-3]) + "..."
+	}
+
+	var out strings.Builder
+	out.WriteString(subject + "\n\n")
+	if body = strings.TrimSpace(body); body != "" {
+		out.WriteString(body + "\n\n")
+	}
+
+	backend := string(r.Backend)
+	if r.Model != "" {
+		backend += " (" + r.Model + ")"
+	}
+	fmt.Fprintf(&out, "Proposed by %s through `copilot-ops %s`", backend, r.Command)
+	for i, c := range r.Candidates {
+		if c.Filemap != r.Filemap {
+			continue
+		}
+		fmt.Fprintf(&out, ", which picked edit %d of %d", i+1, len(r.Candidates))
+		if i == 0 && r.Pick == 0 && len(r.Candidates) > 1 {
+			out.WriteString(" as the best ranked")
+		}
+		fmt.Fprintf(&out, ": %s", describeCandidate(c))
+	}
+	out.WriteString(".\n\nChanged files:\n")
+	for _, path := range paths {
+		fmt.Fprintf(&out, "- %s\n", path)
+	}
+	if r.Run != "" {
+		fmt.Fprintf(&out, "\nRun: %s\n", r.Run)
+	}
+	return out.String()
+}
+
+// GitFiles Selects files through git, in addition to the files and filesets of a request.
+type GitFiles struct {
+	// ChangedSince Selects the files changed since HEAD forked from this revision,
+	// including uncommitted changes.
+	ChangedSince string
+	// Staged Selects the files with staged changes.
+	Staged bool
+	// Refs Select the files matching a glob in a revision, given as REV:GLOB, with their
+	// content at that revision.
+	Refs []string
+	// TrackedOnly Drops the files of the globs and filesets which git does not track.
+	TrackedOnly bool
+}
+
+// Empty Returns true when no file is selected through git.
+func (g *GitFiles) Empty() bool {
+	return g.ChangedSince == "" && !g.Staged && len(g.Refs) == 0 && !g.TrackedOnly
+}
+
+// LoadGitFiles Adds the files selected through git to the filemap, once its files and
+// filesets were loaded from the working tree, which are first restricted to the tracked
+// files if requested. Changed and staged files are read from the working tree, while
+// the files of a ref hold their content at that revision, and are tagged as REV:PATH.
+// A file of a ref cannot be loaded when its path is already loaded from the working tree.
+func LoadGitFiles(fm *filemap.Filemap, g GitFiles) error {
+	if g.Empty() {
+		return nil
+	}
+	repo, err := vcs.Open(".")
+	if err != nil {
+		return err
+	}
+	if g.TrackedOnly {
+		tracked, trackedErr := repo.Tracked()
+		if trackedErr != nil {
+			return trackedErr
+		}
+		for tag, file := range fm.Files {
+			// the files of globs and filesets may be spelled in any way, e.g. absolute
+			rel, relErr := repo.Rel(file.Path)
+			if relErr != nil || !tracked[rel] {
+				log.Printf("leaving out %q, which git does not track\n", file.Path)
+				delete(fm.Files, tag)
+			}
+		}
+	}
+
+	var paths []string
+	if g.ChangedSince != "" {
+		changed, changedErr := repo.ChangedSince(g.ChangedSince)
+		if changedErr != nil {
+			return changedErr
+		}
+		log.Printf("files changed since %s: %v\n", g.ChangedSince, changed)
+		paths = append(paths, changed...)
+	}
+	if g.Staged {
+		staged, stagedErr := repo.Staged()
+		if stagedErr != nil {
+			return stagedErr
+		}
+		log.Printf("staged files: %v\n", staged)
+		paths = append(paths, staged...)
+	}
+	for _, path := range paths {
+		if err = fm.LoadFile(path); err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range g.Refs {
+		contents, refErr := repo.ReadRef(ref)
+		if refErr != nil {
+			return refErr
+		}
+		refPaths := make([]string, 0, len(contents))
+		for path := range contents {
+			refPaths = append(refPaths, path)
+		}
+		sort.Strings(refPaths)
+		log.Printf("files of %s: %v\n", ref, refPaths)
+		rev, _, _ := strings.Cut(ref, ":")
+		for _, path := range refPaths {
+			// the tag tells the model which revision the content comes from
+			tag := rev + ":" + filepath.ToSlash(path)
+			if _, ok := fm.Files[tag]; ok {
+				continue
+			}
+			if fm.HasPath(path) {
+				return fmt.Errorf("%s is already loaded from the working tree, so it cannot be loaded at %s too", path, rev)
+			}
+			fm.Files[tag] = filemap.File{Name: tag, Path: path, Content: contents[path], Rev: rev}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/candidates"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/lsp"
+	"github.com/redhat-et/copilot-ops/pkg/session"
+)
+
+var _ = Describe("Git integration", func() {
+	var (
+		cwd  string
+		repo *git.Repository
+	)
+
+	BeforeEach(func() {
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+
+		repo, err = git.PlainInit(".", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile("pvc.yaml", []byte("storage: 10Gi\n"), 0o600)).To(Succeed())
+		wt, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = wt.Add("pvc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = wt.Commit("Add the PVC", &git.CommitOptions{
+			Author: &object.Signature{Name: "dev", Email: "dev@example.com", When: time.Now()},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+	})
+
+	It("needs the files to be written", func() {
+		c := cmd.NewEditCmd()
+		Expect(c.Flags().Set(cmd.FlagGitBranchFull, "pvc")).To(Succeed())
+		_, err := cmd.PrepareRequest(c)
+		Expect(err).To(MatchError(ContainSubstring("need --" + cmd.FlagWriteFull)))
+	})
+
+	It("derives the commit message from the request and the picked edit", func() {
+		fm := filemap.NewFilemap()
+		r := &cmd.Request{
+			Command:     cmd.CommandEdit,
+			Backend:     ai.GPT3,
+			Model:       "gpt-4",
+			UserRequest: "Increase the size of the PVC to 100Gi\n\nThe database is running out of space.",
+			Filemap:     fm,
+			Candidates:  []candidates.Candidate{{Filemap: fm, Added: 1, Removed: 1}, {Filemap: filemap.NewFilemap()}},
+			Run:         "20230101-000000.000000",
+		}
+		Expect(cmd.CommitMessage(r, []string{"pvc.yaml"})).To(Equal(`Increase the size of the PVC to 100Gi
+
+The database is running out of space.
+
+Proposed by gpt-3 (gpt-4) through ` + "`copilot-ops edit`" +
+			`, which picked edit 1 of 2 as the best ranked: +1 -1 lines, 0/0 changed files valid.
+
+Changed files:
+- pvc.yaml
+
+Run: 20230101-000000.000000
+`))
+
+		r = &cmd.Request{Command: cmd.CommandGenerate, Backend: ai.GPT3}
+		Expect(cmd.CommitMessage(r, []string{"a.yaml", "b.yaml"})).To(HavePrefix("copilot-ops: generate a.yaml b.yaml\n\n"))
+	})
+
+	It("commits the changed files on a new branch after writing them", func() {
+		fm := filemap.NewFilemap()
+		fm.Files["pvc"] = filemap.File{Path: "pvc.yaml", Content: "storage: 100Gi\n"}
+		fm.Files["pod"] = filemap.File{Path: "app/pod.yaml", Content: "kind: Pod\n"}
+		r := &cmd.Request{
+			Command:     cmd.CommandGenerate,
+			Backend:     ai.GPT3,
+			UserRequest: "Increase the size to 100Gi",
+			GitCommit:   true,
+			GitBranch:   "copilot-ops/pvc",
+		}
+		Expect(cmd.WriteFiles(r, fm)).To(Succeed())
+
+		head, err := repo.Head()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(head.Name().Short()).To(Equal("copilot-ops/pvc"))
+		commit, err := repo.CommitObject(head.Hash())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commit.Message).To(HavePrefix("Increase the size to 100Gi\n"))
+		stats, err := commit.Stats()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(HaveLen(2))
+
+		// the journal is not committed along with the files
+		wt, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		status, err := wt.Status()
+		Expect(err).NotTo(HaveOccurred())
+		for path, file := range status {
+			Expect(file.Staging).To(Equal(git.Untracked), path)
+		}
+	})
+
+	It("selects files through git", func() {
+		Expect(os.WriteFile("pvc.yaml", []byte("storage: 100Gi\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile("notes.yaml", []byte("todo: []\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile("svc.yaml", []byte("kind: Service\n"), 0o600)).To(Succeed())
+		wt, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = wt.Add("svc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+
+		c := cmd.NewEditCmd()
+		Expect(c.Flags().Set(cmd.FlagFilesFull, "notes.yaml")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagTrackedOnlyFull, "true")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagChangedSinceFull, "master")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagStagedFull, "true")).To(Succeed())
+		r, err := cmd.PrepareRequest(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Filemap.Files).To(HaveLen(2))
+		Expect(r.Filemap.Files).To(HaveKeyWithValue("pvc.yaml", filemap.File{Path: "pvc.yaml", Content: "storage: 100Gi\n"}))
+		Expect(r.Filemap.Files).To(HaveKey("svc.yaml"))
+		Expect(r.Original.Files).To(HaveLen(2))
+
+		c = cmd.NewEditCmd()
+		Expect(c.Flags().Set(cmd.FlagRefFull, "HEAD:pvc.yaml")).To(Succeed())
+		r, err = cmd.PrepareRequest(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Filemap.Files).To(HaveKeyWithValue("HEAD:pvc.yaml", filemap.File{
+			Name: "HEAD:pvc.yaml", Path: "pvc.yaml", Content: "storage: 10Gi\n", Rev: "HEAD",
+		}))
+		Expect(r.FilemapText).To(ContainSubstring("# @HEAD:pvc.yaml\n"))
+
+		c = cmd.NewEditCmd()
+		Expect(c.Flags().Set(cmd.FlagRefFull, "v9:pvc.yaml")).To(Succeed())
+		_, err = cmd.PrepareRequest(c)
+		Expect(err).To(MatchError(ContainSubstring("error loading files from git")))
+	})
+
+	It("keeps the tracked files however their paths are spelled", func() {
+		abs, err := filepath.Abs("pvc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile("notes.yaml", []byte("todo: []\n"), 0o600)).To(Succeed())
+
+		c := cmd.NewEditCmd()
+		Expect(c.Flags().Set(cmd.FlagFilesFull, abs)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagFilesFull, "./notes.yaml")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagTrackedOnlyFull, "true")).To(Succeed())
+		r, err := cmd.PrepareRequest(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Filemap.Files).To(HaveLen(1))
+		Expect(r.Filemap.Files).To(HaveKeyWithValue("pvc.yaml", HaveField("Path", abs)))
+	})
+
+	It("refuses to load a file at a ref when it is loaded from the working tree", func() {
+		c := cmd.NewEditCmd()
+		Expect(c.Flags().Set(cmd.FlagFilesFull, "pvc.yaml")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagRefFull, "HEAD:*.yaml")).To(Succeed())
+		_, err := cmd.PrepareRequest(c)
+		Expect(err).To(MatchError(ContainSubstring("pvc.yaml is already loaded from the working tree")))
+	})
+
+	It("leaves the files of a ref out of the edits of the LSP", func() {
+		ts := OpenAITestServer()
+		ts.Start()
+		defer ts.Close()
+		Expect(os.WriteFile("pvc.yaml", []byte("storage: 100Gi\n"), 0o600)).To(Succeed())
+		base := &cmd.Request{
+			Backend:      ai.GPT3,
+			NTokens:      cmd.DefaultTokens,
+			NCompletions: cmd.DefaultCompletions,
+			Config:       config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+			GitFiles:     cmd.GitFiles{Refs: []string{"HEAD:pvc.yaml"}},
+		}
+		result, err := cmd.LSPRunner(base, nil, nil)(context.Background(), &lsp.Action{
+			Command: lsp.CommandEdit,
+			Request: "port the pod to the old PVC",
+			File:    filemap.File{Name: "pod.yaml", Path: "pod.yaml", Content: "kind: Pod\n"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Files).To(HaveKey("pod.yaml"))
+		Expect(result.Files).NotTo(HaveKey("HEAD:pvc.yaml"))
+	})
+
+	It("refuses to apply the chat's changes to the files of a ref", func() {
+		Expect(os.WriteFile("pvc.yaml", []byte("storage: 100Gi\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile("pod.yaml", []byte("kind: Pod\n"), 0o600)).To(Succeed())
+		s := session.NewSession("refs")
+		s.Files.Files["pod"] = filemap.File{Path: "pod.yaml", Content: "kind: Pod\n"}
+		s.Files.Files["HEAD:pvc.yaml"] = filemap.File{
+			Name: "HEAD:pvc.yaml", Path: "pvc.yaml", Content: "storage: 10Gi\n", Rev: "HEAD",
+		}
+		s.AddMessage(ai.RoleUser, "shrink the PVC")
+		s.AddMessage(ai.RoleAssistant, "# @HEAD:pvc.yaml\nstorage: 1Gi\n")
+		out := &bytes.Buffer{}
+		Expect(cmd.RunChatLoop(strings.NewReader("/apply\n"), out, &cmd.Request{}, s, GinkgoT().TempDir())).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("refusing to write pvc.yaml, which was read at HEAD"))
+		content, err := os.ReadFile("pvc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("storage: 100Gi\n"))
+
+		// the unchanged files of a ref do not keep the other files from being written
+		s.AddMessage(ai.RoleAssistant, "# @pod\nkind: Pod\nmetadata: {}\n# @HEAD:pvc.yaml\nstorage: 10Gi\n")
+		out.Reset()
+		Expect(cmd.RunChatLoop(strings.NewReader("/apply\n"), out, &cmd.Request{}, s, GinkgoT().TempDir())).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("wrote pod.yaml"))
+		Expect(out.String()).NotTo(ContainSubstring("wrote pvc.yaml"))
+		content, err = os.ReadFile("pvc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("storage: 100Gi\n"))
+	})
+
+	It("never writes the files of a ref", func() {
+		Expect(os.WriteFile("pvc.yaml", []byte("storage: 100Gi\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile("pod.yaml", []byte("kind: Pod\n"), 0o600)).To(Succeed())
+		c := cmd.NewEditCmd()
+		Expect(c.Flags().Set(cmd.FlagFilesFull, "pod.yaml")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagRefFull, "HEAD:pvc.yaml")).To(Succeed())
+		r, err := cmd.PrepareRequest(c)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the unchanged file of the ref is left out, rather than reverting the working tree
+		r.Filemap.Files["pod.yaml"] = filemap.File{Path: "pod.yaml", Content: "kind: Pod\nmetadata: {}\n"}
+		Expect(cmd.WriteFiles(r, r.Filemap)).To(Succeed())
+		content, err := os.ReadFile("pvc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("storage: 100Gi\n"))
+
+		file := r.Filemap.Files["HEAD:pvc.yaml"]
+		file.Content = "storage: 1Gi\n"
+		r.Filemap.Files["HEAD:pvc.yaml"] = file
+		Expect(cmd.WriteFiles(r, r.Filemap)).To(MatchError(ContainSubstring("refusing to write pvc.yaml, which was read at HEAD")))
+		content, err = os.ReadFile("pvc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("storage: 100Gi\n"))
+	})
+})
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/api"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// RPCServer Implements the gRPC service on top of the API's server, so that both
+// build their requests the same way and share its limits.
+type RPCServer struct {
+	rpc.UnimplementedCopilotOpsServer
+	server *Server
+}
+
+// NewGRPCServer Returns a gRPC server offering the service, which accepts messages
+// up to the server's MaxBodySize.
+func NewGRPCServer(server *Server) *grpc.Server {
+	g := grpc.NewServer(grpc.MaxRecvMsgSize(int(server.MaxBodySize)))
+	rpc.RegisterCopilotOpsServer(g, &RPCServer{server: server})
+	// lets clients such as grpcurl list the service
+	reflection.Register(g)
+	return g
+}
+
+// Generate Generates the files described by the request.
+func (s *RPCServer) Generate(ctx context.Context, req *rpc.Request) (*rpc.Result, error) {
+	return s.propose(ctx, CommandGenerate, req)
+}
+
+// Edit Edits the files sent along with the request.
+func (s *RPCServer) Edit(ctx context.Context, req *rpc.Request) (*rpc.Result, error) {
+	return s.propose(ctx, CommandEdit, req)
+}
+
+// Ask Answers the question, streaming the answer as the backend generates it.
+func (s *RPCServer) Ask(req *rpc.Request, stream rpc.CopilotOps_AskServer) error {
+	if !s.server.acquire() {
+		return status.Error(codes.ResourceExhausted, s.server.busyMessage())
+	}
+	defer s.server.release()
+	r, err := s.server.request(stream.Context(), CommandAsk, apiRequest(req))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	log.Printf("gRPC %s: %q\n", CommandAsk, r.UserRequest)
+	_, err = StreamAnswer(r, func(text string) error {
+		return stream.Send(&rpc.AskResponse{Text: text})
+	})
+	if err != nil {
+		return rpcError(stream.Context(), err)
+	}
+	return nil
+}
+
+// propose Runs the command for the request, as the API's handlers do. The backend's
+// requests are canceled with the call.
+func (s *RPCServer) propose(ctx context.Context, command string, req *rpc.Request) (*rpc.Result, error) {
+	if !s.server.acquire() {
+		return nil, status.Error(codes.ResourceExhausted, s.server.busyMessage())
+	}
+	defer s.server.release()
+	r, err := s.server.request(ctx, command, apiRequest(req))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	log.Printf("gRPC %s: %q\n", command, r.UserRequest)
+	answer, err := ProposeCommand(r)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return rpcResult(APIResult(r, answer)), nil
+}
+
+// rpcError Returns the status of a call whose backend failed: the call's own status
+// when it was canceled or ran out of time, and Unavailable otherwise.
+func rpcError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+// apiRequest Converts the message into the body of an API request.
+func apiRequest(req *rpc.Request) *api.Request {
+	files := make([]filemap.File, 0, len(req.GetFiles()))
+	for _, file := range req.GetFiles() {
+		files = append(files, filemap.File{Name: file.GetName(), Path: file.GetPath(), Content: file.GetContent()})
+	}
+	return &api.Request{
+		Request:      req.GetRequest(),
+		Files:        files,
+		NCompletions: req.GetNcompletions(),
+		NTokens:      req.GetNtokens(),
+		Backend:      req.GetBackend(),
+		Model:        req.GetModel(),
+	}
+}
+
+// rpcResult Converts the result of an API request into its message.
+func rpcResult(result *Result) *rpc.Result {
+	message := &rpc.Result{}
+	if result.Warnings != nil {
+		for _, warning := range *result.Warnings {
+			message.Warnings = append(message.Warnings, &rpc.Error{Code: warning.Code, Message: warning.Message})
+		}
+	}
+	if result.Result != nil {
+		for _, fm := range *result.Result {
+			files := make(map[string]*rpc.File, len(fm.Files))
+			for tag, file := range fm.Files {
+				files[tag] = &rpc.File{Name: tag, Path: file.Path, Content: file.Content}
+			}
+			message.Result = append(message.Result, &rpc.Filemap{Files: files})
+		}
+	}
+	return message
+}
+
+// StopGRPC Stops the server once the calls in progress are done, or cancels them
+// after the timeout.
+func StopGRPC(server *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		server.Stop()
+	}
+}
@@ -0,0 +1,199 @@
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/api"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/rpc"
+)
+
+var _ = Describe("gRPC service", func() {
+	var (
+		ts         *httptest.Server
+		server     *cmd.Server
+		grpcServer *grpc.Server
+		conn       *grpc.ClientConn
+		client     rpc.CopilotOpsClient
+	)
+
+	BeforeEach(func() {
+		ts = OpenAITestServer()
+		ts.Start()
+		base := &cmd.Request{
+			Backend:      ai.GPT3,
+			NTokens:      cmd.DefaultTokens,
+			NCompletions: cmd.DefaultCompletions,
+			Config:       config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+		}
+		server = cmd.NewServer(base, cmd.DefaultMaxBodySize, 1)
+
+		listener := bufconn.Listen(1 << 20)
+		grpcServer = cmd.NewGRPCServer(server)
+		go func() {
+			_ = grpcServer.Serve(listener)
+		}()
+		var err error
+		conn, err = grpc.Dial("bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		client = rpc.NewCopilotOpsClient(conn)
+	})
+
+	AfterEach(func() {
+		Expect(conn.Close()).To(Succeed())
+		grpcServer.Stop()
+		ts.Close()
+	})
+
+	// ask Calls Ask, and returns the parts of the answer it streams.
+	ask := func(req *rpc.Request) ([]string, error) {
+		stream, err := client.Ask(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		var parts []string
+		for {
+			res, recvErr := stream.Recv()
+			if errors.Is(recvErr, io.EOF) {
+				return parts, nil
+			}
+			if recvErr != nil {
+				return parts, recvErr
+			}
+			parts = append(parts, res.GetText())
+		}
+	}
+
+	It("edits the files of the request", func() {
+		result, err := client.Edit(context.Background(), &rpc.Request{
+			Request: "Rename the pod",
+			Files:   []*rpc.File{{Path: "app/pod.yaml", Content: "kind: Pod\n"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.GetResult()).To(HaveLen(1))
+		Expect(result.GetResult()[0].GetFiles()).NotTo(BeEmpty())
+	})
+
+	It("generates files", func() {
+		result, err := client.Generate(context.Background(), &rpc.Request{Request: "Add a pod running nginx"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.GetResult()).To(HaveLen(1))
+	})
+
+	It("streams the answer to a question", func() {
+		parts, err := ask(&rpc.Request{
+			Request: "What is @pod?",
+			Files:   []*rpc.File{{Name: "pod", Content: "kind: Pod\n"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(parts)).To(BeNumerically(">", 1))
+		Expect(strings.Join(parts, "")).To(HavePrefix("answer to: "))
+		Expect(strings.Join(parts, "")).To(ContainSubstring("What is @pod?"))
+	})
+
+	It("rejects invalid requests", func() {
+		_, err := client.Edit(context.Background(), &rpc.Request{Request: "Rename the pod"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		_, err = ask(&rpc.Request{})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+		_, err = client.Generate(context.Background(), &rpc.Request{Request: "Add a pod", Ncompletions: 1000000})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("shares the concurrency limit of the API", func() {
+		// hold the server's only slot with an API request whose body is still being written
+		body, write := io.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, api.PathAsk, body))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		}()
+		_, err := io.WriteString(write, `{"request": "what `)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = client.Generate(context.Background(), &rpc.Request{Request: "Add a pod running nginx"})
+		Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+
+		_, err = io.WriteString(write, `is a pod?"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(write.Close()).To(Succeed())
+		<-done
+	})
+
+	Context("when the backend stalls", func() {
+		var canceled chan struct{}
+
+		BeforeEach(func() {
+			canceled = make(chan struct{}, 1)
+			// no request has been sent yet, so the handler can be swapped
+			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the server only notices the client went away once the body is read
+				_, _ = io.Copy(io.Discard, r.Body)
+				select {
+				case <-r.Context().Done():
+					canceled <- struct{}{}
+				case <-time.After(5 * time.Second):
+				}
+			})
+		})
+
+		It("cancels the backend's request along with the call", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err := client.Generate(ctx, &rpc.Request{Request: "Add a pod running nginx"})
+			Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+			Eventually(canceled).Should(Receive())
+
+			// the call gave its slot back
+			ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err = client.Edit(ctx, &rpc.Request{
+				Request: "Rename the pod",
+				Files:   []*rpc.File{{Path: "app/pod.yaml", Content: "kind: Pod\n"}},
+			})
+			Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+			Eventually(canceled).Should(Receive())
+		})
+
+		It("cancels the backend's stream when the client stops asking", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			stream, err := client.Ask(ctx, &rpc.Request{Request: "What is a pod?"})
+			Expect(err).NotTo(HaveOccurred())
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+			}()
+			_, err = stream.Recv()
+			Expect(status.Code(err)).To(Equal(codes.Canceled))
+			Eventually(canceled).Should(Receive())
+		})
+	})
+})
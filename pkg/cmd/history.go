@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/journal"
+	"github.com/spf13/cobra"
+)
+
+// HistoryRequestWidth Is the number of characters of a request shown in the history.
+const HistoryRequestWidth = 60
+
+// NewHistoryCmd Creates the `copilot-ops history` CLI command.
+func NewHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandHistory,
+
+		Short: "Lists the writes made to the repo files",
+
+		Long: "Every time files are written, the contents they replace are journaled under " +
+			journal.HistoryDir + " along with the request, so that the write can be undone with `copilot-ops " +
+			CommandUndo + "`. Writes are identified by the ID of their run.",
+
+		Example: `  copilot-ops history
+  copilot-ops history --output json`,
+
+		RunE: RunHistory,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, OutputText,
+		"How to format output: text or json",
+	)
+
+	return cmd
+}
+
+// NewUndoCmd Creates the `copilot-ops undo` CLI command.
+func NewUndoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandUndo + " [ID]",
+
+		Short: "Restores the files as they were before a write",
+
+		Long: "Undo restores the contents the write replaced, removing the files it created. It refuses " +
+			"to restore anything when a file was modified since it was written. The ID defaults to the " +
+			"latest write which was not undone, and the undo itself is journaled as well.",
+
+		Example: `  copilot-ops edit --file app/pvc.yaml --request 'Increase the size to 100Gi' --write
+  copilot-ops undo
+  copilot-ops undo 20230102-150405.000000`,
+
+		RunE: RunUndo,
+		Args: cobra.MaximumNArgs(1),
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	return cmd
+}
+
+// RunHistory Runs when the `history` command is invoked.
+func RunHistory(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString(FlagPathFull)
+	outputType, _ := cmd.Flags().GetString(FlagOutputTypeFull)
+	if err := os.Chdir(path); err != nil {
+		return err
+	}
+	entries, err := journal.List(journal.HistoryDir)
+	if err != nil {
+		return err
+	}
+	return PrintHistory(cmd.OutOrStdout(), outputType, entries)
+}
+
+// RunUndo Runs when the `undo` command is invoked.
+func RunUndo(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString(FlagPathFull)
+	if err := os.Chdir(path); err != nil {
+		return err
+	}
+	id := ""
+	if len(args) > 0 {
+		id = args[0]
+	}
+	undo, err := journal.Undo(journal.HistoryDir, id)
+	if err != nil {
+		return err
+	}
+	if len(undo.Files) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "the files of %s already hold their previous contents\n", undo.Undoes)
+		return nil
+	}
+	for _, change := range undo.Files {
+		if change.After == "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "removed %s\n", change.Path)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "restored %s\n", change.Path)
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "undid %s, recorded as %s\n", undo.Undoes, undo.ID)
+	return nil
+}
+
+// PrintHistory Writes the journal's entries in the requested output format, newest first.
+func PrintHistory(w io.Writer, outputType string, entries []*journal.Entry) error {
+	switch outputType {
+	case OutputText:
+		if len(entries) == 0 {
+			_, err := fmt.Fprintln(w, "no writes were recorded")
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tCOMMAND\tFILES\tSTATUS\tREQUEST")
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			status := ""
+			switch {
+			case e.UndoneBy != "":
+				status = "undone by " + e.UndoneBy
+			case e.Undoes != "":
+				status = "undid " + e.Undoes
+			}
+			paths := make([]string, 0, len(e.Files))
+			for _, change := range e.Files {
+				paths = append(paths, change.Path)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.Command, strings.Join(paths, " "), status,
+				summarizeRequest(e.Request))
+		}
+		return tw.Flush()
+	case filemap.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(entries)
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
+
+// summarizeRequest Returns the first line of the request, shortened to fit in the history.
+func summarizeRequest(request string) string {
+	request = strings.TrimSpace(request)
+	if i := strings.IndexByte(request, '\n'); i >= 0 {
+		request = request[:i] + "..."
+	}
+	if runes := []rune(request); len(runes) > HistoryRequestWidth {
+		request = string(runes[:HistoryRequestWidth-3]) + "..."
+	}
+	return request
+}
@@ -0,0 +1,84 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+var _ = Describe("History and undo commands", func() {
+	var (
+		cwd    string
+		stdout *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+		Expect(os.WriteFile("pvc.yaml", []byte("storage: 10Gi\n"), 0o600)).To(Succeed())
+		stdout = &bytes.Buffer{}
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+	})
+
+	It("are registered on the root command", func() {
+		for _, name := range []string{cmd.CommandHistory, cmd.CommandUndo} {
+			found, _, err := cmd.NewRootCmd().Find([]string{name})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Name()).To(Equal(name))
+		}
+	})
+
+	It("lists the writes under the ID of their run, and undoes them", func() {
+		fm := filemap.NewFilemap()
+		fm.Files["pvc"] = filemap.File{Path: "pvc.yaml", Content: "storage: 100Gi\n"}
+		r := &cmd.Request{
+			Command:     cmd.CommandEdit,
+			Backend:     ai.GPT3,
+			UserRequest: "Increase the size to 100Gi",
+			Run:         "20230101-000000.000000",
+		}
+		Expect(cmd.WriteFiles(r, fm)).To(Succeed())
+
+		history := cmd.NewHistoryCmd()
+		history.SetOut(stdout)
+		Expect(cmd.RunHistory(history, nil)).To(Succeed())
+		Expect(stdout.String()).To(MatchRegexp(`20230101-000000.000000\s+edit\s+pvc.yaml\s+Increase the size`))
+
+		stdout.Reset()
+		undo := cmd.NewUndoCmd()
+		undo.SetOut(stdout)
+		Expect(cmd.RunUndo(undo, []string{r.Run})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("restored pvc.yaml"))
+		content, err := os.ReadFile("pvc.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("storage: 10Gi\n"))
+
+		stdout.Reset()
+		Expect(cmd.RunHistory(history, nil)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("undone by"))
+		Expect(stdout.String()).To(ContainSubstring("undid " + r.Run))
+		Expect(cmd.RunUndo(undo, nil)).To(MatchError(ContainSubstring("no writes which can be undone")))
+	})
+
+	It("refuses to undo a write when the files were modified since", func() {
+		fm := filemap.NewFilemap()
+		fm.Files["pvc"] = filemap.File{Path: "pvc.yaml", Content: "storage: 100Gi\n"}
+		Expect(cmd.WriteFiles(&cmd.Request{Command: cmd.CommandGenerate}, fm)).To(Succeed())
+		Expect(os.WriteFile("pvc.yaml", []byte("storage: 1Ti\n"), 0o600)).To(Succeed())
+
+		undo := cmd.NewUndoCmd()
+		undo.SetOut(stdout)
+		Expect(cmd.RunUndo(undo, nil)).To(MatchError(ContainSubstring("modified since")))
+	})
+})
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/scaffold"
+	"github.com/spf13/cobra"
+)
+
+// NewInitCmd Creates the `copilot-ops init` CLI command.
+func NewInitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandInit,
+
+		Short: "Scaffolds " + config.ConfigFile + " from the repo layout",
+
+		Long: "Init scans the repo for kustomizations, Helm charts, Terraform modules and folders of " +
+			"Kubernetes manifests, and writes a commented " + config.ConfigFile + " with a fileset for each of them, " +
+			"using the backend detected from the environment. Secrets go in " + scaffold.LocalConfigFile +
+			", which is created alongside it and added to " + scaffold.GitignoreFile + ".",
+
+		Example: `  copilot-ops init
+  copilot-ops init --path ../my-gitops-repo --dry-run`,
+
+		RunE: RunInit,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().Bool(
+		FlagForceFull, false,
+		"Overwrite an existing "+config.ConfigFile,
+	)
+
+	cmd.Flags().Bool(
+		FlagDryRunFull, false,
+		"Print the generated config instead of writing any files",
+	)
+
+	return cmd
+}
+
+// RunInit Runs when the `init` command is invoked.
+func RunInit(cmd *cobra.Command, args []string) error {
+	root, _ := cmd.Flags().GetString(FlagPathFull)
+	force, _ := cmd.Flags().GetBool(FlagForceFull)
+	dryRun, _ := cmd.Flags().GetBool(FlagDryRunFull)
+	return Init(cmd.OutOrStdout(), root, os.Getenv, force, dryRun)
+}
+
+// Init Scans the repo at root and writes its config files, reporting what was done to out.
+func Init(out io.Writer, root string, getenv func(string) string, force, dryRun bool) error {
+	detections, err := scaffold.Scan(root)
+	if err != nil {
+		return fmt.Errorf("could not scan %s: %w", root, err)
+	}
+	backend := scaffold.DetectBackend(getenv)
+	content := scaffold.Render(detections, backend)
+	if dryRun {
+		_, err = io.WriteString(out, content)
+		return err
+	}
+
+	configPath := filepath.Join(root, config.ConfigFile)
+	if _, err = os.Stat(configPath); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --%s to overwrite it", configPath, FlagForceFull)
+	}
+	if err = os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "wrote %s with %d fileset(s) using the %s backend\n", configPath, len(detections), backend.Backend)
+	for _, d := range detections {
+		fmt.Fprintf(out, " - %-20s %-10s %s\n", d.Name, d.Kind, d.Dir)
+	}
+
+	// the local file may already hold secrets, so it is never overwritten
+	localPath := filepath.Join(root, scaffold.LocalConfigFile)
+	if _, err = os.Stat(localPath); os.IsNotExist(err) {
+		if err = os.WriteFile(localPath, []byte(scaffold.RenderLocal(backend)), 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "wrote %s for secrets\n", localPath)
+	}
+	added, err := scaffold.EnsureGitignore(root, scaffold.LocalConfigFile)
+	if err != nil {
+		return err
+	}
+	if added {
+		fmt.Fprintf(out, "added %s to %s\n", scaffold.LocalConfigFile, scaffold.GitignoreFile)
+	}
+	return nil
+}
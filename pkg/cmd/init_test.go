@@ -0,0 +1,59 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/scaffold"
+)
+
+var _ = Describe("Init command", func() {
+	var (
+		root string
+		out  *bytes.Buffer
+	)
+	getenv := func(string) string { return "" }
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		out = &bytes.Buffer{}
+		Expect(os.MkdirAll(filepath.Join(root, "app"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "app", "pod.yaml"), []byte("apiVersion: v1\nkind: Pod\n"), 0644)).To(Succeed())
+	})
+
+	It("writes the config files and ignores the local one", func() {
+		Expect(cmd.Init(out, root, getenv, false, false)).To(Succeed())
+		content, err := os.ReadFile(filepath.Join(root, config.ConfigFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("- name: app\n"))
+		Expect(filepath.Join(root, scaffold.LocalConfigFile)).To(BeAnExistingFile())
+		gitignore, err := os.ReadFile(filepath.Join(root, scaffold.GitignoreFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(gitignore)).To(ContainSubstring(scaffold.LocalConfigFile))
+		Expect(out.String()).To(ContainSubstring("with 1 fileset(s)"))
+	})
+
+	It("only overwrites an existing config when forced, keeping the local config", func() {
+		Expect(cmd.Init(out, root, getenv, false, false)).To(Succeed())
+		local := filepath.Join(root, scaffold.LocalConfigFile)
+		Expect(os.WriteFile(local, []byte("openAI:\n  apiKey: sk-kept\n"), 0600)).To(Succeed())
+
+		Expect(cmd.Init(out, root, getenv, false, false)).To(MatchError(ContainSubstring("already exists")))
+		Expect(cmd.Init(out, root, getenv, true, false)).To(Succeed())
+		content, err := os.ReadFile(local)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("sk-kept"))
+	})
+
+	It("prints the config without writing it on dry runs", func() {
+		Expect(cmd.Init(out, root, getenv, false, true)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("filesets:\n"))
+		Expect(filepath.Join(root, config.ConfigFile)).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+	"github.com/spf13/cobra"
+)
+
+// NewIssueCmd Creates the `copilot-ops issue` CLI command.
+func NewIssueCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandIssue,
+
+		Short: "Proposes the edits requested by an issue",
+
+		Long: "Issue reads an issue which references the files it is about as `" + filemap.FileTagPrefix +
+			"name:path`, loads those files tagged with their names, and uses the issue text as the request " +
+			"to edit them. The issue can mention the files by their tags, e.g. 'increase the CPU in " +
+			filemap.FileTagPrefix + "quota'.",
+
+		Example: `  copilot-ops issue --body-file issue.md
+  gh issue view 42 --json body --jq .body | copilot-ops issue --body-file - --write`,
+
+		RunE: RunIssue,
+		Args: cobra.NoArgs,
+	}
+
+	AddRequestFlags(cmd)
+	AddInteractiveFlag(cmd)
+	AddFileFlags(cmd)
+
+	cmd.Flags().String(
+		FlagBodyFileFull, "",
+		"File containing the body of the issue, or - to read it from STDIN",
+	)
+
+	cmd.Flags().String(
+		FlagTitleFull, "",
+		"Title of the issue, which is sent along with its body",
+	)
+
+	_ = cmd.MarkFlagRequired(FlagBodyFileFull)
+
+	return cmd
+}
+
+// RunIssue Runs when the `issue` command is invoked.
+func RunIssue(cmd *cobra.Command, args []string) error {
+	// the body file is relative to where the command was run, so it is read before changing to --path
+	bodyFile, _ := cmd.Flags().GetString(FlagBodyFileFull)
+	title, _ := cmd.Flags().GetString(FlagTitleFull)
+	body, err := readBody(cmd.InOrStdin(), bodyFile)
+	if err != nil {
+		return err
+	}
+	iss, err := issue.Parse(title, body)
+	if err != nil {
+		return err
+	}
+
+	r, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	if err = PrepareIssueRequest(r, iss); err != nil {
+		return err
+	}
+
+	if err = ProposeEdit(r); err != nil {
+		return err
+	}
+	SaveRun(r)
+
+	return PrintOrWriteOut(r)
+}
+
+// PrepareIssueRequest Loads the files referenced by the issue into the request, alongside
+// any files given with the request, and uses the issue text as the user request.
+// Text given with --request is added as further instructions.
+func PrepareIssueRequest(r *Request, iss *issue.Issue) error {
+	if err := AddIssue(r, iss); err != nil {
+		return err
+	}
+	if len(r.Filemap.Files) == 0 {
+		return fmt.Errorf("the issue references no files, reference them as `%sname:path` or use --%s or --%s",
+			filemap.FileTagPrefix, FlagFilesFull, FlagFilesetsFull)
+	}
+	return nil
+}
+
+// AddIssue Loads the files referenced by the issue into the request, and puts the
+// issue text before the user request. Unlike PrepareIssueRequest, the issue may reference no files.
+// The references of untrusted requests must stay within the working directory.
+func AddIssue(r *Request, iss *issue.Issue) error {
+	load := iss.LoadFiles
+	if r.Untrusted {
+		load = func(fm *filemap.Filemap) error { return iss.LoadFilesWithin(fm, ".") }
+	}
+	if err := load(r.Filemap); err != nil {
+		return err
+	}
+	r.Original = r.Filemap.Clone()
+	r.FilemapText = r.Filemap.EncodeToInputText()
+
+	request := iss.Request()
+	if r.UserRequest != "" {
+		request = fmt.Sprintf("%s\n\n%s", request, r.UserRequest)
+	}
+	if request == "" {
+		return fmt.Errorf("the issue has no text to use as the request")
+	}
+	r.UserRequest = request
+	return nil
+}
+
+// readBody Reads the body of an issue or comment from the file, or from stdin when the file is StdinArg.
+func readBody(stdin io.Reader, file string) (string, error) {
+	var data []byte
+	var err error
+	if file == StdinArg {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read the body: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,37 @@
+package cmd_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+var _ = Describe("Issue command", func() {
+	It("is registered on the root command", func() {
+		c, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandIssue})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name()).To(Equal(cmd.CommandIssue))
+	})
+
+	It("uses the issue as the request, followed by extra instructions", func() {
+		r := &cmd.Request{Filemap: filemap.NewFilemap(), UserRequest: "keep the labels"}
+		r.Filemap.Files["pvc.yaml"] = filemap.File{Path: "pvc.yaml", Content: "kind: PersistentVolumeClaim\n"}
+		iss, err := issue.Parse("Resize", "@pvc.yaml needs 100Gi")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cmd.PrepareIssueRequest(r, iss)).To(Succeed())
+		Expect(r.UserRequest).To(Equal("Resize\n\n@pvc.yaml needs 100Gi\n\nkeep the labels"))
+		Expect(r.FilemapText).To(ContainSubstring("# @pvc.yaml\n"))
+		Expect(r.Original.Files).To(HaveKey("pvc.yaml"))
+	})
+
+	It("requires files to edit", func() {
+		iss, err := issue.Parse("", "The cluster needs NFS drivers.")
+		Expect(err).NotTo(HaveOccurred())
+		err = cmd.PrepareIssueRequest(&cmd.Request{Filemap: filemap.NewFilemap()}, iss)
+		Expect(err).To(MatchError(ContainSubstring("the issue references no files")))
+	})
+})
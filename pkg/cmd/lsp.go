@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/lsp"
+	"github.com/spf13/cobra"
+)
+
+// NewLSPCmd Creates the `copilot-ops lsp` CLI command.
+func NewLSPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandLSP,
+
+		Short: "Serves the Language Server Protocol over stdio for editor integrations",
+
+		Long: "LSP speaks the Language Server Protocol on STDIN and STDOUT, so that editors can offer " +
+			"\"" + lsp.TitleEdit + "\" and \"" + lsp.TitleGenerate + "\" as code actions. The request is the " +
+			"selected text, or the comment on the line of the cursor. Actions run on the open buffer, " +
+			"including its unsaved changes, along with the files and filesets given with --" + FlagFilesFull +
+			" and --" + FlagFilesetsFull + ", and the changes are sent back to the editor as workspace edits " +
+			"for it to apply.\n\n" +
+			"Editors should start the server at the root of the workspace, or point --path to it, since " +
+			"the config and the files are loaded from there. Logs are written to STDERR.",
+
+		Example: `  copilot-ops lsp --path ~/src/infra --fileset base`,
+
+		RunE: RunLSP,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the workspace",
+	)
+
+	AddFileFlags(cmd)
+
+	cmd.Flags().Int32P(
+		FlagNCompletionsFull, FlagNCompletionsShort, DefaultCompletions,
+		"Number of edits to request, the best one is applied",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNTokensFull, FlagNTokensShort, DefaultTokens,
+		"Max number of tokens to generate",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Model of the backend to use",
+	)
+
+	AddBackendFlags(cmd)
+
+	return cmd
+}
+
+// RunLSP Runs when the `lsp` command is invoked.
+func RunLSP(cmd *cobra.Command, args []string) error {
+	files, _ := cmd.Flags().GetStringArray(FlagFilesFull)
+	filesets, _ := cmd.Flags().GetStringArray(FlagFilesetsFull)
+	base, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := lsp.NewServer(cmd.InOrStdin(), cmd.OutOrStdout(), root, LSPRunner(base, files, filesets))
+	return server.Serve(ctx)
+}
+
+// LSPRunner Returns the runner of the code actions, which runs the edit and generate
+// flows on a copy of the base request. The files and filesets are loaded again for every
+// action, so that they are up to date, but the open buffer replaces its file. The files
+// read at a git revision are left out of the result, and are refused when they were changed.
+// The calls to the backend are canceled with ctx, e.g. when the server is stopped.
+func LSPRunner(base *Request, files, filesets []string) lsp.Runner {
+	return func(ctx context.Context, action *lsp.Action) (*filemap.Filemap, error) {
+		r := *base
+		r.SetContext(ctx)
+		switch action.Command {
+		case lsp.CommandEdit:
+			r.Command = CommandEdit
+		case lsp.CommandGenerate:
+			r.Command = CommandGenerate
+		default:
+			return nil, fmt.Errorf("unknown command %q", action.Command)
+		}
+		r.UserRequest = action.Request
+		r.Candidates = nil
+		r.Parent, r.ParentRequest = "", ""
+
+		r.Filemap = filemap.NewFilemap()
+		r.Filemap.Files[action.File.Name] = action.File
+		if err := r.Filemap.LoadFiles(files); err != nil {
+			return nil, fmt.Errorf("error loading files: %w", err)
+		}
+		if err := r.Filemap.LoadFilesets(filesets, r.Config, config.ConfigFile); err != nil {
+			return nil, fmt.Errorf("error loading filesets: %w", err)
+		}
+		if err := LoadGitFiles(r.Filemap, r.GitFiles); err != nil {
+			return nil, fmt.Errorf("error loading files from git: %w", err)
+		}
+		r.Original = r.Filemap.Clone()
+		r.FilemapText = r.Filemap.EncodeToInputText()
+
+		if _, err := ProposeCommand(&r); err != nil {
+			return nil, err
+		}
+		SaveRun(&r)
+		return withoutRefFiles(r.Original, r.Filemap)
+	}
+}
@@ -0,0 +1,96 @@
+package cmd_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/lsp"
+)
+
+var _ = Describe("LSP command", func() {
+	var (
+		ts   *httptest.Server
+		base *cmd.Request
+		cwd  string
+	)
+
+	BeforeEach(func() {
+		ts = OpenAITestServer()
+		ts.Start()
+		base = &cmd.Request{
+			Backend:      ai.GPT3,
+			NTokens:      cmd.DefaultTokens,
+			NCompletions: cmd.DefaultCompletions,
+			Config:       config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+		}
+		// runs are recorded in the working directory
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+		ts.Close()
+	})
+
+	It("is registered on the root command", func() {
+		c, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandLSP})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name()).To(Equal(cmd.CommandLSP))
+	})
+
+	It("generates files next to the open buffer and the loaded files", func() {
+		Expect(os.WriteFile("svc.yaml", []byte("kind: Service\n"), 0o600)).To(Succeed())
+		run := cmd.LSPRunner(base, []string{"*.yaml"}, nil)
+		result, err := run(context.Background(), &lsp.Action{
+			Command: lsp.CommandGenerate,
+			Request: "add a deployment for the service",
+			File:    filemap.File{Name: "pod.yaml", Path: "pod.yaml", Content: "kind: Pod\n"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Files).To(HaveLen(1))
+		for _, file := range result.Files {
+			Expect(file.Content).To(Equal("choice 1"))
+		}
+	})
+
+	It("edits the open buffer instead of its file", func() {
+		Expect(os.WriteFile("pod.yaml", []byte("kind: Pod\nmetadata: {}\n"), 0o600)).To(Succeed())
+		run := cmd.LSPRunner(base, []string{"pod.yaml"}, nil)
+		result, err := run(context.Background(), &lsp.Action{
+			Command: lsp.CommandEdit,
+			Request: "rename the pod",
+			File:    filemap.File{Name: "pod.yaml", Path: "pod.yaml", Content: "kind: Pod\n"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		// the file on disk is not loaded over the buffer
+		Expect(result.Files).To(HaveKeyWithValue("pod.yaml", filemap.File{
+			Name: "pod.yaml", Path: "pod.yaml", Content: "kind: Pod\n",
+		}))
+
+		_, err = run(context.Background(), &lsp.Action{Command: "copilot-ops.unknown", Request: "rename the pod"})
+		Expect(err).To(MatchError(ContainSubstring("unknown command")))
+	})
+
+	It("stops calling the backend once the server is stopped", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := cmd.LSPRunner(base, nil, nil)(ctx, &lsp.Action{
+			Command: lsp.CommandEdit,
+			Request: "rename the pod",
+			File:    filemap.File{Name: "pod.yaml", Path: "pod.yaml", Content: "kind: Pod\n"},
+		})
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
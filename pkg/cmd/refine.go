@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/redhat-et/copilot-ops/pkg/runs"
+	"github.com/spf13/cobra"
+)
+
+// NewRefineCmd Creates the `copilot-ops refine` CLI command.
+func NewRefineCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandRefine + " [REQUEST]",
+
+		Short: "Refines the result of a previous edit or generate run",
+
+		Long: "Refine sends the files resulting from a previous run, recorded under " + runs.RunsDir +
+			", back to the model along with a follow-up request, iteratively improving the same files.",
+
+		Example: `  copilot-ops edit --file examples/app1/mysql-pvc.yaml --request 'Increase the size of the PVC to 100Gi'
+  copilot-ops refine 'also set the storage class to gp2' --write`,
+
+		RunE: RunRefine,
+		Args: cobra.MaximumNArgs(1),
+	}
+
+	AddRequestFlags(cmd)
+	AddInteractiveFlag(cmd)
+
+	cmd.Flags().String(
+		FlagRunFull, "",
+		"ID of the run to refine (defaults to the latest run)",
+	)
+
+	return cmd
+}
+
+// RunRefine Runs when the `refine` command is invoked.
+func RunRefine(cmd *cobra.Command, args []string) error {
+	r, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		r.UserRequest = args[0]
+	}
+	if r.UserRequest == "" {
+		return fmt.Errorf("no request provided")
+	}
+
+	runID, _ := cmd.Flags().GetString(FlagRunFull)
+	if err = ContinueRun(r, runID); err != nil {
+		return err
+	}
+	if err = ProposeEdit(r); err != nil {
+		return err
+	}
+	SaveRun(r)
+
+	return PrintOrWriteOut(r)
+}
@@ -0,0 +1,140 @@
+package cmd_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/runs"
+)
+
+var _ = Describe("Refine command", func() {
+	var (
+		cwd string
+		ts  *httptest.Server
+	)
+
+	// newCmd Returns the command pointed at the test server.
+	newCmd := func(c *cobra.Command) *cobra.Command {
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagAIBackendFull, string(ai.GPT3))).To(Succeed())
+		return c
+	}
+
+	// saveRun Records a run which resulted in the given content of pvc.yaml.
+	saveRun := func(request, content string) *runs.Run {
+		run := runs.NewRun(cmd.CommandEdit)
+		run.Request = request
+		run.After = filemap.NewFilemap()
+		run.After.Files["pvc.yaml"] = filemap.File{Path: "pvc.yaml", Content: content}
+		Expect(run.Save(runs.RunsDir)).To(Succeed())
+		// keeps the IDs of runs saved in a row distinct
+		time.Sleep(time.Millisecond)
+		return run
+	}
+
+	// latestRun Returns the run recorded last.
+	latestRun := func() *runs.Run {
+		run, err := runs.Latest(runs.RunsDir)
+		Expect(err).NotTo(HaveOccurred())
+		return run
+	}
+
+	BeforeEach(func() {
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+		ts = OpenAITestServer()
+		ts.Start()
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		Expect(os.Chdir(cwd)).To(Succeed())
+	})
+
+	It("is registered on the root command", func() {
+		refine, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandRefine})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refine.Name()).To(Equal(cmd.CommandRefine))
+	})
+
+	It("keeps the previous request in the instruction", func() {
+		r := &cmd.Request{UserRequest: "also set replicas to 3", ParentRequest: "bump memory to 1Gi"}
+		instruction := cmd.PrepareEditInstruction(r)
+		Expect(instruction).To(ContainSubstring("also set replicas to 3"))
+		Expect(instruction).To(ContainSubstring(`"bump memory to 1Gi"`))
+
+		r.ParentRequest = ""
+		Expect(cmd.PrepareEditInstruction(r)).NotTo(ContainSubstring("previous request"))
+	})
+
+	It("refines the latest run, or the given one", func() {
+		first := saveRun("Increase the size to 100Gi", "storage: 100Gi\n")
+		last := saveRun("Use the gp2 storage class", "storage: 100Gi\nclass: gp2\n")
+
+		Expect(cmd.RunRefine(newCmd(cmd.NewRefineCmd()), []string{"also add a label"})).To(Succeed())
+		refined := latestRun()
+		Expect(refined.Command).To(Equal(cmd.CommandRefine))
+		Expect(refined.Parent).To(Equal(last.ID))
+		Expect(refined.Request).To(Equal("also add a label"))
+		Expect(refined.Before.Files).To(HaveKeyWithValue("pvc.yaml", last.After.Files["pvc.yaml"]))
+
+		c := newCmd(cmd.NewRefineCmd())
+		Expect(c.Flags().Set(cmd.FlagRunFull, first.ID)).To(Succeed())
+		Expect(cmd.RunRefine(c, []string{"also add a label"})).To(Succeed())
+		Expect(latestRun().Parent).To(Equal(first.ID))
+	})
+
+	It("continues the last run through --continue", func() {
+		last := saveRun("Increase the size to 100Gi", "storage: 100Gi\n")
+
+		c := newCmd(cmd.NewEditCmd())
+		Expect(c.Flags().Set(cmd.FlagRequestFull, "also add a label")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagContinueFull, "true")).To(Succeed())
+		Expect(cmd.RunEdit(c, nil)).To(Succeed())
+		continued := latestRun()
+		Expect(continued.Parent).To(Equal(last.ID))
+		Expect(continued.Input).To(ContainSubstring("storage: 100Gi"))
+	})
+
+	It("chains every refinement to the run it refines", func() {
+		first := saveRun("Increase the size to 100Gi", "storage: 100Gi\n")
+
+		Expect(cmd.RunRefine(newCmd(cmd.NewRefineCmd()), []string{"use the gp2 storage class"})).To(Succeed())
+		second := latestRun()
+		time.Sleep(time.Millisecond)
+		Expect(cmd.RunRefine(newCmd(cmd.NewRefineCmd()), []string{"also add a label"})).To(Succeed())
+		third := latestRun()
+
+		Expect(second.Parent).To(Equal(first.ID))
+		Expect(third.Parent).To(Equal(second.ID))
+		Expect(third.ID).NotTo(Equal(second.ID))
+		// the second refinement starts from the result of the first one
+		Expect(third.Before).To(Equal(second.After))
+	})
+
+	It("fails when there is no run to refine", func() {
+		err := cmd.RunRefine(newCmd(cmd.NewRefineCmd()), []string{"also add a label"})
+		Expect(err).To(MatchError(ContainSubstring("could not load the run to continue")))
+		Expect(err).To(MatchError(runs.ErrNoRuns))
+
+		c := newCmd(cmd.NewEditCmd())
+		Expect(c.Flags().Set(cmd.FlagRequestFull, "also add a label")).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagContinueFull, "true")).To(Succeed())
+		Expect(cmd.RunEdit(c, nil)).To(MatchError(runs.ErrNoRuns))
+
+		c = newCmd(cmd.NewRefineCmd())
+		Expect(c.Flags().Set(cmd.FlagRunFull, "20230101-000000.000000")).To(Succeed())
+		Expect(cmd.RunRefine(c, []string{"also add a label"})).To(MatchError(ContainSubstring("could not load the run")))
+	})
+})
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/review"
+	"github.com/spf13/cobra"
+)
+
+// NewReviewCmd Creates the `copilot-ops review` CLI command.
+func NewReviewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandReview,
+
+		Short: "Reviews files and reports structured findings",
+
+		Long: "Review sends the given files to a chat model acting as a reviewer, and reports its findings " +
+			"with the file, line, severity, rule and a suggested fix. Findings can be printed as text, JSON, " +
+			"or SARIF for code scanning, and the suggested fixes can be written to the repo with --" + FlagWriteFull + ".",
+
+		Example: `  copilot-ops review --fileset app1
+  copilot-ops review --fileset prod --output sarif > copilot-ops.sarif
+  copilot-ops review --file 'examples/app1/*.yaml' --write`,
+
+		RunE: RunReview,
+		Args: cobra.NoArgs,
+	}
+
+	AddFileFlags(cmd)
+	AddBackendFlags(cmd)
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the root of the repo",
+	)
+
+	cmd.Flags().StringP(
+		FlagOutputTypeFull, FlagOutputTypeShort, OutputText,
+		"How to format output: text, json or sarif",
+	)
+
+	cmd.Flags().BoolP(
+		FlagWriteFull, FlagWriteShort, false,
+		"Apply the suggested fixes to the repo files",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Chat model to use (defaults to gpt-3.5-turbo for gpt-3, and the configured model for ollama)",
+	)
+
+	return cmd
+}
+
+// RunReview Runs when the `review` command is invoked.
+func RunReview(cmd *cobra.Command, args []string) error {
+	r, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	if len(r.Filemap.Files) == 0 {
+		return fmt.Errorf("no files to review, use --%s or --%s", FlagFilesFull, FlagFilesetsFull)
+	}
+
+	client, err := PrepareChatClient(r, review.PrepareMessages(r.Filemap))
+	if err != nil {
+		return fmt.Errorf("could not create client: %w", err)
+	}
+	answer, err := client.Chat()
+	if err != nil {
+		return err
+	}
+	report, err := review.Parse(answer, r.Filemap)
+	if err != nil {
+		return err
+	}
+
+	if err = printReport(cmd.OutOrStdout(), r.OutputType, report); err != nil {
+		return err
+	}
+	if !r.IsWrite {
+		return nil
+	}
+
+	// write the fixed files through the same path as edit and generate
+	fixed, err := review.ApplyFixes(r.Filemap, report.Findings)
+	if err != nil {
+		return err
+	}
+	log.Printf("applying fixes to %d file(s)\n", len(fixed.Files))
+	r.Filemap = fixed
+	return PrintOrWriteOut(r)
+}
+
+// printReport Writes the review to the given writer in the requested output format.
+func printReport(w io.Writer, outputType string, report *review.Report) error {
+	switch outputType {
+	case OutputText:
+		_, err := io.WriteString(w, report.Text())
+		return err
+	case filemap.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(report)
+	case OutputSARIF:
+		sarif, err := report.SARIF().JSON()
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, sarif)
+		return err
+	default:
+		return fmt.Errorf("invalid output type %q", outputType)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/review"
+)
+
+var _ = Describe("Review command", func() {
+	const (
+		pvc = `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: mysql
+spec:
+  resources:
+    requests:
+      storage: 1Gi
+`
+		answer = "```json\n" + `{"findings": [
+  {"file": "@pvc.yaml", "line": 8, "severity": "low", "rule": "small-volume",
+   "message": "1Gi is too small for MySQL", "fix": "      storage: 20Gi"},
+  {"file": "pvc.yaml", "line": 0, "severity": "medium", "rule": "no-labels",
+   "message": "none of the resources are labelled"}
+]}` + "\n```"
+	)
+
+	var (
+		c      *cobra.Command
+		ts     *httptest.Server
+		stdout *bytes.Buffer
+		cwd    string
+	)
+
+	BeforeEach(func() {
+		ts = ChatTestServer(answer)
+		ts.Start()
+		stdout = &bytes.Buffer{}
+		c = cmd.NewReviewCmd()
+		c.SetOut(stdout)
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagAIBackendFull, string(ai.GPT3))).To(Succeed())
+
+		// the fixes are written to the working directory
+		var err error
+		cwd, err = os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(GinkgoT().TempDir())).To(Succeed())
+		Expect(os.WriteFile("pvc.yaml", []byte(pvc), 0o600)).To(Succeed())
+		Expect(c.Flags().Set(cmd.FlagFilesFull, "pvc.yaml")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Chdir(cwd)).To(Succeed())
+		ts.Close()
+	})
+
+	It("is registered on the root command", func() {
+		found, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandReview})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name()).To(Equal(cmd.CommandReview))
+	})
+
+	It("prints the findings without changing the files", func() {
+		Expect(cmd.RunReview(c, nil)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("pvc.yaml:8: note [small-volume] 1Gi is too small for MySQL"))
+		Expect(stdout.String()).To(ContainSubstring("2 finding(s)"))
+		Expect(os.ReadFile("pvc.yaml")).To(BeEquivalentTo(pvc))
+	})
+
+	It("prints the findings as SARIF", func() {
+		Expect(c.Flags().Set(cmd.FlagOutputTypeFull, cmd.OutputSARIF)).To(Succeed())
+		Expect(cmd.RunReview(c, nil)).To(Succeed())
+
+		var sarif review.SARIFLog
+		Expect(json.Unmarshal(stdout.Bytes(), &sarif)).To(Succeed())
+		Expect(sarif.Version).To(Equal(review.SARIFVersion))
+		results := sarif.Runs[0].Results
+		Expect(results).To(HaveLen(2))
+		// the finding about the whole file has no region, since SARIF lines start at 1
+		Expect(results[0].Locations[0].PhysicalLocation.Region).To(BeNil())
+		Expect(results[1].Locations[0].PhysicalLocation.Region.StartLine).To(Equal(8))
+		Expect(results[1].Fixes).To(HaveLen(1))
+	})
+
+	It("writes the suggested fixes", func() {
+		Expect(c.Flags().Set(cmd.FlagWriteFull, "true")).To(Succeed())
+		Expect(cmd.RunReview(c, nil)).To(Succeed())
+		Expect(os.ReadFile("pvc.yaml")).To(BeEquivalentTo(
+			"apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: mysql\n" +
+				"spec:\n  resources:\n    requests:\n      storage: 20Gi\n",
+		))
+	})
+
+	It("requires files to review", func() {
+		c = cmd.NewReviewCmd()
+		Expect(c.Flags().Set(cmd.FlagOpenAIURLFull, ts.URL+gpt3.OpenAIEndpointV1)).To(Succeed())
+		Expect(cmd.RunReview(c, nil)).To(MatchError(ContainSubstring("no files to review")))
+	})
+})
@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/api"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/spf13/cobra"
+)
+
+// Define the timeouts of the API's server.
+const (
+	ServeReadHeaderTimeout = 10 * time.Second
+	ServeShutdownTimeout   = 2 * time.Minute
+)
+
+// NewServeCmd Creates the `copilot-ops serve` CLI command.
+func NewServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use: CommandServe,
+
+		Short: "Serves an HTTP API which generates and edits files, and answers questions",
+
+		Long: "Serve offers the " + CommandGenerate + ", " + CommandEdit + ", and " + CommandAsk + " commands as " +
+			"JSON endpoints at " + api.PathGenerate + ", " + api.PathEdit + ", and " + api.PathAsk + ". " +
+			"Requests send the request text along with the files, and receive the same result envelope as " +
+			"the CLI prints. The server never writes files. Its OpenAPI spec is served at " + api.PathOpenAPI + ".\n\n" +
+			"Bodies larger than --" + FlagMaxBodySizeFull + " are rejected, as are requests made while " +
+			"--" + FlagMaxConcurrentFull + " others are being handled, and so are requests asking for more " +
+			"completions or tokens than --" + FlagMaxCompletionsFull + " and --" + FlagMaxTokensFull + " allow, " +
+			"which default to --" + FlagNCompletionsFull + " and --" + FlagNTokensFull + ". " +
+			"The backend and its settings are read from the config found in --path, and can be overridden " +
+			"by each request.\n\n" +
+			"With --" + FlagGRPCListenFull + ", the same commands are also served as the gRPC service " +
+			"defined in pkg/rpc/copilot_ops.proto, whose Ask streams the answer as it is generated. " +
+			"Both share the limits above.",
+
+		Example: `  copilot-ops serve --listen :8080 --grpc-listen :9090
+  curl -s localhost:8080/v1/edit -d '{"request": "Increase the size of @pvc to 100Gi",
+    "files": [{"name": "pvc", "path": "app/pvc.yaml", "content": "..."}]}'`,
+
+		RunE: RunServe,
+		Args: cobra.NoArgs,
+	}
+
+	cmd.Flags().StringP(
+		FlagPathFull, FlagPathShort, ".",
+		"Path to the directory holding the config",
+	)
+
+	cmd.Flags().String(
+		FlagListenFull, DefaultListenAddress,
+		"Address to serve the API on",
+	)
+
+	cmd.Flags().String(
+		FlagGRPCListenFull, "",
+		"Address to serve the gRPC service on, it is not served when empty",
+	)
+
+	cmd.Flags().Int64(
+		FlagMaxBodySizeFull, DefaultMaxBodySize,
+		"Largest request body accepted, in bytes",
+	)
+
+	cmd.Flags().Int(
+		FlagMaxConcurrentFull, DefaultMaxConcurrent,
+		"Number of requests handled at once, further requests are rejected",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNCompletionsFull, FlagNCompletionsShort, DefaultCompletions,
+		"Number of edits to request, unless the request sets it",
+	)
+
+	cmd.Flags().Int32(
+		FlagMaxCompletionsFull, 0,
+		"Largest number of edits a request may ask for (defaults to --"+FlagNCompletionsFull+")",
+	)
+
+	cmd.Flags().Int32P(
+		FlagNTokensFull, FlagNTokensShort, DefaultTokens,
+		"Max number of tokens to generate, unless the request sets it",
+	)
+
+	cmd.Flags().Int32(
+		FlagMaxTokensFull, 0,
+		"Largest max number of tokens a request may ask for (defaults to --"+FlagNTokensFull+")",
+	)
+
+	cmd.Flags().StringP(
+		FlagModelFull, FlagModelShort, "",
+		"Model of the backend to use, unless the request sets it",
+	)
+
+	AddBackendFlags(cmd)
+
+	return cmd
+}
+
+// RunServe Runs when the `serve` command is invoked.
+func RunServe(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString(FlagListenFull)
+	grpcListen, _ := cmd.Flags().GetString(FlagGRPCListenFull)
+	maxBodySize, _ := cmd.Flags().GetInt64(FlagMaxBodySizeFull)
+	maxConcurrent, _ := cmd.Flags().GetInt(FlagMaxConcurrentFull)
+	maxCompletions, _ := cmd.Flags().GetInt32(FlagMaxCompletionsFull)
+	maxTokens, _ := cmd.Flags().GetInt32(FlagMaxTokensFull)
+	if maxBodySize < 1 || maxConcurrent < 1 {
+		return fmt.Errorf("--%s and --%s must be positive", FlagMaxBodySizeFull, FlagMaxConcurrentFull)
+	}
+
+	base, err := PrepareRequest(cmd)
+	if err != nil {
+		return err
+	}
+	server := NewServer(base, maxBodySize, maxConcurrent)
+	if maxCompletions > 0 {
+		server.MaxCompletions = maxCompletions
+	}
+	if maxTokens > 0 {
+		server.MaxTokens = maxTokens
+	}
+
+	if grpcListen != "" {
+		listener, listenErr := net.Listen("tcp", grpcListen)
+		if listenErr != nil {
+			return fmt.Errorf("could not listen on %s: %w", grpcListen, listenErr)
+		}
+		grpcServer := NewGRPCServer(server)
+		go func() {
+			log.Printf("serving the gRPC service on %s\n", grpcListen)
+			if serveErr := grpcServer.Serve(listener); serveErr != nil {
+				log.Printf("the gRPC service stopped: %s\n", serveErr)
+			}
+		}()
+		defer StopGRPC(grpcServer, ServeShutdownTimeout)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("serving the API on %s\n", listen)
+	return ListenAndServe(ctx, &http.Server{
+		Addr:              listen,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: ServeReadHeaderTimeout,
+	}, ServeShutdownTimeout)
+}
+
+// Server Handles the requests of the API, each on a copy of the base request.
+type Server struct {
+	base        *Request
+	MaxBodySize int64
+	// MaxCompletions Is the largest number of completions a request may ask for, as MaxTokens is
+	// for tokens, since the backend is called once per completion by some backends.
+	MaxCompletions int32
+	MaxTokens      int32
+	// slots Holds a value for every request being handled.
+	slots chan struct{}
+}
+
+// NewServer Returns a server which handles at most maxConcurrent requests at once, whose
+// requests may ask for at most the completions and tokens of the base request.
+func NewServer(base *Request, maxBodySize int64, maxConcurrent int) *Server {
+	return &Server{
+		base:           base,
+		MaxBodySize:    maxBodySize,
+		MaxCompletions: base.NCompletions,
+		MaxTokens:      base.NTokens,
+		slots:          make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Handler Returns the handler of the API's paths.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(api.PathGenerate, s.handle(CommandGenerate))
+	mux.Handle(api.PathEdit, s.handle(CommandEdit))
+	mux.Handle(api.PathAsk, s.handle(CommandAsk))
+	mux.HandleFunc(api.PathOpenAPI, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", api.ContentTypeYAML)
+		_, _ = w.Write(api.OpenAPI)
+	})
+	return mux
+}
+
+// handle Returns the handler which runs the command for the requests of its path.
+func (s *Server) handle(command string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, ErrorMethodNotAllowed, "use POST")
+			return
+		}
+		if !s.acquire() {
+			writeError(w, http.StatusTooManyRequests, ErrorTooManyRequests, s.busyMessage())
+			return
+		}
+		defer s.release()
+
+		var body api.Request
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, s.MaxBodySize)).Decode(&body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, ErrorRequestTooLarge,
+					fmt.Sprintf("the body is larger than %d bytes", s.MaxBodySize))
+				return
+			}
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "could not decode the body: "+err.Error())
+			return
+		}
+		r, err := s.request(req.Context(), command, &body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, err.Error())
+			return
+		}
+
+		log.Printf("%s %s: %q\n", req.Method, req.URL.Path, r.UserRequest)
+		answer, err := ProposeCommand(r)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, ErrorBackend, err.Error())
+			return
+		}
+		writeResult(w, http.StatusOK, APIResult(r, answer))
+	}
+}
+
+// acquire Takes a slot for a request, returning false when every slot is taken.
+func (s *Server) acquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release Frees the slot taken by acquire.
+func (s *Server) release() {
+	<-s.slots
+}
+
+// busyMessage Returns the message telling that every slot is taken.
+func (s *Server) busyMessage() string {
+	return fmt.Sprintf("already handling %d requests, retry later", cap(s.slots))
+}
+
+// request Returns a copy of the base request with the command, files, and settings of the body,
+// whose calls to the backend are canceled once ctx is done.
+func (s *Server) request(ctx context.Context, command string, body *api.Request) (*Request, error) {
+	r := *s.base
+	r.SetContext(ctx)
+	r.Command = command
+	r.UserRequest = strings.TrimSpace(body.Request)
+	r.Candidates = nil
+	r.Filemap = filemap.NewFilemap()
+	for i, file := range body.Files {
+		tag := file.Name
+		if tag == "" {
+			tag = filepath.Base(file.Path)
+		}
+		if file.Path == "" {
+			file.Path = tag
+		}
+		if tag == "" || tag == "." {
+			return nil, fmt.Errorf("files[%d] needs a name or a path", i)
+		}
+		if _, ok := r.Filemap.Files[tag]; ok {
+			return nil, fmt.Errorf("files[%d]: more than one file is named %q", i, tag)
+		}
+		r.Filemap.Files[tag] = file
+	}
+	r.Original = r.Filemap.Clone()
+	r.FilemapText = r.Filemap.EncodeToInputText()
+
+	if body.NCompletions < 0 || body.NTokens < 0 {
+		return nil, errors.New("ncompletions and ntokens must be positive")
+	}
+	if body.NCompletions > s.MaxCompletions || body.NTokens > s.MaxTokens {
+		return nil, fmt.Errorf("ncompletions and ntokens may be at most %d and %d", s.MaxCompletions, s.MaxTokens)
+	}
+	if body.NCompletions > 0 {
+		r.NCompletions = body.NCompletions
+	}
+	if body.NTokens > 0 {
+		r.NTokens = body.NTokens
+	}
+	if body.Backend != "" {
+		r.Backend = ai.Backend(body.Backend)
+	}
+	if body.Model != "" {
+		r.Model = body.Model
+	}
+
+	switch {
+	case command == CommandEdit && len(r.Filemap.Files) == 0:
+		return nil, errors.New("no files to edit")
+	case command != CommandEdit && r.UserRequest == "":
+		return nil, errors.New("the request is empty")
+	}
+	return &r, nil
+}
+
+// APIResult Returns the result of running the request's command. The edits which
+// could be decoded are the results, the best one first, and the others are warnings.
+func APIResult(r *Request, answer string) *Result {
+	result := &Result{}
+	if r.Command == CommandAsk {
+		result.Answer = &answer
+		return result
+	}
+	filemaps := []filemap.Filemap{}
+	warnings := []Error{}
+	if len(r.Candidates) == 0 {
+		filemaps = append(filemaps, *r.Filemap)
+	}
+	for _, c := range r.Candidates {
+		if c.Error != "" {
+			warnings = append(warnings, Error{
+				Code:    WarningUndecodable,
+				Message: fmt.Sprintf("response %d could not be decoded: %s", c.Index, c.Error),
+			})
+			continue
+		}
+		filemaps = append(filemaps, *c.Filemap)
+	}
+	result.Result = &filemaps
+	if len(warnings) > 0 {
+		result.Warnings = &warnings
+	}
+	return result
+}
+
+// writeError Responds with a result holding only the error.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeResult(w, status, &Result{Error: &Error{Code: code, Message: message}})
+}
+
+// writeResult Responds with the result as JSON.
+func writeResult(w http.ResponseWriter, status int, result *Result) {
+	w.Header().Set("Content-Type", api.ContentTypeJSON)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("could not write the response: %s\n", err)
+	}
+}
+
+// ListenAndServe Serves until the context is done, then shuts the server down,
+// waiting up to the timeout for the requests in progress.
+func ListenAndServe(ctx context.Context, server *http.Server, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	log.Println("shutting down, waiting for the requests in progress")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,148 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/api"
+	"github.com/redhat-et/copilot-ops/pkg/cmd"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+)
+
+var _ = Describe("Serve command", func() {
+	var (
+		ts      *httptest.Server
+		server  *cmd.Server
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		ts = OpenAITestServer()
+		ts.Start()
+		base := &cmd.Request{
+			Backend:      ai.GPT3,
+			NTokens:      cmd.DefaultTokens,
+			NCompletions: cmd.DefaultCompletions,
+			Config:       config.Config{OpenAI: &gpt3.Config{BaseURL: ts.URL + gpt3.OpenAIEndpointV1}},
+		}
+		server = cmd.NewServer(base, cmd.DefaultMaxBodySize, 1)
+		handler = server.Handler()
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	// call Posts the body to the path, and decodes the result.
+	call := func(path string, body string) (int, *cmd.Result) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(api.ContentTypeJSON))
+		var result cmd.Result
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		return rec.Code, &result
+	}
+
+	It("is registered on the root command", func() {
+		c, _, err := cmd.NewRootCmd().Find([]string{cmd.CommandServe})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name()).To(Equal(cmd.CommandServe))
+	})
+
+	It("edits the files of the request", func() {
+		code, result := call(api.PathEdit, `{"request": "Rename the pod",
+			"files": [{"path": "app/pod.yaml", "content": "kind: Pod\n"}]}`)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(result.Error).To(BeNil())
+		Expect(*result.Result).To(HaveLen(1))
+		Expect((*result.Result)[0].Files).NotTo(BeEmpty())
+	})
+
+	It("generates files and answers questions", func() {
+		code, result := call(api.PathGenerate, `{"request": "Add a pod running nginx"}`)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(*result.Result).To(HaveLen(1))
+
+		code, result = call(api.PathAsk, `{"request": "What is @pod?",
+			"files": [{"name": "pod", "content": "kind: Pod\n"}]}`)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(result.Result).To(BeNil())
+		Expect(*result.Answer).To(ContainSubstring("What is @pod?"))
+	})
+
+	It("rejects invalid requests", func() {
+		code, result := call(api.PathEdit, `{"request": "Rename the pod"}`)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(result.Error.Code).To(Equal(cmd.ErrorInvalidRequest))
+
+		code, result = call(api.PathAsk, `{"files": [{"name": "pod", "content": ""}]}`)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(result.Error.Message).To(ContainSubstring("empty"))
+
+		code, result = call(api.PathGenerate, `{"request": `)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(result.Error.Code).To(Equal(cmd.ErrorInvalidRequest))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.PathAsk, nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("limits the completions and tokens requests may ask for", func() {
+		server.MaxCompletions = 2
+		code, result := call(api.PathGenerate, `{"request": "Add a pod", "ncompletions": 1000000}`)
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(result.Error.Message).To(ContainSubstring("at most 2 and 1000"))
+		code, _ = call(api.PathGenerate, `{"request": "Add a pod", "ntokens": 1001}`)
+		Expect(code).To(Equal(http.StatusBadRequest))
+
+		code, _ = call(api.PathGenerate, `{"request": "Add a pod", "ncompletions": 2, "ntokens": 1000}`)
+		Expect(code).To(Equal(http.StatusOK))
+	})
+
+	It("limits the size of bodies", func() {
+		server.MaxBodySize = 64
+		code, result := call(api.PathAsk, `{"request": "`+strings.Repeat("why? ", 20)+`"}`)
+		Expect(code).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(result.Error.Code).To(Equal(cmd.ErrorRequestTooLarge))
+	})
+
+	It("rejects requests beyond the concurrency limit", func() {
+		// hold the server's only slot with a request whose body is still being written
+		body, write := io.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, api.PathAsk, body))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		}()
+		_, err := io.WriteString(write, `{"request": "what `)
+		Expect(err).NotTo(HaveOccurred())
+
+		code, result := call(api.PathAsk, `{"request": "why?"}`)
+		Expect(code).To(Equal(http.StatusTooManyRequests))
+		Expect(result.Error.Code).To(Equal(cmd.ErrorTooManyRequests))
+
+		_, err = io.WriteString(write, `is a pod?"}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(write.Close()).To(Succeed())
+		<-done
+	})
+
+	It("serves the OpenAPI spec", func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.PathOpenAPI, nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.Bytes()).To(Equal(api.OpenAPI))
+	})
+})
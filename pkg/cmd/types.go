@@ -11,8 +11,21 @@ type Result struct {
 	Error    *Error        `json:"error"`
 	Warnings *[]Error      `json:"warnings"`
 	Result   *[]fm.Filemap `json:"result"`
+	// Answer Is the reply to a question, which has no files as its result.
+	Answer *string `json:"answer,omitempty"`
 }
 
+// Codes of the errors and warnings of a Result.
+const (
+	ErrorInvalidRequest   = "invalid_request"
+	ErrorMethodNotAllowed = "method_not_allowed"
+	ErrorRequestTooLarge  = "request_too_large"
+	ErrorTooManyRequests  = "too_many_requests"
+	ErrorBackend          = "backend_error"
+	// WarningUndecodable Reports a completion which could not be decoded into files.
+	WarningUndecodable = "undecodable_completion"
+)
+
 // Error represents an error or warning from within the program
 // which has taken place during the execution of the CLI.
 type Error struct {
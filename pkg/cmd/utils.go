@@ -1,14 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/redhat-et/copilot-ops/pkg/ai"
 	"github.com/redhat-et/copilot-ops/pkg/ai/gpt3"
+	"github.com/redhat-et/copilot-ops/pkg/ai/ollama"
+	"github.com/redhat-et/copilot-ops/pkg/candidates"
 	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/diff"
 	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/interactive"
+	"github.com/redhat-et/copilot-ops/pkg/journal"
+	"github.com/redhat-et/copilot-ops/pkg/runs"
 	"github.com/spf13/cobra"
 )
 
@@ -16,18 +23,96 @@ import (
 // AI backends.
 // FIXME: consolidate the settings depending on the type of Model. E.g., OpenAI settings should be under their own.
 type Request struct {
-	Config       config.Config
-	Fileset      *config.Filesets
-	Filemap      *filemap.Filemap
-	FilemapText  string
-	UserRequest  string
-	IsWrite      bool
-	OutputType   string
-	OpenAIURL    string
-	NTokens      int32
-	NCompletions int32
+	Config      config.Config
+	Fileset     *config.Filesets
+	Filemap     *filemap.Filemap
+	FilemapText string
+	UserRequest string
+	IsWrite     bool
+	// GitCommit Commits the written files to the git repo, on a new branch when GitBranch is set.
+	GitCommit bool
+	GitBranch string
+	// IsInteractive Asks the user about every hunk before writing the accepted ones.
+	IsInteractive bool
+	OutputType    string
+	OpenAIURL     string
+	NTokens       int32
+	NCompletions  int32
 	// Backend Sepecifies which type of AI Backend to use.
 	Backend ai.Backend
+	// Model Overrides the model used by the selected backend, if set.
+	Model string
+	// SystemPrompt Is the system message sent to chat models.
+	SystemPrompt string
+	// Command Is the name of the command which created the request.
+	Command string
+	// Original Holds the files as they were loaded, before any response was decoded into Filemap.
+	Original *filemap.Filemap
+	// Prompt Is the prompt or instruction which was sent to the backend.
+	Prompt string
+	// Responses Are the raw responses returned by the backend.
+	Responses []string
+	// Parent Is the ID of the run which this request refines, if any.
+	Parent string
+	// Run Is the ID the request was recorded under, once it has been.
+	Run string
+	// ParentRequest Is the user request of the run being refined.
+	ParentRequest string
+	// Candidates Are the ranked edits returned by the backend, the best one first.
+	Candidates []candidates.Candidate
+	// Pick Selects a candidate by its rank, starting at 1. Zero selects the best candidate.
+	Pick int
+	// GitFiles Selects files through git, in addition to the files and filesets.
+	GitFiles GitFiles
+	// Untrusted Confines the files named by issues and comments to the working directory,
+	// since anyone may have written them.
+	Untrusted bool
+	// reviewer Is shared by every prompt shown to the user, since it buffers STDIN.
+	reviewer *interactive.Reviewer
+	// ctx Bounds the calls to the backend, which are not canceled when it is nil.
+	ctx context.Context
+}
+
+// Context Returns the context which bounds the calls to the backend.
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// SetContext Cancels the calls to the backend once ctx is done, e.g. when the client
+// of a server goes away.
+func (r *Request) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// Reviewer Returns the reviewer used to prompt the user on STDIN.
+func (r *Request) Reviewer() *interactive.Reviewer {
+	if r.reviewer == nil {
+		r.reviewer = interactive.NewReviewer(os.Stdin, os.Stdout)
+	}
+	return r.reviewer
+}
+
+// completions Returns the number of completions to request, which is at least one.
+func (r *Request) completions() int {
+	if r.NCompletions < 1 {
+		return 1
+	}
+	return int(r.NCompletions)
+}
+
+// ollamaConfig Returns the config of the ollama backend, refusing one whose timeout is invalid
+// rather than falling back to the default timeout.
+func ollamaConfig(r *Request) (ollama.Config, error) {
+	if r.Config.Ollama == nil {
+		return ollama.Config{}, fmt.Errorf("no config provided for ollama")
+	}
+	if _, err := ollama.Timeout(*r.Config.Ollama); err != nil {
+		return ollama.Config{}, err
+	}
+	return *r.Config.Ollama, nil
 }
 
 // PrepareRequest Processes the user input along with provided environment variables,
@@ -35,22 +120,29 @@ type Request struct {
 func PrepareRequest(cmd *cobra.Command) (*Request, error) {
 	request, _ := cmd.Flags().GetString(FlagRequestFull)
 	write, _ := cmd.Flags().GetBool(FlagWriteFull)
+	gitCommit, _ := cmd.Flags().GetBool(FlagGitCommitFull)
+	gitBranch, _ := cmd.Flags().GetString(FlagGitBranchFull)
+	interactive, _ := cmd.Flags().GetBool(FlagInteractiveFull)
 	path, _ := cmd.Flags().GetString(FlagPathFull)
 	files, _ := cmd.Flags().GetStringArray(FlagFilesFull)
-	if cmd.Name() == CommandEdit {
-		file, _ := cmd.Flags().GetString(FlagFilesFull)
-		files = append(files, file)
-	}
 	filesets, _ := cmd.Flags().GetStringArray(FlagFilesetsFull)
+	changedSince, _ := cmd.Flags().GetString(FlagChangedSinceFull)
+	staged, _ := cmd.Flags().GetBool(FlagStagedFull)
+	refs, _ := cmd.Flags().GetStringArray(FlagRefFull)
+	trackedOnly, _ := cmd.Flags().GetBool(FlagTrackedOnlyFull)
 	nTokens, _ := cmd.Flags().GetInt32(FlagNTokensFull)
 	nCompletions, _ := cmd.Flags().GetInt32(FlagNCompletionsFull)
+	pick, _ := cmd.Flags().GetInt(FlagPickFull)
 	outputType, _ := cmd.Flags().GetString(FlagOutputTypeFull)
 	openAIURL, _ := cmd.Flags().GetString(FlagOpenAIURLFull)
 	aiBackend, _ := cmd.Flags().GetString(FlagAIBackendFull)
+	model, _ := cmd.Flags().GetString(FlagModelFull)
+	systemPrompt, _ := cmd.Flags().GetString(FlagSystemFull)
 
 	log.Println("flags:")
 	log.Printf(" - %-8s: %v\n", FlagRequestFull, request)
 	log.Printf(" - %-8s: %v\n", FlagWriteFull, write)
+	log.Printf(" - %-8s: %v\n", FlagInteractiveFull, interactive)
 	log.Printf(" - %-8s: %v\n", FlagPathFull, path)
 	log.Printf(" - %-8s: %v\n", FlagFilesFull, files)
 	log.Printf(" - %-8s: %v\n", FlagFilesetsFull, filesets)
@@ -60,26 +152,19 @@ func PrepareRequest(cmd *cobra.Command) (*Request, error) {
 
 	log.Printf(" - %-8s: %q\n", FlagOpenAIURLFull, openAIURL)
 	log.Printf(" - %-8s: %q\n", FlagAIBackendFull, aiBackend)
+	log.Printf(" - %-8s: %q\n", FlagModelFull, model)
 
-	// Handle --path by changing the working directory
-	// so that every file name we refer to is relative to path
-	if path != "" {
-		if err := os.Chdir(path); err != nil {
-			return nil, err
-		}
+	if (gitCommit || gitBranch != "") && !write && !interactive {
+		return nil, fmt.Errorf("--%s and --%s commit the written files, so they need --%s or --%s",
+			FlagGitCommitFull, FlagGitBranchFull, FlagWriteFull, FlagInteractiveFull)
 	}
 
-	// Load the config from file if it exists, but if it doesn't exist
-	// we'll just use the defaults and continue without error.
-	// Errors here might return if the file exists but is invalid.
-	conf := config.Config{}
-	if err := conf.Load(); err != nil {
+	conf, err := LoadConfig(path)
+	if err != nil {
 		return nil, err
 	}
-	// TODO: generalize overriding default values via CLI
-	conf.SetDefaults()
-	// override OpenAI URL
-	if openAIURL != "" {
+	// the flag overrides the URL from OPENAI_URL or the config only when it is given
+	if cmd.Flags().Changed(FlagOpenAIURLFull) {
 		conf.OpenAI.BaseURL = openAIURL
 	}
 
@@ -94,38 +179,133 @@ func PrepareRequest(cmd *cobra.Command) (*Request, error) {
 	if err := fm.LoadFilesets(filesets, conf, config.ConfigFile); err != nil {
 		log.Fatalf("error loading filesets: %s\n", err.Error())
 	}
+	gitFiles := GitFiles{ChangedSince: changedSince, Staged: staged, Refs: refs, TrackedOnly: trackedOnly}
+	if err = LoadGitFiles(fm, gitFiles); err != nil {
+		return nil, fmt.Errorf("error loading files from git: %w", err)
+	}
 	filemapText := fm.EncodeToInputText()
 
 	// select backend type
 	selectedBackend := ai.Backend(aiBackend)
-	if selectedBackend == "" {
+	if selectedBackend == ai.Unselected {
 		selectedBackend = conf.Backend
 	}
+	if selectedBackend == ai.Unselected {
+		selectedBackend = ai.GPT3
+	}
 
 	// configure backends
 	// FIXME: create default config methods for these
 	r := Request{
-		Config:       conf,
-		Filemap:      fm,
-		FilemapText:  filemapText,
-		UserRequest:  request,
-		IsWrite:      write,
-		OutputType:   outputType,
-		NTokens:      nTokens,
-		NCompletions: nCompletions,
-		Backend:      selectedBackend,
+		Config:        conf,
+		GitFiles:      gitFiles,
+		Command:       cmd.Name(),
+		Filemap:       fm,
+		Original:      fm.Clone(),
+		FilemapText:   filemapText,
+		UserRequest:   request,
+		IsWrite:       write,
+		GitCommit:     gitCommit || gitBranch != "",
+		GitBranch:     gitBranch,
+		IsInteractive: interactive,
+		OutputType:    outputType,
+		NTokens:       nTokens,
+		NCompletions:  nCompletions,
+		Pick:          pick,
+		Backend:       selectedBackend,
+		Model:         model,
+		SystemPrompt:  systemPrompt,
 	}
 
 	return &r, nil
 }
 
+// LoadConfig Changes the working directory to the root of the repo, so that every
+// file name we refer to is relative to path, then loads the config with its defaults.
+func LoadConfig(path string) (config.Config, error) {
+	if path != "" {
+		if err := os.Chdir(path); err != nil {
+			return config.Config{}, err
+		}
+	}
+
+	// Load the config from file if it exists, but if it doesn't exist
+	// we'll just use the defaults and continue without error.
+	// Errors here might return if the file exists but is invalid.
+	conf := config.Config{}
+	if err := conf.Load(); err != nil {
+		return conf, err
+	}
+	// TODO: generalize overriding default values via CLI
+	conf.SetDefaults()
+	return conf, nil
+}
+
+// SaveRun Records the request and its result under the runs directory, so that
+// it can be refined later. Failing to record a run does not fail the command.
+func SaveRun(r *Request) {
+	run := runs.NewRun(r.Command)
+	run.Backend = r.Backend
+	run.Model = r.Model
+	run.Parent = r.Parent
+	run.Request = r.UserRequest
+	run.Prompt = r.Prompt
+	run.Responses = r.Responses
+	run.Before = r.Original
+	run.After = r.Filemap
+	// everything but generate edits the loaded files
+	if r.Command != CommandGenerate || r.Parent != "" {
+		run.Input = r.Original.EncodeToInputText()
+	}
+	if err := run.Save(runs.RunsDir); err != nil {
+		log.Printf("could not record run: %s\n", err)
+		return
+	}
+	r.Run = run.ID
+	log.Printf("recorded run %s\n", run.ID)
+}
+
+// ContinueRun Replaces the request's files with the result of a previous run,
+// so that the request refines that result. An empty ID selects the latest run.
+func ContinueRun(r *Request, id string) error {
+	previous, err := runs.LoadOrLatest(runs.RunsDir, id)
+	if err != nil {
+		return fmt.Errorf("could not load the run to continue: %w", err)
+	}
+	log.Printf("continuing run %s: %q\n", previous.ID, previous.Request)
+	files := filemap.NewFilemap()
+	if previous.After != nil {
+		files = previous.After.Clone()
+	}
+	// files given with this request are sent along with the previous result
+	for tag, file := range r.Filemap.Files {
+		if _, ok := files.Files[tag]; !ok {
+			files.Files[tag] = file
+		}
+	}
+	r.Filemap = files
+	r.Original = files.Clone()
+	r.FilemapText = files.EncodeToInputText()
+	r.Parent = previous.ID
+	r.ParentRequest = previous.Request
+	return nil
+}
+
 // PrintOrWriteOut Accepts a request object and writes the contents of the filemap
 // to the disk if specified, otherwise it prints to STDOUT.
 func PrintOrWriteOut(r *Request) error {
+	if r.IsInteractive {
+		return ReviewAndWrite(r, r.Reviewer())
+	}
+
 	if r.IsWrite {
-		err := r.Filemap.WriteUpdatesToFiles()
+		return WriteFiles(r, r.Filemap)
+	}
+
+	if r.OutputType == OutputDiff {
+		_, err := os.Stdout.WriteString(diff.Filemaps(r.Original, r.Filemap))
 		if err != nil {
-			return err
+			return fmt.Errorf("could not write to stdout: %w", err)
 		}
 		return nil
 	}
@@ -143,6 +323,69 @@ func PrintOrWriteOut(r *Request) error {
 	return nil
 }
 
+// WriteFiles Writes the files to the repo, journaling the write under the ID of the
+// request's run so that it can be undone, then commits them if requested. The files
+// read at a git revision are left out, and are refused when they were changed.
+func WriteFiles(r *Request, fm *filemap.Filemap) error {
+	fm, err := withoutRefFiles(r.Original, fm)
+	if err != nil {
+		return err
+	}
+	entry := journal.NewEntry(r.Run, r.Command)
+	entry.Backend = r.Backend
+	entry.Model = r.Model
+	entry.Request = r.UserRequest
+	if err = journal.Write(journal.HistoryDir, entry, fm); err != nil {
+		return err
+	}
+	if !r.GitCommit {
+		return nil
+	}
+	return CommitWrite(r, entry)
+}
+
+// withoutRefFiles Returns the files to write, leaving out the unchanged files read at a
+// git revision, since writing them would revert the working tree to that revision.
+func withoutRefFiles(original, fm *filemap.Filemap) (*filemap.Filemap, error) {
+	written := filemap.NewFilemap()
+	for tag, file := range fm.Files {
+		if file.Rev == "" {
+			written.Files[tag] = file
+			continue
+		}
+		if original == nil || original.Files[tag].Content != file.Content {
+			return nil, fmt.Errorf("refusing to write %s, which was read at %s with --%s", file.Path, file.Rev, FlagRefFull)
+		}
+	}
+	return written, nil
+}
+
+// ReviewAndWrite Walks the user through every hunk of the proposed changes and
+// writes only the accepted hunks to the repo files.
+func ReviewAndWrite(r *Request, reviewer *interactive.Reviewer) error {
+	accepted, err := reviewer.Review(r.Original, r.Filemap)
+	if err != nil {
+		return err
+	}
+	if len(accepted.Files) == 0 {
+		fmt.Fprintln(reviewer.Out, "no changes were accepted")
+		return nil
+	}
+	if err = WriteFiles(r, accepted); err != nil {
+		return err
+	}
+	fmt.Fprintf(reviewer.Out, "wrote %d file(s)\n", len(accepted.Files))
+	return nil
+}
+
+// AddInteractiveFlag Appends the flag which reviews the proposed changes hunk by hunk.
+func AddInteractiveFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP(
+		FlagInteractiveFull, FlagInteractiveShort, false,
+		"Review every changed hunk, then write only the accepted ones to the repo files",
+	)
+}
+
 // AddRequestFlags Appends flags to the given command which are then used at the command-line.
 func AddRequestFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(
@@ -155,6 +398,16 @@ func AddRequestFlags(cmd *cobra.Command) {
 		"Write changes to the repo files (if not set the patch is printed to stdout)",
 	)
 
+	cmd.Flags().Bool(
+		FlagGitCommitFull, false,
+		"Commit the written files to the git repo, with a message derived from the request",
+	)
+
+	cmd.Flags().String(
+		FlagGitBranchFull, "",
+		"Create this branch from HEAD and commit the written files on it (implies --"+FlagGitCommitFull+")",
+	)
+
 	cmd.Flags().StringP(
 		FlagPathFull, FlagPathShort, ".",
 		"Path to the root of the repo",
@@ -162,17 +415,65 @@ func AddRequestFlags(cmd *cobra.Command) {
 
 	cmd.Flags().StringP(
 		FlagOutputTypeFull, FlagOutputTypeShort, "json",
-		"How to format output",
+		"How to format output: json, plain, or diff (a unified diff against the loaded files)",
 	)
 
+	AddBackendFlags(cmd)
+}
+
+// AddBackendFlags Appends the flags which select and configure the AI backend.
+func AddBackendFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP(
-		FlagAIBackendFull, FlagAIBackendShort, string(ai.GPT3), "AI Backend to use",
+		FlagAIBackendFull, FlagAIBackendShort, "",
+		"AI Backend to use: gpt-3 or ollama (defaults to the configured backend, then gpt-3)",
 	)
 
 	cmd.Flags().StringP(
 		FlagOpenAIURLFull,
 		FlagOpenAIURLShort,
 		gpt3.OpenAIURL+gpt3.OpenAIEndpointV1,
-		"OpenAI URL",
+		"OpenAI URL (overrides OPENAI_URL and openAI.url from the config)",
+	)
+}
+
+// AddContinueFlag Appends the flag which refines the previous run instead of starting over.
+func AddContinueFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool(
+		FlagContinueFull, false,
+		"Refine the result of the previous edit or generate run instead of the files on disk",
+	)
+}
+
+// AddFileFlags Appends the flags used to select the files which are sent along with a request.
+func AddFileFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayP(
+		FlagFilesFull, FlagFilesShort, []string{},
+		"File paths (glob) to be considered for the patch (can be specified multiple times)",
+	)
+
+	cmd.Flags().StringArrayP(
+		FlagFilesetsFull, FlagFilesetsShort, []string{},
+		"Fileset names (defined in "+config.ConfigFile+") to be considered for the patch (can be specified multiple times)",
+	)
+
+	cmd.Flags().String(
+		FlagChangedSinceFull, "",
+		"Also consider the files changed since HEAD forked from this git revision, including uncommitted changes",
+	)
+
+	cmd.Flags().Bool(
+		FlagStagedFull, false,
+		"Also consider the files with changes staged in git",
+	)
+
+	cmd.Flags().StringArray(
+		FlagRefFull, []string{},
+		"Also consider the files matching a glob as they are at a git revision, given as REV:GLOB; "+
+			"they are tagged REV:PATH and never written (can be specified multiple times)",
+	)
+
+	cmd.Flags().Bool(
+		FlagTrackedOnlyFull, false,
+		"Only consider the files of --"+FlagFilesFull+" and --"+FlagFilesetsFull+" which git tracks",
 	)
 }
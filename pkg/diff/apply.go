@@ -0,0 +1,80 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Apply Applies the given hunks, which must be ordered and must not overlap, to the
+// content. Hunks which were not selected are left out, keeping the original lines.
+func Apply(content string, hunks []Hunk) (string, error) {
+	lines := SplitLines(content)
+	var out strings.Builder
+	// next is the index of the first original line which has not been copied yet
+	next := 0
+	for _, h := range hunks {
+		start := h.OldStart - 1
+		if start < next || start > len(lines) {
+			return "", fmt.Errorf("hunk %s does not fit the content", h.Header())
+		}
+		for _, line := range lines[next:start] {
+			out.WriteString(line)
+		}
+		next = start
+		for _, line := range h.Lines {
+			switch line.Op {
+			case Equal, Delete:
+				if next >= len(lines) || strings.TrimSuffix(lines[next], "\n") != strings.TrimSuffix(line.Text, "\n") {
+					return "", fmt.Errorf("hunk %s does not match the content", h.Header())
+				}
+				if line.Op == Equal {
+					out.WriteString(line.Text)
+				}
+				next++
+			case Insert:
+				out.WriteString(line.Text)
+			}
+		}
+	}
+	for _, line := range lines[next:] {
+		out.WriteString(line)
+	}
+	return out.String(), nil
+}
+
+// ParseHunk Parses a single hunk in unified diff format, as produced by Hunk.String,
+// keeping the position of the original hunk. Lines starting with '#' are ignored.
+func ParseHunk(text string, original Hunk) (Hunk, error) {
+	h := Hunk{OldStart: original.OldStart, NewStart: original.NewStart}
+	var previous *Line
+	for _, raw := range SplitLines(text) {
+		trimmed := strings.TrimSuffix(raw, "\n")
+		switch {
+		case strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "@@"):
+			continue
+		case trimmed == noNewline:
+			if previous != nil {
+				previous.Text = strings.TrimSuffix(previous.Text, "\n")
+			}
+			continue
+		case trimmed == "":
+			// editors like to strip the trailing space of empty context lines
+			h.Lines = append(h.Lines, Line{Op: Equal, Text: "\n"})
+		case trimmed[0] == ' ':
+			h.Lines = append(h.Lines, Line{Op: Equal, Text: raw[1:]})
+		case trimmed[0] == '-':
+			h.Lines = append(h.Lines, Line{Op: Delete, Text: raw[1:]})
+		case trimmed[0] == '+':
+			h.Lines = append(h.Lines, Line{Op: Insert, Text: raw[1:]})
+		default:
+			return Hunk{}, fmt.Errorf("invalid hunk line %q", trimmed)
+		}
+		previous = &h.Lines[len(h.Lines)-1]
+	}
+	countLines(&h)
+	if h.OldLines != original.OldLines {
+		return Hunk{}, fmt.Errorf("the edited hunk must keep the %d original line(s), found %d",
+			original.OldLines, h.OldLines)
+	}
+	return h, nil
+}
@@ -0,0 +1,307 @@
+// diff computes line-based differences between files and renders them as
+// unified diffs which can be applied with `git apply`.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext Is the number of unchanged lines shown around every change.
+const DefaultContext = 3
+
+// DevNull Is the path used in place of a file that does not exist on one side of a diff.
+const DevNull = "/dev/null"
+
+// noNewline Is the marker used by unified diffs when a file does not end with a newline.
+const noNewline = "\\ No newline at end of file"
+
+// Op Is the kind of change made to a line.
+type Op int
+
+const (
+	// Equal Marks a line which is present on both sides.
+	Equal Op = iota
+	// Delete Marks a line which is only present on the old side.
+	Delete
+	// Insert Marks a line which is only present on the new side.
+	Insert
+)
+
+// Line Is a single line of a diff, including its line terminator if it has one.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Hunk Is a group of changes along with their surrounding context.
+type Hunk struct {
+	// OldStart and NewStart Are the 1-based line numbers at which the hunk starts.
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// SplitLines Splits the content into lines, keeping the line terminators.
+func SplitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines Returns the shortest edit script which turns a into b, as computed by Myers' algorithm.
+func Lines(a, b string) []Line {
+	return myers(SplitLines(a), SplitLines(b))
+}
+
+// myers Implements the linear space variant of Myers' O(ND) difference algorithm,
+// which splits the files at the middle snake of an edit script and recurses on both
+// halves, so that large rewrites only need memory proportional to the files' lengths.
+func myers(a, b []string) []Line {
+	script := make([]Line, 0, len(a)+len(b))
+	size := len(a) + len(b) + 3
+	d := &differ{a: a, b: b, forward: make([]int, 2*size), backward: make([]int, 2*size), script: script}
+	d.compare(0, len(a), 0, len(b))
+	return d.script
+}
+
+// differ Holds the files being compared, the furthest reaching paths of the
+// search for a middle snake and the edit script built so far.
+type differ struct {
+	a, b              []string
+	forward, backward []int
+	script            []Line
+}
+
+// compare Appends the edit script which turns a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.script = append(d.script, Line{Op: Equal, Text: d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for _, text := range d.b[bLo:bHi] {
+			d.script = append(d.script, Line{Op: Insert, Text: text})
+		}
+	case bLo == bHi:
+		for _, text := range d.a[aLo:aHi] {
+			d.script = append(d.script, Line{Op: Delete, Text: text})
+		}
+	default:
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for _, text := range d.a[x:u] {
+			d.script = append(d.script, Line{Op: Equal, Text: text})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for _, text := range d.a[aHi : aHi+suffix] {
+		d.script = append(d.script, Line{Op: Equal, Text: text})
+	}
+}
+
+// middleSnake Searches for the shortest edit script of a[aLo:aHi] and b[bLo:bHi] from
+// both ends at once, and returns the snake from (x, y) to (u, v) where the searches meet,
+// which lies on a shortest edit script.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	// offset Keeps the diagonals, which range from -(n+m) to n+m, within the slices
+	offset := n + m + 1
+	forward, backward := d.forward, d.backward
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	for step := 0; step <= (n+m+1)/2; step++ {
+		// forward paths, with x and y counted from the start
+		for k := -step; k <= step; k += 2 {
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && d.a[aLo+u] == d.b[bLo+v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			if c := delta - k; odd && c >= -(step-1) && c <= step-1 && u+backward[offset+c] >= n {
+				return aLo + x, bLo + y, aLo + u, bLo + v
+			}
+		}
+		// backward paths, with x and y counted from the end
+		for c := -step; c <= step; c += 2 {
+			if c == -step || (c != step && backward[offset+c-1] < backward[offset+c+1]) {
+				x = backward[offset+c+1]
+			} else {
+				x = backward[offset+c-1] + 1
+			}
+			y = x - c
+			u, v = x, y
+			for u < n && v < m && d.a[aHi-u-1] == d.b[bHi-v-1] {
+				u++
+				v++
+			}
+			backward[offset+c] = u
+			if k := delta - c; !odd && k >= -step && k <= step && u+forward[offset+k] >= n {
+				return aHi - u, bHi - v, aHi - x, bHi - y
+			}
+		}
+	}
+	// the searches always meet by the time half of the longest script has been explored
+	panic("diff: no middle snake found")
+}
+
+// Hunks Groups the changes between a and b into hunks with the given amount of context.
+func Hunks(a, b string, context int) []Hunk {
+	script := Lines(a, b)
+	var hunks []Hunk
+	var current *Hunk
+	oldLine, newLine := 1, 1
+	// lastChange is the index in the script of the most recent change
+	lastChange := -1
+
+	for i, line := range script {
+		if line.Op != Equal {
+			if current == nil || i-lastChange > 2*context {
+				if current != nil {
+					current.Lines = append(current.Lines, script[lastChange+1:lastChange+1+context]...)
+					hunks = append(hunks, *current)
+				}
+				// start a new hunk, including up to context lines before the change
+				start := i - context
+				if start < 0 {
+					start = 0
+				}
+				current = &Hunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+				current.Lines = append(current.Lines, script[start:i]...)
+			} else {
+				current.Lines = append(current.Lines, script[lastChange+1:i]...)
+			}
+			current.Lines = append(current.Lines, line)
+			lastChange = i
+		}
+		switch line.Op {
+		case Equal:
+			oldLine++
+			newLine++
+		case Delete:
+			oldLine++
+		case Insert:
+			newLine++
+		}
+	}
+	if current != nil {
+		end := lastChange + 1 + context
+		if end > len(script) {
+			end = len(script)
+		}
+		current.Lines = append(current.Lines, script[lastChange+1:end]...)
+		hunks = append(hunks, *current)
+	}
+	for i := range hunks {
+		countLines(&hunks[i])
+	}
+	return hunks
+}
+
+// countLines Sets the number of old and new lines covered by the hunk.
+func countLines(h *Hunk) {
+	h.OldLines, h.NewLines = 0, 0
+	for _, line := range h.Lines {
+		switch line.Op {
+		case Equal:
+			h.OldLines++
+			h.NewLines++
+		case Delete:
+			h.OldLines++
+		case Insert:
+			h.NewLines++
+		}
+	}
+}
+
+// Header Returns the hunk's header line, e.g. "@@ -1,4 +1,5 @@".
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// hunkRange Formats a range of a hunk header, where empty ranges start one line earlier.
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// String Renders the hunk in unified diff format.
+func (h Hunk) String() string {
+	var b strings.Builder
+	b.WriteString(h.Header() + "\n")
+	for _, line := range h.Lines {
+		prefix := " "
+		switch line.Op {
+		case Delete:
+			prefix = "-"
+		case Insert:
+			prefix = "+"
+		case Equal:
+		}
+		b.WriteString(prefix + line.Text)
+		if !strings.HasSuffix(line.Text, "\n") {
+			b.WriteString("\n" + noNewline + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Unified Renders the differences between a and b as a unified diff in the format
+// produced by `git diff`. Paths equal to DevNull mark files which were added or removed.
+func Unified(oldPath, newPath, a, b string) string {
+	hunks := Hunks(a, b, DefaultContext)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	name := newPath
+	if name == DevNull {
+		name = oldPath
+	}
+	fmt.Fprintf(&out, "diff --git a/%s b/%s\n", name, name)
+	switch {
+	case oldPath == DevNull:
+		out.WriteString("new file mode 100644\n")
+	case newPath == DevNull:
+		out.WriteString("deleted file mode 100644\n")
+	}
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", prefixPath("a/", oldPath), prefixPath("b/", newPath))
+	for _, h := range hunks {
+		out.WriteString(h.String())
+	}
+	return out.String()
+}
+
+// prefixPath Adds the given prefix to the path, unless the path is DevNull.
+func prefixPath(prefix, path string) string {
+	if path == DevNull {
+		return path
+	}
+	return prefix + path
+}
@@ -0,0 +1,216 @@
+package diff_test
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/diff"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+// numbered Returns n lines of the form "line i".
+func numbered(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return lines
+}
+
+// lcs Returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] > lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+var _ = Describe("Diff", func() {
+	It("finds no differences between equal content", func() {
+		Expect(diff.Hunks("a\nb\n", "a\nb\n", diff.DefaultContext)).To(BeEmpty())
+		Expect(diff.Unified("f", "f", "", "")).To(BeEmpty())
+	})
+
+	It("computes the shortest edit script", func() {
+		script := diff.Lines("a\nb\nc\n", "a\nc\nd\n")
+		Expect(script).To(Equal([]diff.Line{
+			{Op: diff.Equal, Text: "a\n"},
+			{Op: diff.Delete, Text: "b\n"},
+			{Op: diff.Equal, Text: "c\n"},
+			{Op: diff.Insert, Text: "d\n"},
+		}))
+	})
+
+	It("finds a shortest edit script for any content", func() {
+		random := rand.New(rand.NewSource(1))
+		for i := 0; i < 500; i++ {
+			a, b := make([]string, random.Intn(30)), make([]string, random.Intn(30))
+			for j := range a {
+				a[j] = fmt.Sprintf("%d\n", random.Intn(4))
+			}
+			for j := range b {
+				b[j] = fmt.Sprintf("%d\n", random.Intn(4))
+			}
+
+			var old, updated []string
+			changes := 0
+			for _, line := range diff.Lines(strings.Join(a, ""), strings.Join(b, "")) {
+				if line.Op != diff.Insert {
+					old = append(old, line.Text)
+				}
+				if line.Op != diff.Delete {
+					updated = append(updated, line.Text)
+				}
+				if line.Op != diff.Equal {
+					changes++
+				}
+			}
+			Expect(strings.Join(old, "")).To(Equal(strings.Join(a, "")))
+			Expect(strings.Join(updated, "")).To(Equal(strings.Join(b, "")))
+			Expect(changes).To(Equal(len(a)+len(b)-2*lcs(a, b)), "%q -> %q", a, b)
+		}
+	})
+
+	It("diffs complete rewrites of large files in linear space", func() {
+		before := strings.Join(numbered(5000), "\n") + "\n"
+		after := strings.ReplaceAll(before, "line", "row")
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		allocated := stats.TotalAlloc
+
+		script := diff.Lines(before, after)
+		Expect(script).To(HaveLen(10000))
+		runtime.ReadMemStats(&stats)
+		Expect(stats.TotalAlloc - allocated).To(BeNumerically("<", 4<<20))
+	})
+
+	It("renders a unified diff with context", func() {
+		before := strings.Join(numbered(10), "\n") + "\n"
+		after := strings.Replace(before, "line 5\n", "line five\n", 1)
+		Expect(diff.Unified("app.yaml", "app.yaml", before, after)).To(Equal(`diff --git a/app.yaml b/app.yaml
+--- a/app.yaml
++++ b/app.yaml
+@@ -2,7 +2,7 @@
+ line 2
+ line 3
+ line 4
+-line 5
++line five
+ line 6
+ line 7
+ line 8
+`))
+	})
+
+	It("splits distant changes into separate hunks", func() {
+		lines := numbered(20)
+		before := strings.Join(lines, "\n") + "\n"
+		lines[1], lines[17] = "changed 2", "changed 18"
+		after := strings.Join(lines, "\n") + "\n"
+
+		hunks := diff.Hunks(before, after, diff.DefaultContext)
+		Expect(hunks).To(HaveLen(2))
+		Expect(hunks[0].Header()).To(Equal("@@ -1,5 +1,5 @@"))
+		Expect(hunks[1].Header()).To(Equal("@@ -15,6 +15,6 @@"))
+	})
+
+	It("marks files without a trailing newline", func() {
+		out := diff.Unified("f", "f", "a\n", "a\nb")
+		Expect(out).To(HaveSuffix("+b\n\\ No newline at end of file\n"))
+	})
+
+	It("diffs filemaps by path, treating new files as additions", func() {
+		before := filemap.NewFilemap()
+		before.Files["pvc"] = filemap.File{Path: "./app/pvc.yaml", Content: "storage: 1Gi\n"}
+		before.Files["ns"] = filemap.File{Path: "app/ns.yaml", Content: "kind: Namespace\n"}
+		after := before.Clone()
+		after.Files["pvc"] = filemap.File{Path: "./app/pvc.yaml", Content: "storage: 100Gi\n"}
+		after.Files["pod.yaml"] = filemap.File{Path: "app/pod.yaml", Content: "kind: Pod\n"}
+
+		out := diff.Filemaps(before, after)
+		Expect(out).To(ContainSubstring("--- a/app/pvc.yaml\n+++ b/app/pvc.yaml\n@@ -1 +1 @@\n-storage: 1Gi\n+storage: 100Gi\n"))
+		Expect(out).To(ContainSubstring("new file mode 100644\n--- /dev/null\n+++ b/app/pod.yaml\n@@ -0,0 +1 @@\n+kind: Pod\n"))
+		Expect(out).NotTo(ContainSubstring("ns.yaml"))
+		// files are ordered by path
+		Expect(strings.Index(out, "app/pod.yaml")).To(BeNumerically("<", strings.Index(out, "app/pvc.yaml")))
+
+		added, removed := diff.Stat(before, after)
+		Expect(added).To(Equal(2))
+		Expect(removed).To(Equal(1))
+	})
+
+	Describe("Apply", func() {
+		lines := numbered(20)
+		before := strings.Join(lines, "\n") + "\n"
+		changed := append([]string{}, lines...)
+		changed[1], changed[17] = "changed 2", "changed 18"
+		after := strings.Join(changed, "\n") + "\n"
+		hunks := diff.Hunks(before, after, diff.DefaultContext)
+
+		It("reproduces the new content when every hunk is applied", func() {
+			Expect(diff.Apply(before, hunks)).To(Equal(after))
+		})
+
+		It("keeps the original lines of hunks which are left out", func() {
+			out, err := diff.Apply(before, hunks[1:])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("line 2\n"))
+			Expect(out).To(ContainSubstring("changed 18\n"))
+		})
+
+		It("applies additions to empty content", func() {
+			Expect(diff.Apply("", diff.Hunks("", "a\nb", 3))).To(Equal("a\nb"))
+		})
+
+		It("rejects hunks which do not match the content", func() {
+			_, err := diff.Apply("something else\n", hunks)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ParseHunk", func() {
+		before := "a\nb\nc\n"
+		original := diff.Hunks(before, "a\nB\nc\n", 1)[0]
+
+		It("parses the hunk as rendered, ignoring comments", func() {
+			parsed, err := diff.ParseHunk("# comment\n"+original.String(), original)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed).To(Equal(original))
+		})
+
+		It("accepts edited added lines", func() {
+			parsed, err := diff.ParseHunk(" a\n-b\n+beta\n+gamma\n c\n", original)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.NewLines).To(Equal(4))
+			Expect(diff.Apply(before, []diff.Hunk{parsed})).To(Equal("a\nbeta\ngamma\nc\n"))
+		})
+
+		It("rejects edits which change the original lines", func() {
+			_, err := diff.ParseHunk(" a\n+B\n c\n", original)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects lines without a prefix", func() {
+			_, err := diff.ParseHunk(" a\n-b\nB\n c\n", original)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
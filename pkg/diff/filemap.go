@@ -0,0 +1,72 @@
+package diff
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+// Filemaps Renders a unified diff for every file in after whose content differs from
+// the file with the same path in before. Files missing from before are shown as additions.
+func Filemaps(before, after *filemap.Filemap) string {
+	original := ContentByPath(before)
+	updated := ContentByPath(after)
+
+	paths := make([]string, 0, len(updated))
+	for path := range updated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var out strings.Builder
+	for _, path := range paths {
+		oldPath := path
+		oldContent, ok := original[path]
+		if !ok {
+			oldPath = DevNull
+		}
+		out.WriteString(Unified(oldPath, path, oldContent, updated[path]))
+	}
+	return out.String()
+}
+
+// ContentByPath Returns the content of every file in the filemap keyed by its
+// cleaned, slash-separated path, falling back to the tag for files without a path.
+func ContentByPath(fm *filemap.Filemap) map[string]string {
+	contents := make(map[string]string)
+	if fm == nil {
+		return contents
+	}
+	for tag, file := range fm.Files {
+		contents[CleanPath(file.Path, tag)] = file.Content
+	}
+	return contents
+}
+
+// CleanPath Normalizes a file's path for use in a diff header.
+func CleanPath(path, tag string) string {
+	if path == "" {
+		path = tag
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// Stat Counts the lines added and removed across every file in after, compared
+// to the file with the same path in before.
+func Stat(before, after *filemap.Filemap) (added, removed int) {
+	original := ContentByPath(before)
+	for path, content := range ContentByPath(after) {
+		for _, line := range Lines(original[path], content) {
+			switch line.Op {
+			case Insert:
+				added++
+			case Delete:
+				removed++
+			case Equal:
+			}
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,363 @@
+// eval scores what a backend proposes for a dataset of issues against the files
+// expected after each issue, so that prompts and models can be compared objectively.
+//
+// A dataset holds one directory per example, laid out as data-lab/examples:
+//
+//	<example>/files/...                      the repo the issues apply to
+//	<example>/issues/<id>/issue.md           the issue, referencing files as `@name:path`
+//	<example>/issues/<id>/expected/<path>    the expected content of a file after the issue
+//
+// Issues without expected files are only scored on the validity of the proposed files.
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+	"github.com/redhat-et/copilot-ops/pkg/validate"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the files and directories of a dataset.
+const (
+	FilesDir    = "files"
+	IssuesDir   = "issues"
+	IssueFile   = "issue.md"
+	ExpectedDir = "expected"
+)
+
+// Case Is an issue of the dataset, along with the files expected after it.
+type Case struct {
+	// Name Is the example followed by the issue's ID, e.g. request-more-cpu/1.
+	Name string
+	// Root Is the directory holding the files of the example.
+	Root  string
+	Issue *issue.Issue
+	// Expected Holds the expected content of files, by their paths relative to Root.
+	Expected map[string]string
+}
+
+// Command Returns the command the case runs: issues which reference files edit
+// them, while the others generate new files.
+func (c *Case) Command() string {
+	if len(c.Issue.References) > 0 {
+		return chatops.CommandEdit
+	}
+	return chatops.CommandGenerate
+}
+
+// Files Returns the files referenced by the issue, tagged with their names.
+func (c *Case) Files() (*filemap.Filemap, error) {
+	fm := filemap.NewFilemap()
+	if err := c.Issue.LoadFilesFrom(fm, c.Root); err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// LoadDataset Returns the cases of the dataset, ordered by example and issue.
+func LoadDataset(dir string) ([]*Case, error) {
+	examples, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var cases []*Case
+	for _, example := range examples {
+		if !example.IsDir() {
+			continue
+		}
+		issues, readErr := os.ReadDir(filepath.Join(dir, example.Name(), IssuesDir))
+		if errors.Is(readErr, fs.ErrNotExist) {
+			continue
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+		sort.Slice(issues, func(i, j int) bool { return lessID(issues[i].Name(), issues[j].Name()) })
+		for _, id := range issues {
+			if !id.IsDir() {
+				continue
+			}
+			c, loadErr := loadCase(filepath.Join(dir, example.Name()), id.Name())
+			if loadErr != nil {
+				return nil, loadErr
+			}
+			cases = append(cases, c)
+		}
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no issues were found in %s", dir)
+	}
+	return cases, nil
+}
+
+// loadCase Loads the issue of the example with the given ID.
+func loadCase(exampleDir, id string) (*Case, error) {
+	name := filepath.Base(exampleDir) + "/" + id
+	issueDir := filepath.Join(exampleDir, IssuesDir, id)
+	body, err := os.ReadFile(filepath.Join(issueDir, IssueFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the issue of %s: %w", name, err)
+	}
+	iss, err := issue.Parse("", string(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	c := &Case{Name: name, Root: filepath.Join(exampleDir, FilesDir), Issue: iss, Expected: map[string]string{}}
+	expectedDir := filepath.Join(issueDir, ExpectedDir)
+	err = filepath.WalkDir(expectedDir, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil || entry.IsDir() {
+			return walkErr
+		}
+		content, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return readErr
+		}
+		rel, relErr := filepath.Rel(expectedDir, path)
+		if relErr != nil {
+			return relErr
+		}
+		c.Expected[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read the expected files of %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// lessID Orders numeric IDs by their value, and the others alphabetically after them.
+func lessID(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return x < y
+	case errA == nil || errB == nil:
+		return errA == nil
+	default:
+		return a < b
+	}
+}
+
+// Runner Runs the case's command on its files, returning the files afterwards.
+type Runner func(ctx context.Context, c *Case, files *filemap.Filemap) (*filemap.Filemap, error)
+
+// FileScore Tells whether a file matches its expected content.
+type FileScore struct {
+	Path string `json:"path"`
+	// Found Is false when the file was neither edited nor generated.
+	Found bool `json:"found"`
+	// Exact Is true when the content is the expected one, but for trailing whitespace.
+	Exact bool `json:"exact"`
+	// Semantic Is true when the content holds the same YAML documents as the expected one.
+	Semantic bool `json:"semantic"`
+}
+
+// Score Is how a case went.
+type Score struct {
+	Case    string `json:"case"`
+	Command string `json:"command"`
+	// Error Is set when the case could not be run, in which case it scores nothing.
+	Error string      `json:"error,omitempty"`
+	Files []FileScore `json:"files"`
+	// Changed Counts the files which were edited or generated.
+	Changed int `json:"changed"`
+	// Valid Is the fraction of the changed files which are valid.
+	Valid float64 `json:"valid"`
+	// Duration Is how long the case took, in seconds.
+	Duration float64 `json:"duration"`
+}
+
+// Summary Averages the scores of the cases.
+type Summary struct {
+	Cases  int `json:"cases"`
+	Errors int `json:"errors"`
+	// Expected Counts the expected files of every case.
+	Expected int `json:"expected"`
+	// Exact Is the fraction of the expected files which matched exactly, as is Semantic.
+	Exact    float64 `json:"exact"`
+	Semantic float64 `json:"semantic"`
+	// Valid Averages the validity of the cases which ran.
+	Valid float64 `json:"valid"`
+	// Unchanged Counts the cases which ran without changing any file.
+	Unchanged int `json:"unchanged"`
+}
+
+// Scorecard Holds the scores of every case, along with the settings which produced them.
+type Scorecard struct {
+	Backend string  `json:"backend"`
+	Model   string  `json:"model,omitempty"`
+	Scores  []Score `json:"scores"`
+	Summary Summary `json:"summary"`
+}
+
+// Evaluate Runs every case and scores it, until the context is done.
+func Evaluate(ctx context.Context, cases []*Case, run Runner) []Score {
+	scores := make([]Score, 0, len(cases))
+	for _, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		score := evaluate(ctx, c, run)
+		score.Duration = time.Since(start).Seconds()
+		scores = append(scores, score)
+	}
+	return scores
+}
+
+// evaluate Runs the case and scores it.
+func evaluate(ctx context.Context, c *Case, run Runner) Score {
+	// the expected files of a failed case count as missed, so that failing does not pay off
+	fail := func(err error) Score {
+		score := Score{Case: c.Name, Command: c.Command(), Error: err.Error(), Files: []FileScore{}}
+		for _, path := range c.ExpectedPaths() {
+			score.Files = append(score.Files, FileScore{Path: path})
+		}
+		return score
+	}
+	before, err := c.Files()
+	if err != nil {
+		return fail(err)
+	}
+	after, err := run(ctx, c, before.Clone())
+	if err != nil {
+		return fail(err)
+	}
+	return ScoreCase(c, before, after)
+}
+
+// ScoreCase Scores the files proposed for the case, given the files it started from.
+// Expected files are looked up by path, and generated files are also compared to
+// every expected file, since the paths they are given cannot be predicted.
+func ScoreCase(c *Case, before, after *filemap.Filemap) Score {
+	score := Score{Case: c.Name, Command: c.Command(), Files: []FileScore{}}
+
+	changed := filemap.NewFilemap()
+	contents := make(map[string]string)
+	for tag, file := range after.Files {
+		path := file.Path
+		if path == "" {
+			path = tag
+		}
+		path = filepath.ToSlash(filepath.Clean(path))
+		contents[path] = file.Content
+		if original, ok := before.Files[tag]; !ok || original.Content != file.Content {
+			changed.Files[tag] = file
+		}
+	}
+	score.Changed = len(changed.Files)
+	score.Valid = validate.PassRate(validate.Filemap(changed))
+
+	for _, path := range c.ExpectedPaths() {
+		expected := c.Expected[path]
+		fileScore := FileScore{Path: path}
+		candidates := []string{}
+		if content, ok := contents[path]; ok {
+			candidates = append(candidates, content)
+		} else if c.Command() == chatops.CommandGenerate {
+			for _, file := range changed.Files {
+				candidates = append(candidates, file.Content)
+			}
+		}
+		for _, content := range candidates {
+			fileScore.Found = true
+			fileScore.Exact = fileScore.Exact || ExactEqual(content, expected)
+			fileScore.Semantic = fileScore.Semantic || SemanticEqual(content, expected)
+		}
+		score.Files = append(score.Files, fileScore)
+	}
+	return score
+}
+
+// ExpectedPaths Returns the paths of the expected files of the case, sorted.
+func (c *Case) ExpectedPaths() []string {
+	paths := make([]string, 0, len(c.Expected))
+	for path := range c.Expected {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ExactEqual Returns true when the contents are the same, but for trailing whitespace.
+func ExactEqual(a, b string) bool {
+	return strings.TrimRight(a, " \t\r\n") == strings.TrimRight(b, " \t\r\n")
+}
+
+// SemanticEqual Returns true when both contents hold the same YAML documents, regardless
+// of formatting, comments, and the order of keys. Contents which are not YAML are never equal.
+func SemanticEqual(a, b string) bool {
+	x, errA := decodeDocuments(a)
+	y, errB := decodeDocuments(b)
+	return errA == nil && errB == nil && reflect.DeepEqual(x, y)
+}
+
+// decodeDocuments Decodes every YAML document of the content, skipping empty ones.
+func decodeDocuments(content string) ([]interface{}, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	var documents []interface{}
+	for {
+		var document interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if document != nil {
+			documents = append(documents, document)
+		}
+	}
+}
+
+// Summarize Averages the scores.
+func Summarize(scores []Score) Summary {
+	summary := Summary{Cases: len(scores)}
+	exact, semantic := 0, 0
+	ran := 0
+	for _, score := range scores {
+		summary.Expected += len(score.Files)
+		if score.Error != "" {
+			summary.Errors++
+			continue
+		}
+		ran++
+		summary.Valid += score.Valid
+		if score.Changed == 0 {
+			summary.Unchanged++
+		}
+		for _, file := range score.Files {
+			if file.Exact {
+				exact++
+			}
+			if file.Semantic {
+				semantic++
+			}
+		}
+	}
+	if ran > 0 {
+		summary.Valid /= float64(ran)
+	}
+	if summary.Expected > 0 {
+		summary.Exact = float64(exact) / float64(summary.Expected)
+		summary.Semantic = float64(semantic) / float64(summary.Expected)
+	}
+	return summary
+}
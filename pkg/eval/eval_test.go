@@ -0,0 +1,150 @@
+package eval_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/chatops"
+	"github.com/redhat-et/copilot-ops/pkg/eval"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+const quotaPath = "cluster-scope/base/core/namespaces/training-model/resourcequota.yaml"
+
+var _ = Describe("Eval", func() {
+	It("loads the issues of the examples, along with their expected files", func() {
+		cases, err := eval.LoadDataset(filepath.Join("..", "..", "data-lab", "examples"))
+		Expect(err).NotTo(HaveOccurred())
+
+		names := []string{}
+		for _, c := range cases {
+			names = append(names, c.Name)
+		}
+		Expect(names).To(Equal([]string{
+			"request-more-cpu/1", "request-more-cpu/2",
+			"simple-prometheus-update/1", "simple-prometheus-update/2", "simple-prometheus-update/3",
+			"update-smaug/1",
+		}))
+		Expect(cases[0].Command()).To(Equal(chatops.CommandEdit))
+		Expect(cases[0].Expected).To(HaveKeyWithValue(quotaPath, ContainSubstring("128Gi")))
+		Expect(cases[5].Command()).To(Equal(chatops.CommandGenerate))
+
+		files, err := cases[0].Files()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files.Files).To(HaveKeyWithValue("resourcequota", HaveField("Path", quotaPath)))
+	})
+
+	It("fails on an empty dataset", func() {
+		_, err := eval.LoadDataset(GinkgoT().TempDir())
+		Expect(err).To(MatchError(ContainSubstring("no issues were found")))
+	})
+
+	It("compares contents exactly and as YAML documents", func() {
+		Expect(eval.ExactEqual("kind: Pod\n", "kind: Pod\n\n")).To(BeTrue())
+		Expect(eval.ExactEqual("kind: Pod\n", "kind:  Pod\n")).To(BeFalse())
+
+		Expect(eval.SemanticEqual("a: 1\nb: [x]\n", "# comment\nb:\n  - x\na: 1\n")).To(BeTrue())
+		Expect(eval.SemanticEqual("---\na: 1\n---\nb: 2\n", "a: 1\n---\nb: 2\n")).To(BeTrue())
+		Expect(eval.SemanticEqual("a: 1\n", "a: '1'\n")).To(BeFalse())
+		Expect(eval.SemanticEqual("a: [\n", "a: [\n")).To(BeFalse())
+	})
+
+	Context("scoring cases", func() {
+		var dataset string
+
+		BeforeEach(func() {
+			dataset = GinkgoT().TempDir()
+			write := func(path, content string) {
+				path = filepath.Join(dataset, path)
+				Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+				Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+			}
+			write("app/files/pod.yaml", "kind: Pod\nmetadata:\n  name: cats\n")
+			write("app/issues/1/issue.md", "Rename `@pod:pod.yaml` to dogs")
+			write("app/issues/1/expected/pod.yaml", "kind: Pod\nmetadata:\n  name: dogs\n")
+			write("app/issues/2/issue.md", "Add a service")
+			write("app/issues/2/expected/service.yaml", "kind: Service\n")
+			write("app/issues/10/issue.md", "Break `@pod:pod.yaml`")
+		})
+
+		It("scores the files proposed for every case", func() {
+			cases, err := eval.LoadDataset(dataset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cases).To(HaveLen(3))
+
+			run := func(_ context.Context, c *eval.Case, files *filemap.Filemap) (*filemap.Filemap, error) {
+				switch c.Name {
+				case "app/1":
+					pod := files.Files["pod"]
+					pod.Content = "kind: Pod\nmetadata: {name: dogs}\n"
+					files.Files["pod"] = pod
+					return files, nil
+				case "app/2":
+					fm := filemap.NewFilemap()
+					fm.Files["svc"] = filemap.File{Path: "svc.yaml", Content: "kind: Service\n"}
+					return fm, nil
+				}
+				return nil, errors.New("the backend is down")
+			}
+
+			scores := eval.Evaluate(context.Background(), cases, run)
+			Expect(scores).To(HaveLen(3))
+			Expect(scores[0].Command).To(Equal(chatops.CommandEdit))
+			Expect(scores[0].Files).To(Equal([]eval.FileScore{{Path: "pod.yaml", Found: true, Semantic: true}}))
+			Expect(scores[0].Changed).To(Equal(1))
+			Expect(scores[0].Valid).To(Equal(1.0))
+			Expect(scores[1].Command).To(Equal(chatops.CommandGenerate))
+			Expect(scores[1].Files).To(Equal([]eval.FileScore{
+				{Path: "service.yaml", Found: true, Exact: true, Semantic: true},
+			}))
+			Expect(scores[2].Case).To(Equal("app/10"))
+			Expect(scores[2].Error).To(Equal("the backend is down"))
+
+			summary := eval.Summarize(scores)
+			Expect(summary).To(Equal(eval.Summary{
+				Cases: 3, Errors: 1, Expected: 2, Exact: 0.5, Semantic: 1, Valid: 1,
+			}))
+		})
+
+		It("counts the expected files of the failed cases as missed", func() {
+			cases, err := eval.LoadDataset(dataset)
+			Expect(err).NotTo(HaveOccurred())
+			run := func(_ context.Context, c *eval.Case, files *filemap.Filemap) (*filemap.Filemap, error) {
+				if c.Name == "app/2" {
+					fm := filemap.NewFilemap()
+					fm.Files["svc"] = filemap.File{Path: "service.yaml", Content: "kind: Service\n"}
+					return fm, nil
+				}
+				return nil, errors.New("the backend is down")
+			}
+
+			scores := eval.Evaluate(context.Background(), cases[:2], run)
+			Expect(scores[0].Error).To(Equal("the backend is down"))
+			Expect(scores[0].Files).To(Equal([]eval.FileScore{{Path: "pod.yaml"}}))
+			Expect(eval.Summarize(scores)).To(Equal(eval.Summary{
+				Cases: 2, Errors: 1, Expected: 2, Exact: 0.5, Semantic: 0.5, Valid: 1,
+			}))
+		})
+
+		It("scores invalid and missing files", func() {
+			cases, err := eval.LoadDataset(dataset)
+			Expect(err).NotTo(HaveOccurred())
+			c := cases[0]
+
+			before, err := c.Files()
+			Expect(err).NotTo(HaveOccurred())
+			after := filemap.NewFilemap()
+			after.Files["other"] = filemap.File{Path: "other.yaml", Content: "kind: [\n"}
+
+			score := eval.ScoreCase(c, before, after)
+			Expect(score.Files).To(Equal([]eval.FileScore{{Path: "pod.yaml"}}))
+			Expect(score.Changed).To(Equal(1))
+			Expect(score.Valid).To(Equal(0.0))
+		})
+	})
+})
@@ -0,0 +1,142 @@
+// explain asks a model for a structured explanation of a set of manifests,
+// and renders the explanation for humans.
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/utils"
+)
+
+// SystemPrompt Instructs the model to answer with an explanation matching the Explanation type.
+const SystemPrompt = `You are an expert in Kubernetes, Kustomize, Helm and infrastructure-as-code,
+explaining unfamiliar configuration to engineers who are new to a repository.
+Respond only with a JSON object of the following form, without any other text:
+
+{
+  "summary": "one or two sentences describing what the files do together",
+  "purpose": "why these files exist and how they are meant to be used",
+  "resources": [
+    {"file": "tag of the file", "kind": "Deployment", "name": "name", "namespace": "namespace", "description": "what it does"}
+  ],
+  "crossReferences": [
+    {"from": "tag or resource", "to": "tag or resource", "description": "how they are related"}
+  ],
+  "risks": [
+    {"severity": "low|medium|high", "file": "tag of the file", "description": "what could go wrong"}
+  ]
+}`
+
+// Explanation Is the structured explanation of a set of files.
+type Explanation struct {
+	Summary         string           `json:"summary"`
+	Purpose         string           `json:"purpose"`
+	Resources       []Resource       `json:"resources"`
+	CrossReferences []CrossReference `json:"crossReferences"`
+	Risks           []Risk           `json:"risks"`
+}
+
+// Resource Describes a single resource defined in one of the files.
+type Resource struct {
+	File        string `json:"file"`
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace,omitempty"`
+	Description string `json:"description"`
+}
+
+// CrossReference Describes how two files or resources relate to each other.
+type CrossReference struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Description string `json:"description"`
+}
+
+// Risk Describes something that could go wrong with the files as they are.
+type Risk struct {
+	Severity    string `json:"severity"`
+	File        string `json:"file,omitempty"`
+	Description string `json:"description"`
+}
+
+// PrepareMessages Builds the conversation which asks the model to explain the files.
+func PrepareMessages(fm *filemap.Filemap, question string) []ai.Message {
+	content := fmt.Sprintf(
+		"Explain the following files. Each file begins with a '# %stagname' line, and files are separated by '%s'.\n\n%s",
+		filemap.FileTagPrefix, filemap.FileDelimeter, fm.EncodeToInputText(),
+	)
+	if strings.TrimSpace(question) != "" {
+		content += "\n\nPay particular attention to the following: " + question
+	}
+	return []ai.Message{
+		{Role: ai.RoleSystem, Content: SystemPrompt},
+		{Role: ai.RoleUser, Content: content},
+	}
+}
+
+// Parse Decodes the explanation contained in the model's answer.
+func Parse(answer string) (*Explanation, error) {
+	object, err := utils.ExtractJSON(answer)
+	if err != nil {
+		return nil, err
+	}
+	e := &Explanation{}
+	if err = json.Unmarshal([]byte(object), e); err != nil {
+		return nil, fmt.Errorf("could not parse explanation: %w", err)
+	}
+	return e, nil
+}
+
+// Markdown Renders the explanation as a Markdown document.
+func (e *Explanation) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Explanation\n\n")
+	if e.Summary != "" {
+		b.WriteString(e.Summary + "\n\n")
+	}
+	if e.Purpose != "" {
+		b.WriteString("## Purpose\n\n" + e.Purpose + "\n\n")
+	}
+
+	if len(e.Resources) > 0 {
+		b.WriteString("## Resources\n\n")
+		b.WriteString("| File | Kind | Name | Namespace | Description |\n")
+		b.WriteString("|------|------|------|-----------|-------------|\n")
+		for _, r := range e.Resources {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				cell(r.File), cell(r.Kind), cell(r.Name), cell(r.Namespace), cell(r.Description))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(e.CrossReferences) > 0 {
+		b.WriteString("## Cross-references\n\n")
+		for _, ref := range e.CrossReferences {
+			fmt.Fprintf(&b, "- `%s` → `%s`: %s\n", ref.From, ref.To, ref.Description)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(e.Risks) > 0 {
+		b.WriteString("## Risks\n\n")
+		for _, risk := range e.Risks {
+			location := ""
+			if risk.File != "" {
+				location = fmt.Sprintf(" (`%s`)", risk.File)
+			}
+			fmt.Fprintf(&b, "- **%s**%s: %s\n", strings.ToLower(risk.Severity), location, risk.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// cell Escapes a value so that it can be placed in a Markdown table.
+func cell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", " ")
+}
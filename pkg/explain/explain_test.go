@@ -0,0 +1,59 @@
+package explain_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/explain"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+var _ = Describe("Explain", func() {
+	const answer = "Sure! Here is the explanation:\n```json\n" + `{
+  "summary": "Grants the dev group access to the cluster.",
+  "purpose": "Overlay for the stale dev environment.",
+  "resources": [
+    {"file": "group-user_patch.yaml", "kind": "Group", "name": "dev", "description": "adds users | admins"}
+  ],
+  "crossReferences": [
+    {"from": "kustomization.yaml", "to": "group-user_patch.yaml", "description": "applies the patch"}
+  ],
+  "risks": [
+    {"severity": "HIGH", "file": "group-user_patch.yaml", "description": "users are hard-coded"}
+  ]
+}` + "\n```"
+
+	It("sends the encoded files to the model", func() {
+		fm := filemap.NewFilemap()
+		fm.Files["kustomization.yaml"] = filemap.File{Path: "overlay/kustomization.yaml", Content: "kind: Kustomization"}
+		messages := explain.PrepareMessages(fm, "who is in the group?")
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[0].Role).To(Equal(ai.RoleSystem))
+		Expect(messages[1].Content).To(ContainSubstring("# @kustomization.yaml"))
+		Expect(messages[1].Content).To(ContainSubstring("who is in the group?"))
+	})
+
+	It("parses explanations surrounded by prose", func() {
+		e, err := explain.Parse(answer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e.Summary).To(ContainSubstring("dev group"))
+		Expect(e.Resources).To(HaveLen(1))
+		Expect(e.Risks[0].Severity).To(Equal("HIGH"))
+	})
+
+	It("rejects answers without an explanation", func() {
+		_, err := explain.Parse("I cannot explain these files.")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("renders markdown", func() {
+		e, err := explain.Parse(answer)
+		Expect(err).NotTo(HaveOccurred())
+		md := e.Markdown()
+		Expect(md).To(ContainSubstring("## Resources"))
+		Expect(md).To(ContainSubstring(`adds users \| admins`))
+		Expect(md).To(ContainSubstring("`kustomization.yaml` → `group-user_patch.yaml`"))
+		Expect(md).To(ContainSubstring("- **high** (`group-user_patch.yaml`): users are hard-coded"))
+	})
+})
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
@@ -35,6 +36,8 @@ type File struct {
 	Path string `json:"path"`
 	// Content is the content of the file.
 	Content string `json:"content"`
+	// Rev is the git revision the content was read at, when it was not read from the working tree.
+	Rev string `json:"rev,omitempty"`
 }
 
 // Filemap represents a mapping of files in a directory by their tagnames.
@@ -49,6 +52,25 @@ func NewFilemap() *Filemap {
 	}
 }
 
+// Clone Returns a copy of the filemap which can be modified independently.
+func (fm *Filemap) Clone() *Filemap {
+	clone := NewFilemap()
+	for tag, file := range fm.Files {
+		clone.Files[tag] = file
+	}
+	return clone
+}
+
+// Tags Returns the tags of the files in the filemap in sorted order.
+func (fm *Filemap) Tags() []string {
+	tags := make([]string, 0, len(fm.Files))
+	for tag := range fm.Files {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
 // LogDump Displays the contents of the filemap to the log.
 func (fm *Filemap) LogDump() {
 	maxShown := 30
@@ -65,11 +87,46 @@ func (fm *Filemap) LogDump() {
 
 // LoadFilesFromGlob reads files into the filemap from the given glob pattern.
 func (fm *Filemap) LoadFile(path string) error {
-	tag := filepath.Base(path)
+	if fm.HasPath(path) {
+		return nil
+	}
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
+	return fm.AddFile(path, string(bytes))
+}
+
+// HasPath Returns true when a file of the filemap is at the given path, however
+// either path is spelled, e.g. relative to the working directory or absolute.
+// Files read at a git revision are not the ones in the working tree, so they do not count.
+func (fm *Filemap) HasPath(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = filepath.Clean(path)
+	}
+	for _, file := range fm.Files {
+		if file.Rev != "" {
+			continue
+		}
+		fileAbs, fileErr := filepath.Abs(file.Path)
+		if fileErr != nil {
+			fileAbs = filepath.Clean(file.Path)
+		}
+		if fileAbs == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFile Adds the content of the file at the given path, tagged with the file's name.
+// Files matched by several globs, filesets or selectors are only sent once.
+func (fm *Filemap) AddFile(path, content string) error {
+	if fm.HasPath(path) {
+		return nil
+	}
+	tag := filepath.Base(path)
 	if _, ok := fm.Files[tag]; ok {
 		tag = fmt.Sprintf("%s#%d", tag, len(fm.Files))
 		if _, ok = fm.Files[tag]; ok {
@@ -78,11 +135,55 @@ func (fm *Filemap) LoadFile(path string) error {
 	}
 	fm.Files[tag] = File{
 		Path:    path,
-		Content: string(bytes),
+		Content: content,
 	}
 	return nil
 }
 
+// ErrOutsideRoot Is returned for the paths which requests confined to a repo may not read.
+var ErrOutsideRoot = errors.New("only files within the repo may be loaded")
+
+// WithinRoot Returns the path cleaned and relative to root when it names a file which a
+// request confined to the repo may read, e.g. one made on behalf of the author of an issue.
+// Absolute paths, paths which leave root once symlinks are resolved, and the files of git
+// and copilot-ops, such as the local config holding API keys, are refused.
+func WithinRoot(root, path string) (string, error) {
+	clean := filepath.Clean(path)
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || outside(clean) || private(clean) {
+		return "", fmt.Errorf("%q: %w", path, ErrOutsideRoot)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := filepath.EvalSymlinks(filepath.Join(realRoot, clean))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(realRoot, realPath)
+	if err != nil || outside(rel) || private(rel) {
+		return "", fmt.Errorf("%q: %w", path, ErrOutsideRoot)
+	}
+	return clean, nil
+}
+
+// outside Returns true when the relative path leaves the directory it is relative to.
+func outside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// private Returns true when the relative path is within the files of git or copilot-ops,
+// i.e. its config files, which may hold secrets, and its state. Names are compared
+// regardless of case, as file systems such as macOS' do.
+func private(rel string) bool {
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if strings.EqualFold(name, ".git") || strings.HasPrefix(strings.ToLower(name), config.ConfigName) {
+			return true
+		}
+	}
+	return false
+}
+
 // LoadFilesFromGlob reads files into the filemap from the given glob pattern.
 func (fm *Filemap) LoadFilesFromGlob(glob string) error {
 	matches, err := filepath.Glob(glob)
@@ -121,7 +222,7 @@ func (fm *Filemap) WriteUpdatesToFiles() error {
 
 		// write the file at the given path with read write permissions for user, read-only for others
 		log.Printf("writing to file %q\n", file.Path)
-		f, err := os.OpenFile(file.Path, os.O_RDWR|os.O_CREATE, 0644)
+		f, err := os.OpenFile(file.Path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 		if err != nil {
 			return err
 		}
@@ -163,8 +264,9 @@ func (fm *Filemap) EncodeToInputText() string {
 	*/
 	var input = ""
 	var i int
-	// join the files together along with their tag
-	for tagname, file := range fm.Files {
+	// join the files together along with their tag, in a stable order
+	for _, tagname := range fm.Tags() {
+		file := fm.Files[tagname]
 		input += fmt.Sprintf("# %s%s\n%s\n", FileTagPrefix, tagname, file.Content)
 		// insert a delimeter between each file, but not after the last file
 		if 1 < len(fm.Files) && i < len(fm.Files)-1 {
@@ -182,7 +284,8 @@ func (fm *Filemap) EncodeToInputTextFullPaths(outputType string) (string, error)
 	var genFiles []File
 
 	// join the files together along with their tag
-	for _, file := range fm.Files {
+	for _, tagname := range fm.Tags() {
+		file := fm.Files[tagname]
 		genFiles = append(genFiles, file)
 		input += fmt.Sprintf("# %s%s\n%s\n", FileTagPrefix, file.Path, file.Content)
 		// insert a delimeter between each file, but not after the last file
@@ -236,20 +339,43 @@ func ConcatenateAfterLineNum(content string, lineNum int32) (string, error) {
 
 // AddContentByTag Adds the given content to the filemap, using the given tagname.
 // If the tagname already exists, the content will be appended to the existing content.
-// If the tagname does not exist, the content will be added as a new file.
-func (fm *Filemap) AddContentByTag(tagname string, content string) {
+// If the tagname does not exist, the content will be added as a new file, at the path
+// given by the tagname, which must stay within the working directory.
+func (fm *Filemap) AddContentByTag(tagname string, content string) error {
 	// check if the tagname already exists
 	if existingFile, ok := fm.Files[tagname]; ok {
 		existingFile.Content = content
 		fm.Files[tagname] = existingFile
-	} else {
-		// if the tagname doesn't exist, add it as a new file
-		fm.Files[tagname] = File{
-			// TODO: infer path
-			Path:    "",
-			Content: content,
-		}
+		return nil
+	}
+	// if the tagname doesn't exist, add it as a new file
+	// new files are assumed to be tagged by their path
+	path, err := NewFilePath(tagname)
+	if err != nil {
+		return err
+	}
+	fm.Files[tagname] = File{
+		Path:    path,
+		Content: content,
+	}
+	return nil
+}
+
+// NewFilePath Returns the path at which a new file tagged by the model is written,
+// refusing absolute paths, paths which leave the working directory, and the files of
+// git and copilot-ops, since the model's output must not decide to write anywhere else.
+func NewFilePath(tagname string) (string, error) {
+	path := filepath.Clean(filepath.FromSlash(tagname))
+	if filepath.IsAbs(path) || strings.HasPrefix(tagname, "/") || filepath.VolumeName(path) != "" {
+		return "", fmt.Errorf("refusing to create %q: new files need a relative path", tagname)
+	}
+	if path == "." || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to create %q: new files must stay within the working directory", tagname)
+	}
+	if private(path) {
+		return "", fmt.Errorf("refusing to create %q: new files may not be among the files of git or copilot-ops", tagname)
 	}
+	return path, nil
 }
 
 // DecodeFromOutput Decodes the given content and updates the filemap with the decoded content.
@@ -285,7 +411,9 @@ func (fm *Filemap) DecodeFromOutput(content string) error {
 		}
 
 		// add to the filemap
-		fm.AddContentByTag(tagName, concatenatedContent)
+		if err = fm.AddContentByTag(tagName, concatenatedContent); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -302,6 +430,55 @@ func (fm *Filemap) LoadFiles(files []string) error {
 	return nil
 }
 
+// LoadFilesWithin Loads the files matching the globs as LoadFiles does, confining them
+// to the repo at root as WithinRoot does. Matches of patterns among the files of git and
+// copilot-ops are skipped, so that e.g. `*` does not load the config, while globs which
+// leave root and files which name those files outright are refused.
+func (fm *Filemap) LoadFilesWithin(root string, globs []string) error {
+	for _, glob := range globs {
+		clean := filepath.Clean(glob)
+		if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || outside(clean) {
+			return fmt.Errorf("%q: %w", glob, ErrOutsideRoot)
+		}
+		matches, err := filepath.Glob(filepath.Join(root, clean))
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			rel, relErr := filepath.Rel(root, match)
+			if relErr != nil {
+				return relErr
+			}
+			if private(rel) && rel != clean {
+				continue
+			}
+			path, withinErr := WithinRoot(root, rel)
+			if withinErr != nil {
+				return withinErr
+			}
+			if err = fm.LoadFile(filepath.Join(root, path)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFilesetsWithin Loads the filesets as LoadFilesets does, confining their files to the
+// repo at root as LoadFilesWithin does.
+func (fm *Filemap) LoadFilesetsWithin(root string, filesets []string, conf config.Config, configFile string) error {
+	for _, name := range filesets {
+		fileset := conf.FindFileset(name)
+		if fileset == nil {
+			return fmt.Errorf("fileset %s not found in %s", name, configFile)
+		}
+		if err := fm.LoadFilesWithin(root, fileset.Files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // LoadFilesets Attempts to populate the filemap from the given filesets.
 func (fm *Filemap) LoadFilesets(filesets []string, conf config.Config, configFile string) error {
 	for _, name := range filesets {
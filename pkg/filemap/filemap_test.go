@@ -2,6 +2,9 @@ package filemap_test
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -52,7 +55,7 @@ kind: CatVideos
 
 		It("adds new files", func() {
 			// make sure the new content is added and is encoded
-			filemap.AddContentByTag("new_tag", "new_content")
+			Expect(filemap.AddContentByTag("new_tag", "new_content")).To(Succeed())
 			encoding := filemap.EncodeToInputText()
 			Expect(encoding).To(ContainSubstring("new_content"))
 			Expect(encoding).To(ContainSubstring(FileDelimeter))
@@ -60,14 +63,29 @@ kind: CatVideos
 
 		It("updates existing files by their tagname", func() {
 			// update the fortnite vods file with content
-			filemap.AddContentByTag("fortnite_vods", "new-fortnite-content")
+			Expect(filemap.AddContentByTag("fortnite_vods", "new-fortnite-content")).To(Succeed())
 			Expect(filemap.Files["fortnite_vods"].Content).To(ContainSubstring("new-fortnite-content"))
 
 			// make sure that content with new tags are simply appended to the filemap
-			filemap.AddContentByTag("new_tag", "new_content")
+			Expect(filemap.AddContentByTag("new_tag", "new_content")).To(Succeed())
 			Expect(filemap.Files["new_tag"].Content).To(ContainSubstring("new_content"))
 		})
 
+		It("refuses new files outside of the working directory", func() {
+			for _, tag := range []string{
+				"../../.bashrc", "/etc/cron.d/x", "app/../../x", "..",
+				".git/config", "app/.GIT/hooks/pre-commit", ".copilot-ops.local.yaml", ".copilot-ops/history/x.json",
+			} {
+				Expect(filemap.AddContentByTag(tag, "content")).To(MatchError(ContainSubstring("refusing to create")), tag)
+				Expect(filemap.Files).NotTo(HaveKey(tag))
+			}
+			response := fmt.Sprintf("# %s../.bashrc\necho pwned\n", FileTagPrefix)
+			Expect(filemap.DecodeFromOutput(response)).To(MatchError(ContainSubstring("refusing to create")))
+
+			Expect(filemap.AddContentByTag("app/./new.yaml", "kind: Pod")).To(Succeed())
+			Expect(filemap.Files["app/./new.yaml"].Path).To(Equal(filepath.Join("app", "new.yaml")))
+		})
+
 		When("the filemap is encoded to output text", func() {
 			It("encodes files using their full paths", func() {
 				output, err := filemap.EncodeToInputTextFullPaths(OutputPlain)
@@ -181,4 +199,34 @@ kind: NotEmtpy`, FileTagPrefix, FileDelimeter, FileTagPrefix)
 			Expect(listing.Content).To(ContainSubstring("kind: NotEmtpy"))
 		})
 	})
+
+	When("files are loaded from overlapping globs", func() {
+		var dir string
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			for _, name := range []string{"pvc.yaml", "pod.yaml"} {
+				Expect(os.WriteFile(filepath.Join(dir, name), []byte("kind: "+name+"\n"), 0600)).To(Succeed())
+			}
+		})
+
+		It("loads every file once", func() {
+			err := filemap.LoadFiles([]string{filepath.Join(dir, "pvc.yaml"), filepath.Join(dir, "*.yaml")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filemap.Files).To(HaveLen(2))
+			Expect(filemap.Tags()).To(Equal([]string{"pod.yaml", "pvc.yaml"}))
+		})
+
+		It("encodes the files in a stable order and decodes changes across them", func() {
+			Expect(filemap.LoadFiles([]string{filepath.Join(dir, "*.yaml")})).To(Succeed())
+			encoding := filemap.EncodeToInputText()
+			Expect(encoding).To(Equal(fmt.Sprintf("# %spod.yaml\nkind: pod.yaml\n\n%s\n# %spvc.yaml\nkind: pvc.yaml\n\n",
+				FileTagPrefix, FileDelimeter, FileTagPrefix)))
+
+			edited := strings.ReplaceAll(encoding, "kind: ", "kind: Edited ")
+			Expect(filemap.DecodeFromOutput(edited)).To(Succeed())
+			Expect(filemap.Files["pod.yaml"].Content).To(Equal("kind: Edited pod.yaml\n"))
+			Expect(filemap.Files["pvc.yaml"].Content).To(Equal("kind: Edited pvc.yaml\n"))
+			Expect(filemap.Files["pvc.yaml"].Path).To(Equal(filepath.Join(dir, "pvc.yaml")))
+		})
+	})
 })
@@ -0,0 +1,98 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Define how long the JWTs of an app are valid. GitHub rejects JWTs which expire
+// more than 10 minutes in the future, and issued-at is backdated to allow for clock drift.
+const (
+	JWTLifetime   = 9 * time.Minute
+	JWTClockDrift = time.Minute
+)
+
+// App Authenticates as a GitHub App, which acts on the repos it is installed in
+// through short-lived installation tokens.
+type App struct {
+	ID      int64
+	Key     *rsa.PrivateKey
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// ParsePrivateKey Parses the PEM encoded private key downloaded from the app's settings.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("the app's private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the app's private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the app's private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// JWT Returns the token which authenticates as the app itself, signed with RS256.
+func (a *App) JWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-JWTClockDrift).Unix(),
+		"exp": now.Add(JWTLifetime).Unix(),
+		"iss": strconv.FormatInt(a.ID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationClient Returns a client authenticated with a new token of the installation.
+func (a *App) InstallationClient(ctx context.Context, installationID int64) (*Client, error) {
+	jwt, err := a.JWT(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("could not sign the app's token: %w", err)
+	}
+	appClient := NewClient(a.BaseURL, jwt)
+	appClient.HTTP = a.HTTP
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", installationID)
+	if err = appClient.do(ctx, http.MethodPost, path, nil, &token); err != nil {
+		return nil, fmt.Errorf("could not create an installation token: %w", err)
+	}
+	client := NewClient(a.BaseURL, token.Token)
+	client.HTTP = a.HTTP
+	return client, nil
+}
@@ -0,0 +1,211 @@
+// github is a small client for the parts of the GitHub REST API used by the bot:
+// reading branches, committing files through the git data API, and opening pull requests.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Define the values used to talk to the GitHub API.
+const (
+	// DefaultBaseURL Is the URL of the public GitHub API. GitHub Enterprise serves it under /api/v3.
+	DefaultBaseURL = "https://api.github.com"
+	// MediaType Is the media type requested from the API.
+	MediaType = "application/vnd.github+json"
+	// FileMode Is the mode of the regular files committed through the API.
+	FileMode = "100644"
+)
+
+// Client Sends authenticated requests to the GitHub REST API.
+type Client struct {
+	// BaseURL Is the root of the API, without a trailing slash.
+	BaseURL string
+	// Token Is sent as a bearer token, it can be a personal access token or an installation token.
+	Token string
+	// HTTP Is the client used to send requests, http.DefaultClient when nil.
+	HTTP *http.Client
+}
+
+// NewClient Returns a client for the API at baseURL, which defaults to DefaultBaseURL.
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// APIError Is returned when the API responds with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github: %d %s", e.StatusCode, e.Message)
+}
+
+// IsStatus Reports whether err is an API error with the given status code.
+func IsStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// Ref Is a git reference such as a branch.
+type Ref struct {
+	Ref    string `json:"ref"`
+	Object struct {
+		SHA string `json:"sha"`
+	} `json:"object"`
+}
+
+// Commit Is a git commit created or read through the git data API.
+type Commit struct {
+	SHA  string `json:"sha"`
+	Tree struct {
+		SHA string `json:"sha"`
+	} `json:"tree"`
+}
+
+// TreeEntry Is a file written to a new tree. Content is stored as a new blob.
+type TreeEntry struct {
+	Path    string `json:"path"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// PullRequest Is a pull request, as sent when creating one and as returned by the API.
+type PullRequest struct {
+	Number  int    `json:"number,omitempty"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Head    string `json:"head,omitempty"`
+	Base    string `json:"base,omitempty"`
+	HTMLURL string `json:"html_url,omitempty"`
+}
+
+// do Sends a request with a JSON body to the API, and decodes the response into v.
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", MediaType)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{StatusCode: res.StatusCode}
+		// the message is informative only, so a body which is not JSON is not an error
+		_ = json.NewDecoder(res.Body).Decode(apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(res.StatusCode)
+		}
+		return apiErr
+	}
+	if v != nil {
+		return json.NewDecoder(res.Body).Decode(v)
+	}
+	return nil
+}
+
+// repoPath Returns the API path of a repository resource.
+func repoPath(owner, repo, format string, args ...interface{}) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo)) + fmt.Sprintf(format, args...)
+}
+
+// GetBranch Returns the reference of the branch.
+func (c *Client) GetBranch(ctx context.Context, owner, repo, branch string) (*Ref, error) {
+	ref := &Ref{}
+	return ref, c.do(ctx, http.MethodGet, repoPath(owner, repo, "/git/ref/heads/%s", branch), nil, ref)
+}
+
+// GetCommit Returns the commit with the given SHA.
+func (c *Client) GetCommit(ctx context.Context, owner, repo, sha string) (*Commit, error) {
+	commit := &Commit{}
+	return commit, c.do(ctx, http.MethodGet, repoPath(owner, repo, "/git/commits/%s", sha), nil, commit)
+}
+
+// CreateTree Creates a tree which has the entries on top of the base tree, returning its SHA.
+func (c *Client) CreateTree(ctx context.Context, owner, repo, baseTree string, entries []TreeEntry) (string, error) {
+	body := map[string]interface{}{"base_tree": baseTree, "tree": entries}
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.do(ctx, http.MethodPost, repoPath(owner, repo, "/git/trees"), body, &tree); err != nil {
+		return "", err
+	}
+	return tree.SHA, nil
+}
+
+// CreateCommit Creates a commit of the tree whose parent is the given commit.
+func (c *Client) CreateCommit(ctx context.Context, owner, repo, message, tree, parent string) (*Commit, error) {
+	body := map[string]interface{}{"message": message, "tree": tree, "parents": []string{parent}}
+	commit := &Commit{}
+	return commit, c.do(ctx, http.MethodPost, repoPath(owner, repo, "/git/commits"), body, commit)
+}
+
+// CreateBranch Creates a branch pointing to the commit. The API responds with
+// http.StatusUnprocessableEntity when the branch already exists.
+func (c *Client) CreateBranch(ctx context.Context, owner, repo, branch, sha string) error {
+	body := map[string]string{"ref": "refs/heads/" + branch, "sha": sha}
+	return c.do(ctx, http.MethodPost, repoPath(owner, repo, "/git/refs"), body, nil)
+}
+
+// UpdateBranch Points an existing branch to the commit, even if it is not a descendant.
+func (c *Client) UpdateBranch(ctx context.Context, owner, repo, branch, sha string) error {
+	body := map[string]interface{}{"sha": sha, "force": true}
+	return c.do(ctx, http.MethodPatch, repoPath(owner, repo, "/git/refs/heads/%s", branch), body, nil)
+}
+
+// CreatePullRequest Opens a pull request of head into base.
+func (c *Client) CreatePullRequest(ctx context.Context, owner, repo string, pr PullRequest) (*PullRequest, error) {
+	created := &PullRequest{}
+	return created, c.do(ctx, http.MethodPost, repoPath(owner, repo, "/pulls"), pr, created)
+}
+
+// ListPullRequests Returns the open pull requests whose head is the branch.
+func (c *Client) ListPullRequests(ctx context.Context, owner, repo, branch string) ([]PullRequest, error) {
+	query := url.Values{"head": {owner + ":" + branch}, "state": {"open"}}
+	var prs []PullRequest
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo, "/pulls?%s", query.Encode()), nil, &prs); err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// CreateComment Comments on an issue or pull request.
+func (c *Client) CreateComment(ctx context.Context, owner, repo string, number int, body string) error {
+	return c.do(ctx, http.MethodPost, repoPath(owner, repo, "/issues/%d/comments", number),
+		map[string]string{"body": body}, nil)
+}
@@ -0,0 +1,153 @@
+package github_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/github"
+)
+
+var _ = Describe("GitHub", func() {
+	Describe("webhook signatures", func() {
+		secret, payload := []byte("s3cret"), []byte(`{"action":"opened"}`)
+
+		It("accepts payloads signed with the secret", func() {
+			signature := github.Sign(secret, payload)
+			Expect(signature).To(HavePrefix(github.SignaturePrefix))
+			Expect(github.ValidateSignature(secret, payload, signature)).To(Succeed())
+		})
+
+		It("rejects tampered, unsigned, or differently signed payloads", func() {
+			signature := github.Sign(secret, payload)
+			Expect(github.ValidateSignature(secret, []byte(`{"action":"closed"}`), signature)).
+				To(MatchError(github.ErrInvalidSignature))
+			Expect(github.ValidateSignature(secret, payload, "")).To(MatchError(github.ErrInvalidSignature))
+			Expect(github.ValidateSignature([]byte("other"), payload, signature)).To(MatchError(github.ErrInvalidSignature))
+		})
+	})
+
+	Describe("Client", func() {
+		var (
+			ts       *httptest.Server
+			client   *github.Client
+			requests []*http.Request
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				switch r.URL.Path {
+				case "/repos/octo/gitops/git/ref/heads/main":
+					fmt.Fprint(w, `{"ref":"refs/heads/main","object":{"sha":"abc123"}}`)
+				case "/repos/octo/gitops/git/refs":
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					fmt.Fprint(w, `{"message":"Reference already exists"}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			client = github.NewClient(ts.URL+"/", "t0ken")
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("authenticates requests and decodes the response", func() {
+			ref, err := client.GetBranch(context.Background(), "octo", "gitops", "main")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref.Object.SHA).To(Equal("abc123"))
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer t0ken"))
+			Expect(requests[0].Header.Get("Accept")).To(Equal(github.MediaType))
+		})
+
+		It("returns the status and message of errors", func() {
+			err := client.CreateBranch(context.Background(), "octo", "gitops", "fix", "abc123")
+			Expect(err).To(MatchError(ContainSubstring("Reference already exists")))
+			Expect(github.IsStatus(err, http.StatusUnprocessableEntity)).To(BeTrue())
+			Expect(github.IsStatus(fmt.Errorf("wrapped: %w", err), http.StatusUnprocessableEntity)).To(BeTrue())
+
+			_, err = client.GetCommit(context.Background(), "octo", "gitops", "missing")
+			Expect(github.IsStatus(err, http.StatusNotFound)).To(BeTrue())
+		})
+	})
+
+	Describe("App", func() {
+		var key *rsa.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			key, err = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("parses PKCS1 and PKCS8 keys", func() {
+			pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+			Expect(github.ParsePrivateKey(pkcs1)).To(Equal(key))
+
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+			parsed, err := github.ParsePrivateKey(pkcs8)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.Equal(key)).To(BeTrue())
+
+			_, err = github.ParsePrivateKey([]byte("not a key"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("signs JWTs which identify the app", func() {
+			now := time.Unix(1700000000, 0)
+			jwt, err := (&github.App{ID: 42, Key: key}).JWT(now)
+			Expect(err).NotTo(HaveOccurred())
+
+			parts := strings.Split(jwt, ".")
+			Expect(parts).To(HaveLen(3))
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature)).To(Succeed())
+
+			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+			Expect(err).NotTo(HaveOccurred())
+			var claims map[string]interface{}
+			Expect(json.Unmarshal(payload, &claims)).To(Succeed())
+			Expect(claims).To(HaveKeyWithValue("iss", "42"))
+			Expect(claims["exp"]).To(BeNumerically("==", now.Add(github.JWTLifetime).Unix()))
+		})
+
+		It("exchanges its JWT for installation tokens", func() {
+			var authorization string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.URL.Path).To(Equal("/app/installations/7/access_tokens"))
+				authorization = r.Header.Get("Authorization")
+				fmt.Fprint(w, `{"token":"ghs_installation"}`)
+			}))
+			defer ts.Close()
+
+			app := &github.App{ID: 42, Key: key, BaseURL: ts.URL}
+			client, err := app.InstallationClient(context.Background(), 7)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Token).To(Equal("ghs_installation"))
+			Expect(client.BaseURL).To(Equal(ts.URL))
+			Expect(authorization).To(HavePrefix("Bearer ey"))
+		})
+	})
+})
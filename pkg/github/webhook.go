@@ -0,0 +1,123 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Define the headers and events of the webhooks sent by GitHub.
+const (
+	// HeaderEvent Holds the name of the event which triggered the webhook.
+	HeaderEvent = "X-GitHub-Event"
+	// HeaderSignature Holds the HMAC-SHA256 of the payload, keyed with the webhook secret.
+	HeaderSignature = "X-Hub-Signature-256"
+	// SignaturePrefix Precedes the hex encoded signature.
+	SignaturePrefix = "sha256="
+
+	EventPing         = "ping"
+	EventIssues       = "issues"
+	EventIssueComment = "issue_comment"
+
+	ActionOpened  = "opened"
+	ActionLabeled = "labeled"
+	ActionCreated = "created"
+
+	// UserTypeBot Is the type of the accounts of apps, whose events are ignored to avoid loops.
+	UserTypeBot = "Bot"
+
+	// AssociationOwner, AssociationMember, and AssociationCollaborator Are the author associations
+	// of the users who own the repository, belong to its organization, or were invited to it.
+	AssociationOwner        = "OWNER"
+	AssociationMember       = "MEMBER"
+	AssociationCollaborator = "COLLABORATOR"
+)
+
+// ErrInvalidSignature Is returned when a payload does not match its signature.
+var ErrInvalidSignature = errors.New("the webhook signature is missing or invalid")
+
+// Sign Returns the value of HeaderSignature for the payload.
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return SignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateSignature Checks the signature which GitHub sent along with the payload.
+func ValidateSignature(secret, payload []byte, signature string) error {
+	if !strings.HasPrefix(signature, SignaturePrefix) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret, payload)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// User Is the account which triggered an event.
+type User struct {
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+// Label Is a label of an issue.
+type Label struct {
+	Name string `json:"name"`
+}
+
+// Issue Is the issue or pull request an event is about.
+type Issue struct {
+	Number int     `json:"number"`
+	Title  string  `json:"title"`
+	Body   string  `json:"body"`
+	Labels []Label `json:"labels"`
+	// AuthorAssociation Is how the author of the issue is associated with the repository.
+	AuthorAssociation string `json:"author_association"`
+	// PullRequest Is only set when the issue is a pull request.
+	PullRequest *struct {
+		URL string `json:"url"`
+	} `json:"pull_request,omitempty"`
+}
+
+// HasLabel Reports whether the issue is labeled with name.
+func (i Issue) HasLabel(name string) bool {
+	for _, label := range i.Labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Comment Is a comment on an issue or pull request.
+type Comment struct {
+	Body string `json:"body"`
+	User User   `json:"user"`
+	// AuthorAssociation Is how the author of the comment is associated with the repository.
+	AuthorAssociation string `json:"author_association"`
+}
+
+// Repository Is the repository an event comes from.
+type Repository struct {
+	Name          string `json:"name"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         User   `json:"owner"`
+}
+
+// Installation Identifies the installation of the GitHub App which received the event.
+type Installation struct {
+	ID int64 `json:"id"`
+}
+
+// Event Holds the fields of the issues and issue_comment events used by the bot.
+type Event struct {
+	Action       string        `json:"action"`
+	Issue        Issue         `json:"issue"`
+	Comment      *Comment      `json:"comment,omitempty"`
+	Label        *Label        `json:"label,omitempty"`
+	Repository   Repository    `json:"repository"`
+	Sender       User          `json:"sender"`
+	Installation *Installation `json:"installation,omitempty"`
+}
@@ -0,0 +1,231 @@
+// gitlab is a small client for the parts of the GitLab REST API used by the bot:
+// committing files to a branch, opening merge requests, and posting notes.
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Define the values used to talk to the GitLab API.
+const (
+	// DefaultBaseURL Is the URL of the API of gitlab.com. Self-hosted instances serve it under /api/v4.
+	DefaultBaseURL = "https://gitlab.com/api/v4"
+	// HeaderToken Holds the token which authenticates requests to the API.
+	HeaderToken = "PRIVATE-TOKEN"
+
+	FileActionCreate = "create"
+	FileActionUpdate = "update"
+)
+
+// Define the access levels of the members of a project, from the lowest to the highest.
+const (
+	AccessNone       = 0
+	AccessGuest      = 10
+	AccessReporter   = 20
+	AccessDeveloper  = 30
+	AccessMaintainer = 40
+	AccessOwner      = 50
+)
+
+// ParseAccessLevel Returns the access level with the given name, e.g. developer.
+// An empty name is no access at all.
+func ParseAccessLevel(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return AccessNone, nil
+	case "guest":
+		return AccessGuest, nil
+	case "reporter":
+		return AccessReporter, nil
+	case "developer":
+		return AccessDeveloper, nil
+	case "maintainer":
+		return AccessMaintainer, nil
+	case "owner":
+		return AccessOwner, nil
+	}
+	return AccessNone, fmt.Errorf("unknown access level %q, expected guest, reporter, developer, maintainer or owner", name)
+}
+
+// Client Sends authenticated requests to the GitLab REST API.
+type Client struct {
+	// BaseURL Is the root of the API, without a trailing slash.
+	BaseURL string
+	// Token Is a personal, group, or project access token with the api scope.
+	Token string
+	// HTTP Is the client used to send requests, http.DefaultClient when nil.
+	HTTP *http.Client
+}
+
+// NewClient Returns a client for the API at baseURL, which defaults to DefaultBaseURL.
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// APIError Is returned when the API responds with an error status.
+type APIError struct {
+	StatusCode int
+	// Message Is a string for most errors, and an object for validation errors.
+	Message interface{} `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gitlab: %d %v", e.StatusCode, e.Message)
+}
+
+// IsStatus Reports whether err is an API error with the given status code.
+func IsStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// CommitAction Creates or updates a file in a commit.
+type CommitAction struct {
+	Action   string `json:"action"`
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+// Commit Is a commit to create with the commits API.
+type Commit struct {
+	Branch string `json:"branch"`
+	// StartBranch Is the branch which Branch is created from.
+	StartBranch string `json:"start_branch,omitempty"`
+	Message     string `json:"commit_message"`
+	// Force Replaces Branch with a commit on top of StartBranch when Branch already exists.
+	Force   bool           `json:"force,omitempty"`
+	Actions []CommitAction `json:"actions"`
+}
+
+// MergeRequest Is a merge request, as sent when creating one and as returned by the API.
+type MergeRequest struct {
+	IID                int    `json:"iid,omitempty"`
+	Title              string `json:"title"`
+	Description        string `json:"description"`
+	SourceBranch       string `json:"source_branch"`
+	TargetBranch       string `json:"target_branch"`
+	RemoveSourceBranch bool   `json:"remove_source_branch,omitempty"`
+	WebURL             string `json:"web_url,omitempty"`
+}
+
+// do Sends a request with a JSON body to the API, and decodes the response into v.
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set(HeaderToken, c.Token)
+	}
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{StatusCode: res.StatusCode}
+		// the message is informative only, so a body which is not JSON is not an error
+		_ = json.NewDecoder(res.Body).Decode(apiErr)
+		if apiErr.Message == nil {
+			apiErr.Message = http.StatusText(res.StatusCode)
+		}
+		return apiErr
+	}
+	if v != nil {
+		return json.NewDecoder(res.Body).Decode(v)
+	}
+	return nil
+}
+
+// projectPath Returns the API path of a project resource.
+func projectPath(project int64, format string, args ...interface{}) string {
+	return fmt.Sprintf("/projects/%d", project) + fmt.Sprintf(format, args...)
+}
+
+// CurrentUser Returns the user whom the token belongs to.
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	user := &User{}
+	return user, c.do(ctx, http.MethodGet, "/user", nil, user)
+}
+
+// Member Is a member of a project, directly or through its groups.
+type Member struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	AccessLevel int    `json:"access_level"`
+}
+
+// AccessLevel Returns the access level of the user to the project, inherited from its
+// groups included, which is AccessNone when the user is not a member.
+func (c *Client) AccessLevel(ctx context.Context, project, user int64) (int, error) {
+	member := &Member{}
+	err := c.do(ctx, http.MethodGet, projectPath(project, "/members/all/%d", user), nil, member)
+	if IsStatus(err, http.StatusNotFound) {
+		return AccessNone, nil
+	}
+	if err != nil {
+		return AccessNone, err
+	}
+	return member.AccessLevel, nil
+}
+
+// CreateCommit Commits the actions to the branch of the project.
+func (c *Client) CreateCommit(ctx context.Context, project int64, commit Commit) error {
+	return c.do(ctx, http.MethodPost, projectPath(project, "/repository/commits"), commit, nil)
+}
+
+// ListMergeRequests Returns the open merge requests whose source is the branch.
+func (c *Client) ListMergeRequests(ctx context.Context, project int64, sourceBranch string) ([]MergeRequest, error) {
+	query := url.Values{"source_branch": {sourceBranch}, "state": {"opened"}}
+	var mrs []MergeRequest
+	if err := c.do(ctx, http.MethodGet, projectPath(project, "/merge_requests?%s", query.Encode()), nil, &mrs); err != nil {
+		return nil, err
+	}
+	return mrs, nil
+}
+
+// CreateMergeRequest Opens a merge request of the source branch into the target branch.
+func (c *Client) CreateMergeRequest(ctx context.Context, project int64, mr MergeRequest) (*MergeRequest, error) {
+	created := &MergeRequest{}
+	return created, c.do(ctx, http.MethodPost, projectPath(project, "/merge_requests"), mr, created)
+}
+
+// CreateIssueNote Comments on an issue.
+func (c *Client) CreateIssueNote(ctx context.Context, project int64, iid int, body string) error {
+	return c.do(ctx, http.MethodPost, projectPath(project, "/issues/%d/notes", iid),
+		map[string]string{"body": body}, nil)
+}
+
+// CreateMergeRequestNote Comments on a merge request.
+func (c *Client) CreateMergeRequestNote(ctx context.Context, project int64, iid int, body string) error {
+	return c.do(ctx, http.MethodPost, projectPath(project, "/merge_requests/%d/notes", iid),
+		map[string]string{"body": body}, nil)
+}
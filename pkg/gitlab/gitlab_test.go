@@ -0,0 +1,122 @@
+package gitlab_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/gitlab"
+)
+
+var _ = Describe("GitLab", func() {
+	It("validates the webhook token", func() {
+		secret := []byte("s3cret")
+		Expect(gitlab.ValidateToken(secret, "s3cret")).To(Succeed())
+		Expect(gitlab.ValidateToken(secret, "other")).To(MatchError(gitlab.ErrInvalidToken))
+		Expect(gitlab.ValidateToken(secret, "")).To(MatchError(gitlab.ErrInvalidToken))
+		Expect(gitlab.ValidateToken(nil, "")).To(MatchError(gitlab.ErrInvalidToken))
+	})
+
+	It("decodes the attributes of issue and note events", func() {
+		var event gitlab.Event
+		Expect(json.Unmarshal([]byte(`{
+			"object_kind": "note",
+			"project": {"id": 5, "path_with_namespace": "ops/gitops", "default_branch": "main"},
+			"object_attributes": {"note": "/copilot-ops", "noteable_type": "Issue"},
+			"issue": {"iid": 7, "title": "Resize", "description": "@pvc needs 100Gi"}
+		}`), &event)).To(Succeed())
+		Expect(event.Project.ID).To(BeEquivalentTo(5))
+		Expect(event.ObjectAttributes.NoteableType).To(Equal(gitlab.NoteableIssue))
+		Expect(event.Issue.IID).To(Equal(7))
+
+		Expect(json.Unmarshal([]byte(`{"object_attributes": {"iid": 9, "title": "Resize", "action": "open"}}`), &event)).
+			To(Succeed())
+		Expect(event.ObjectAttributes.IID).To(Equal(9))
+		Expect(event.ObjectAttributes.Action).To(Equal(gitlab.ActionOpen))
+	})
+
+	Describe("Client", func() {
+		var (
+			ts     *httptest.Server
+			client *gitlab.Client
+			token  string
+			commit gitlab.Commit
+		)
+
+		BeforeEach(func() {
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				token = r.Header.Get(gitlab.HeaderToken)
+				switch r.Method + " " + r.URL.Path {
+				case "POST /projects/5/repository/commits":
+					Expect(json.NewDecoder(r.Body).Decode(&commit)).To(Succeed())
+					w.WriteHeader(http.StatusCreated)
+					fmt.Fprint(w, `{"id":"abc123"}`)
+				case "GET /projects/5/merge_requests":
+					Expect(r.URL.Query().Get("source_branch")).To(Equal("fix"))
+					fmt.Fprint(w, `[{"iid":3,"web_url":"https://gitlab.test/ops/gitops/-/merge_requests/3"}]`)
+				case "GET /user":
+					fmt.Fprint(w, `{"id":9,"username":"copilot-ops-bot"}`)
+				case "GET /projects/5/members/all/11":
+					fmt.Fprint(w, `{"id":11,"username":"alice","access_level":30}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"message":"404 Project Not Found"}`)
+				}
+			}))
+			client = gitlab.NewClient(ts.URL, "glpat-t0ken")
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("authenticates requests and encodes commits", func() {
+			Expect(client.CreateCommit(context.Background(), 5, gitlab.Commit{
+				Branch:      "fix",
+				StartBranch: "main",
+				Message:     "fix",
+				Force:       true,
+				Actions:     []gitlab.CommitAction{{Action: gitlab.FileActionUpdate, FilePath: "a.yaml", Content: "a: 1\n"}},
+			})).To(Succeed())
+			Expect(token).To(Equal("glpat-t0ken"))
+			Expect(commit.StartBranch).To(Equal("main"))
+			Expect(commit.Force).To(BeTrue())
+			Expect(commit.Actions).To(HaveLen(1))
+
+			mrs, err := client.ListMergeRequests(context.Background(), 5, "fix")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mrs).To(HaveLen(1))
+			Expect(mrs[0].WebURL).To(HaveSuffix("/merge_requests/3"))
+
+			user, err := client.CurrentUser(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Username).To(Equal("copilot-ops-bot"))
+
+			level, err := client.AccessLevel(context.Background(), 5, 11)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(level).To(Equal(gitlab.AccessDeveloper))
+			level, err = client.AccessLevel(context.Background(), 5, 12)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(level).To(Equal(gitlab.AccessNone))
+		})
+
+		It("parses access levels", func() {
+			Expect(gitlab.ParseAccessLevel("Maintainer")).To(Equal(gitlab.AccessMaintainer))
+			Expect(gitlab.ParseAccessLevel("")).To(Equal(gitlab.AccessNone))
+			_, err := gitlab.ParseAccessLevel("admin")
+			Expect(err).To(MatchError(ContainSubstring("unknown access level")))
+		})
+
+		It("returns the status and message of errors", func() {
+			err := client.CreateIssueNote(context.Background(), 6, 1, "hi")
+			Expect(err).To(MatchError(ContainSubstring("404 Project Not Found")))
+			Expect(gitlab.IsStatus(err, http.StatusNotFound)).To(BeTrue())
+		})
+	})
+})
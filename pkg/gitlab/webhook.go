@@ -0,0 +1,100 @@
+package gitlab
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// Define the headers and events of the webhooks sent by GitLab.
+const (
+	// HeaderEvent Holds the name of the event which triggered the webhook.
+	HeaderEvent = "X-Gitlab-Event"
+	// HeaderWebhookToken Holds the secret token configured for the webhook.
+	HeaderWebhookToken = "X-Gitlab-Token"
+
+	EventIssue = "Issue Hook"
+	EventNote  = "Note Hook"
+
+	ActionOpen   = "open"
+	ActionUpdate = "update"
+
+	NoteableIssue        = "Issue"
+	NoteableMergeRequest = "MergeRequest"
+)
+
+// ErrInvalidToken Is returned when a webhook does not carry the secret token.
+var ErrInvalidToken = errors.New("the webhook token is missing or invalid")
+
+// ValidateToken Checks the secret token which GitLab sent along with the webhook.
+func ValidateToken(secret []byte, token string) error {
+	if len(secret) == 0 || subtle.ConstantTimeCompare(secret, []byte(token)) != 1 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// User Is the account which triggered an event.
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// Label Is a label of an issue or merge request.
+type Label struct {
+	Title string `json:"title"`
+}
+
+// Project Is the project an event comes from.
+type Project struct {
+	ID                int64  `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+}
+
+// Issue Is the issue or merge request an event is about.
+type Issue struct {
+	IID         int    `json:"iid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// Attributes Holds the attributes of the object an event is about: an issue for
+// issue events, and the note for note events.
+type Attributes struct {
+	Issue
+	Action       string `json:"action"`
+	Note         string `json:"note"`
+	NoteableType string `json:"noteable_type"`
+}
+
+// LabelChanges Holds the labels before and after an update.
+type LabelChanges struct {
+	Previous []Label `json:"previous"`
+	Current  []Label `json:"current"`
+}
+
+// Event Holds the fields of the issue and note events used by the bot.
+type Event struct {
+	ObjectKind       string     `json:"object_kind"`
+	User             User       `json:"user"`
+	Project          Project    `json:"project"`
+	ObjectAttributes Attributes `json:"object_attributes"`
+	Labels           []Label    `json:"labels"`
+	Changes          struct {
+		Labels *LabelChanges `json:"labels,omitempty"`
+	} `json:"changes"`
+	// Issue Is the issue which a note was added to.
+	Issue *Issue `json:"issue,omitempty"`
+	// MergeRequest Is the merge request which a note was added to.
+	MergeRequest *Issue `json:"merge_request,omitempty"`
+}
+
+// HasLabel Reports whether the labels include name.
+func HasLabel(labels []Label, name string) bool {
+	for _, label := range labels {
+		if label.Title == name {
+			return true
+		}
+	}
+	return false
+}
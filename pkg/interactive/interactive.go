@@ -0,0 +1,309 @@
+// interactive lets the user review the changes proposed by a model hunk by hunk,
+// keeping only the hunks which were accepted.
+package interactive
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/diff"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+// Define the ANSI escape sequences used to color the preview.
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// DefaultEditor Is the editor used when neither $VISUAL nor $EDITOR are set.
+const DefaultEditor = "vi"
+
+// Help Describes the answers which can be given for every hunk.
+const Help = `y - apply this hunk
+n - do not apply this hunk
+e - edit this hunk in $EDITOR, then apply it
+q - quit; do not apply this hunk or any of the remaining ones
+? - print help
+`
+
+// editHeader Is written above the hunk given to the editor.
+const editHeader = `# Edit the hunk below, then save and close the editor to apply it.
+# Lines starting with '-' are removed and lines starting with '+' are added.
+# To keep a line which would be removed, replace its '-' with a space.
+# To drop a line which would be added, delete it.
+# Lines starting with '#' are ignored.
+`
+
+// EditFunc Lets the user edit the given text, returning the edited text.
+type EditFunc func(text string) (string, error)
+
+// Reviewer Prompts the user to accept or reject every hunk of the proposed changes.
+type Reviewer struct {
+	In  *bufio.Reader
+	Out io.Writer
+	// Color Enables ANSI colors in the preview.
+	Color bool
+	// Edit Is called when the user chooses to edit a hunk.
+	Edit EditFunc
+}
+
+// NewReviewer Returns a reviewer reading answers from in and writing the preview to out,
+// using colors unless $NO_COLOR is set, and editing hunks with the user's editor.
+func NewReviewer(in io.Reader, out io.Writer) *Reviewer {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &Reviewer{
+		In:    bufio.NewReader(in),
+		Out:   out,
+		Color: !noColor && os.Getenv("TERM") != "dumb",
+		Edit:  EditInEditor,
+	}
+}
+
+// Review Walks through every file in after which differs from before, asking about
+// each hunk, and returns a filemap with the accepted changes applied to the files.
+// Files for which no hunk was accepted are left out.
+func (rv *Reviewer) Review(before, after *filemap.Filemap) (*filemap.Filemap, error) {
+	accepted := filemap.NewFilemap()
+	original := diff.ContentByPath(before)
+
+	tags := make(map[string]string, len(after.Files))
+	paths := make([]string, 0, len(after.Files))
+	for tag, file := range after.Files {
+		path := diff.CleanPath(file.Path, tag)
+		tags[path] = tag
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		tag := tags[path]
+		file := after.Files[tag]
+		oldContent, exists := original[path]
+		hunks := diff.Hunks(oldContent, file.Content, diff.DefaultContext)
+		if len(hunks) == 0 {
+			continue
+		}
+
+		rv.printFileHeader(path, exists)
+		selected, quit, err := rv.reviewHunks(path, hunks)
+		if err != nil {
+			return nil, err
+		}
+		if len(selected) > 0 {
+			content, err := diff.Apply(oldContent, selected)
+			if err != nil {
+				return nil, fmt.Errorf("could not apply the accepted hunks to %s: %w", path, err)
+			}
+			file.Content = content
+			accepted.Files[tag] = file
+		}
+		if quit {
+			break
+		}
+	}
+	return accepted, nil
+}
+
+// reviewHunks Asks about every hunk of a single file, returning the accepted hunks
+// and whether the user chose to quit.
+func (rv *Reviewer) reviewHunks(path string, hunks []diff.Hunk) ([]diff.Hunk, bool, error) {
+	var selected []diff.Hunk
+	for i := 0; i < len(hunks); i++ {
+		h := hunks[i]
+		rv.printHunk(h)
+		for {
+			fmt.Fprintf(rv.Out, "(%d/%d) Apply this hunk to %s [y,n,e,q,?]? ", i+1, len(hunks), path)
+			answer, err := rv.readAnswer()
+			if err != nil {
+				return nil, false, err
+			}
+			switch answer {
+			case "y":
+				selected = append(selected, h)
+			case "n":
+			case "e":
+				edited, editErr := rv.editHunk(h)
+				if editErr != nil {
+					fmt.Fprintf(rv.Out, "could not use the edited hunk: %s\n", editErr)
+					continue
+				}
+				selected = append(selected, edited)
+			case "q":
+				return selected, true, nil
+			default:
+				fmt.Fprint(rv.Out, Help)
+				continue
+			}
+			break
+		}
+	}
+	return selected, false, nil
+}
+
+// readAnswer Reads the first letter of the user's answer. Running out of input
+// is treated as quitting.
+func (rv *Reviewer) readAnswer() (string, error) {
+	line, err := rv.readLine()
+	if err != nil || line == "q" || line == "" {
+		return line, err
+	}
+	return line[:1], nil
+}
+
+// readLine Reads the user's answer, returning "q" when the input runs out and
+// "?" for empty answers.
+func (rv *Reviewer) readLine() (string, error) {
+	line, err := rv.In.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("could not read answer: %w", err)
+	}
+	line = strings.ToLower(strings.TrimSpace(line))
+	if line == "" {
+		if err == io.EOF {
+			fmt.Fprintln(rv.Out)
+			return "q", nil
+		}
+		return "?", nil
+	}
+	return line, nil
+}
+
+// Choose Lists the options and asks the user to pick one of them, returning its
+// index, or -1 if the user quits. Answering d followed by a number prints the
+// preview of that option.
+func (rv *Reviewer) Choose(options []string, preview func(i int) string) (int, error) {
+	list := func() {
+		for i, option := range options {
+			fmt.Fprintf(rv.Out, "%d) %s\n", i+1, option)
+		}
+	}
+	list()
+	for {
+		fmt.Fprintf(rv.Out, "Pick one [1-%d, d<number> to show its diff, q]? ", len(options))
+		answer, err := rv.readLine()
+		if err != nil {
+			return -1, err
+		}
+		if answer == "q" {
+			return -1, nil
+		}
+		showPreview := strings.HasPrefix(answer, "d")
+		var n int
+		if _, scanErr := fmt.Sscanf(strings.TrimPrefix(answer, "d"), "%d", &n); scanErr != nil || n < 1 || n > len(options) {
+			list()
+			continue
+		}
+		if !showPreview {
+			return n - 1, nil
+		}
+		rv.printPreview(preview(n - 1))
+	}
+}
+
+// printPreview Prints a unified diff, coloring it like the hunks.
+func (rv *Reviewer) printPreview(text string) {
+	// file headers run from a "diff" line up to the first hunk
+	inHeader := false
+	for _, line := range diff.SplitLines(text) {
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			inHeader = true
+			line = rv.colorize(colorBold, line)
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+			line = rv.colorize(colorCyan, line)
+		case inHeader:
+			line = rv.colorize(colorBold, line)
+		case strings.HasPrefix(line, "-"):
+			line = rv.colorize(colorRed, line)
+		case strings.HasPrefix(line, "+"):
+			line = rv.colorize(colorGreen, line)
+		}
+		fmt.Fprint(rv.Out, line)
+	}
+}
+
+// editHunk Lets the user edit the hunk, and parses the result.
+func (rv *Reviewer) editHunk(h diff.Hunk) (diff.Hunk, error) {
+	if rv.Edit == nil {
+		return diff.Hunk{}, fmt.Errorf("no editor available")
+	}
+	text, err := rv.Edit(editHeader + h.String())
+	if err != nil {
+		return diff.Hunk{}, err
+	}
+	return diff.ParseHunk(text, h)
+}
+
+// printFileHeader Prints the header shown before the hunks of a file.
+func (rv *Reviewer) printFileHeader(path string, exists bool) {
+	oldPath := "a/" + path
+	if !exists {
+		oldPath = diff.DevNull
+	}
+	header := fmt.Sprintf("diff --git a/%s b/%s\n--- %s\n+++ b/%s\n", path, path, oldPath, path)
+	fmt.Fprint(rv.Out, rv.colorize(colorBold, header))
+}
+
+// printHunk Prints the hunk, coloring removed lines red and added lines green.
+func (rv *Reviewer) printHunk(h diff.Hunk) {
+	rv.printPreview(h.String())
+}
+
+// colorize Wraps the text in the given color, keeping a trailing newline outside of it.
+func (rv *Reviewer) colorize(color, text string) string {
+	if !rv.Color {
+		return text
+	}
+	trimmed := strings.TrimSuffix(text, "\n")
+	return color + trimmed + colorReset + text[len(trimmed):]
+}
+
+// EditInEditor Opens the text in the editor named by $VISUAL or $EDITOR and returns
+// the saved result.
+func EditInEditor(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = DefaultEditor
+	}
+
+	f, err := os.CreateTemp("", "copilot-ops-hunk-*.diff")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+
+	// the editor may be given with arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(edited), nil
+}
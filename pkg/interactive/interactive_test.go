@@ -0,0 +1,136 @@
+package interactive_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/interactive"
+)
+
+// manifest Returns 20 numbered lines, replacing the given lines.
+func manifest(replace map[int]string) string {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+		if r, ok := replace[i+1]; ok {
+			lines[i] = r
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+var _ = Describe("Reviewer", func() {
+	var (
+		before, after *filemap.Filemap
+		out           *bytes.Buffer
+	)
+
+	// review Runs a reviewer answering with the given lines.
+	review := func(answers string, edit interactive.EditFunc) *filemap.Filemap {
+		rv := interactive.NewReviewer(strings.NewReader(answers), out)
+		rv.Color = false
+		rv.Edit = edit
+		accepted, err := rv.Review(before, after)
+		Expect(err).NotTo(HaveOccurred())
+		return accepted
+	}
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		before = filemap.NewFilemap()
+		before.Files["app.yaml"] = filemap.File{Path: "app.yaml", Content: manifest(nil)}
+		before.Files["same.yaml"] = filemap.File{Path: "same.yaml", Content: "kind: Pod\n"}
+		after = before.Clone()
+		after.Files["app.yaml"] = filemap.File{
+			Path:    "app.yaml",
+			Content: manifest(map[int]string{2: "changed 2", 18: "changed 18"}),
+		}
+		after.Files["new.yaml"] = filemap.File{Path: "new.yaml", Content: "kind: Service\n"}
+	})
+
+	It("writes only the accepted hunks", func() {
+		accepted := review("y\nn\nn\n", nil)
+		Expect(accepted.Files).To(HaveLen(1))
+		Expect(accepted.Files["app.yaml"].Content).To(Equal(manifest(map[int]string{2: "changed 2"})))
+		Expect(out.String()).To(ContainSubstring("(2/2) Apply this hunk to app.yaml"))
+		Expect(out.String()).To(ContainSubstring("--- /dev/null\n+++ b/new.yaml\n"))
+		Expect(out.String()).NotTo(ContainSubstring("same.yaml"))
+	})
+
+	It("stops asking when the user quits", func() {
+		accepted := review("n\ny\nq\n", nil)
+		Expect(accepted.Files).To(HaveKey("app.yaml"))
+		Expect(accepted.Files).NotTo(HaveKey("new.yaml"))
+	})
+
+	It("treats running out of input as quitting", func() {
+		Expect(review("", nil).Files).To(BeEmpty())
+	})
+
+	It("prints help and asks again for unknown answers", func() {
+		accepted := review("x\ny\ny\ny\n", nil)
+		Expect(out.String()).To(ContainSubstring(interactive.Help))
+		Expect(accepted.Files["app.yaml"].Content).To(Equal(after.Files["app.yaml"].Content))
+		Expect(accepted.Files["new.yaml"].Content).To(Equal("kind: Service\n"))
+	})
+
+	It("applies edited hunks", func() {
+		edit := func(text string) (string, error) {
+			Expect(text).To(ContainSubstring("+changed 2\n"))
+			return strings.Replace(text, "+changed 2\n", "+edited 2\n", 1), nil
+		}
+		accepted := review("e\nn\nn\n", edit)
+		Expect(accepted.Files["app.yaml"].Content).To(Equal(manifest(map[int]string{2: "edited 2"})))
+	})
+
+	It("asks again when the edited hunk is invalid", func() {
+		edit := func(text string) (string, error) {
+			return "", nil
+		}
+		accepted := review("e\ny\nn\nn\n", edit)
+		Expect(out.String()).To(ContainSubstring("could not use the edited hunk"))
+		Expect(accepted.Files["app.yaml"].Content).To(Equal(manifest(map[int]string{2: "changed 2"})))
+	})
+
+	It("colors the preview", func() {
+		rv := interactive.NewReviewer(strings.NewReader("q\n"), out)
+		rv.Color = true
+		_, err := rv.Review(before, after)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.String()).To(ContainSubstring("\x1b[32m+changed 2\x1b[0m\n"))
+		Expect(out.String()).To(ContainSubstring("\x1b[31m-line 2\x1b[0m\n"))
+	})
+
+	Describe("Choose", func() {
+		choose := func(answers string) int {
+			rv := interactive.NewReviewer(strings.NewReader(answers), out)
+			rv.Color = false
+			picked, err := rv.Choose([]string{"first", "second"}, func(i int) string {
+				return fmt.Sprintf("preview of %d\n", i)
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return picked
+		}
+
+		It("returns the picked option", func() {
+			Expect(choose("2\n")).To(Equal(1))
+			Expect(out.String()).To(ContainSubstring("1) first\n2) second\n"))
+		})
+
+		It("shows previews and asks again for invalid answers", func() {
+			Expect(choose("d2\n3\n1\n")).To(Equal(0))
+			Expect(out.String()).To(ContainSubstring("preview of 1\n"))
+			Expect(strings.Count(out.String(), "1) first")).To(Equal(2))
+		})
+
+		It("returns -1 when the user quits", func() {
+			Expect(choose("q\n")).To(Equal(-1))
+			Expect(choose("")).To(Equal(-1))
+		})
+	})
+})
@@ -0,0 +1,107 @@
+// issue parses issues which reference the files they are about as `@name:path`,
+// turning them into a request and the tagged files it applies to.
+package issue
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+// ReferencePattern Matches file references of the form `@name:path`. The name is
+// used as the file's tag, so that the issue text can mention the file as @name.
+var ReferencePattern = regexp.MustCompile("`" + regexp.QuoteMeta(filemap.FileTagPrefix) + "([a-zA-Z0-9_\\-]+):([^`]+)`")
+
+// Reference Is a file referenced by an issue.
+type Reference struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Issue Is an issue along with the files it references.
+type Issue struct {
+	Title      string      `json:"title,omitempty"`
+	Body       string      `json:"body"`
+	References []Reference `json:"references"`
+}
+
+// Parse Extracts the file references from the issue body. Referencing the same
+// name more than once is only allowed if it refers to the same path.
+func Parse(title, body string) (*Issue, error) {
+	iss := &Issue{Title: strings.TrimSpace(title), Body: body, References: []Reference{}}
+	paths := make(map[string]string)
+	for _, match := range ReferencePattern.FindAllStringSubmatch(body, -1) {
+		name, path := match[1], strings.TrimSpace(match[2])
+		if existing, ok := paths[name]; ok {
+			if existing != path {
+				return nil, fmt.Errorf("%s%s refers to both %q and %q", filemap.FileTagPrefix, name, existing, path)
+			}
+			continue
+		}
+		paths[name] = path
+		iss.References = append(iss.References, Reference{Name: name, Path: path})
+	}
+	return iss, nil
+}
+
+// Request Returns the text of the issue to use as the request: the title followed
+// by the body, leaving out the lines which only declare file references.
+func (iss *Issue) Request() string {
+	var lines []string
+	for _, line := range strings.Split(iss.Body, "\n") {
+		rest := strings.TrimSpace(ReferencePattern.ReplaceAllString(line, ""))
+		if rest == "" && strings.TrimSpace(line) != "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	request := strings.TrimSpace(strings.Join(lines, "\n"))
+	if iss.Title != "" {
+		request = strings.TrimSpace(iss.Title + "\n\n" + request)
+	}
+	return request
+}
+
+// LoadFiles Adds the referenced files to the filemap, tagged with their names.
+// Paths are relative to the working directory, i.e. the root of the repo.
+func (iss *Issue) LoadFiles(fm *filemap.Filemap) error {
+	return iss.LoadFilesFrom(fm, "")
+}
+
+// LoadFilesFrom Adds the referenced files to the filemap as LoadFiles does, reading them
+// from the repo at root. The paths of the files stay relative to the root.
+func (iss *Issue) LoadFilesFrom(fm *filemap.Filemap, root string) error {
+	for _, ref := range iss.References {
+		content, err := os.ReadFile(filepath.Join(root, ref.Path))
+		if err != nil {
+			return fmt.Errorf("could not load %s%s: %w", filemap.FileTagPrefix, ref.Name, err)
+		}
+		// the reference's tag replaces the tag of a file loaded from the same path
+		for tag, file := range fm.Files {
+			if filepath.Clean(file.Path) == filepath.Clean(ref.Path) {
+				delete(fm.Files, tag)
+			}
+		}
+		fm.Files[ref.Name] = filemap.File{Name: ref.Name, Path: ref.Path, Content: string(content)}
+	}
+	return nil
+}
+
+// LoadFilesWithin Adds the referenced files to the filemap as LoadFilesFrom does, refusing
+// the references which leave the repo at root or name its secrets, as filemap.WithinRoot
+// describes. The references of issues written by anyone are loaded this way.
+func (iss *Issue) LoadFilesWithin(fm *filemap.Filemap, root string) error {
+	confined := &Issue{References: make([]Reference, 0, len(iss.References))}
+	for _, ref := range iss.References {
+		path, err := filemap.WithinRoot(root, ref.Path)
+		if err != nil {
+			return fmt.Errorf("could not load %s%s: %w", filemap.FileTagPrefix, ref.Name, err)
+		}
+		confined.References = append(confined.References, Reference{Name: ref.Name, Path: path})
+	}
+	return confined.LoadFilesFrom(fm, root)
+}
@@ -0,0 +1,13 @@
+package issue_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestIssue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Issue Suite")
+}
@@ -0,0 +1,105 @@
+package issue_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/issue"
+)
+
+// example Is the root of a data-lab example, whose issues reference files below files/.
+const example = "../../data-lab/examples/simple-prometheus-update"
+
+var _ = Describe("Issue", func() {
+	It("parses the references of the data-lab issues", func() {
+		body, err := os.ReadFile(filepath.Join(example, "issues", "1", "issue.md"))
+		Expect(err).NotTo(HaveOccurred())
+		iss, err := issue.Parse("", string(body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(iss.References).To(Equal([]issue.Reference{
+			{Name: "file1", Path: "grafana/base/datasource.yaml"},
+			{Name: "file2", Path: "grafana/base/grafana-route.yaml"},
+		}))
+		Expect(iss.Request()).To(HavePrefix("@file1 and @file2 must specify the `grafana-datasource` namespace."))
+		Expect(iss.Request()).NotTo(ContainSubstring("grafana/base"))
+	})
+
+	It("keeps references which are part of a sentence", func() {
+		iss, err := issue.Parse("Bump the quota", "Update `@quota:a/quota.yaml` and `@ns:a/ns.yaml` please\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(iss.References).To(HaveLen(2))
+		Expect(iss.References[1]).To(Equal(issue.Reference{Name: "ns", Path: "a/ns.yaml"}))
+		Expect(iss.Request()).To(Equal("Bump the quota\n\nUpdate `@quota:a/quota.yaml` and `@ns:a/ns.yaml` please"))
+	})
+
+	It("rejects names which refer to different paths", func() {
+		_, err := issue.Parse("", "`@a:one.yaml`\n`@a:one.yaml`\n")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = issue.Parse("", "`@a:one.yaml`\n`@a:two.yaml`\n")
+		Expect(err).To(MatchError(ContainSubstring(`@a refers to both "one.yaml" and "two.yaml"`)))
+	})
+
+	It("loads the referenced files tagged with their names", func() {
+		wd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(filepath.Join(example, "files"))).To(Succeed())
+		defer func() { Expect(os.Chdir(wd)).To(Succeed()) }()
+
+		iss, err := issue.Parse("", "`@file1:grafana/base/datasource.yaml`\nchange @file1")
+		Expect(err).NotTo(HaveOccurred())
+		fm := filemap.NewFilemap()
+		fm.Files["datasource.yaml"] = filemap.File{Path: "./grafana/base/datasource.yaml"}
+		Expect(iss.LoadFiles(fm)).To(Succeed())
+		Expect(fm.Tags()).To(Equal([]string{"file1"}))
+		Expect(fm.Files["file1"].Content).To(ContainSubstring("kind:"))
+
+		missing, err := issue.Parse("", "`@gone:grafana/base/missing.yaml`")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(missing.LoadFiles(fm)).To(MatchError(ContainSubstring("could not load @gone")))
+	})
+
+	It("loads the referenced files from another repo", func() {
+		iss, err := issue.Parse("", "`@file1:grafana/base/datasource.yaml`\nchange @file1")
+		Expect(err).NotTo(HaveOccurred())
+		fm := filemap.NewFilemap()
+		Expect(iss.LoadFilesFrom(fm, filepath.Join(example, "files"))).To(Succeed())
+		Expect(fm.Files["file1"].Path).To(Equal("grafana/base/datasource.yaml"))
+		Expect(fm.Files["file1"].Content).To(ContainSubstring("kind:"))
+	})
+
+	It("refuses references which leave the repo or name its secrets", func() {
+		root := GinkgoT().TempDir()
+		outside := GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(root, "app"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "app", "pvc.yaml"), []byte("kind: PVC\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, ".copilot-ops.local.yaml"), []byte("apiKey: sk\n"), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(outside, "id_rsa"), []byte("PRIVATE KEY\n"), 0o600)).To(Succeed())
+		Expect(os.Symlink(outside, filepath.Join(root, "app", "link"))).To(Succeed())
+
+		for _, path := range []string{
+			"/proc/self/environ",
+			filepath.Join(outside, "id_rsa"),
+			"../" + filepath.Base(outside) + "/id_rsa",
+			"app/../../" + filepath.Base(outside) + "/id_rsa",
+			"app/link/id_rsa",
+			".copilot-ops.local.yaml",
+			".git/config",
+		} {
+			iss, err := issue.Parse("", "`@x:"+path+"`\nsummarize @x")
+			Expect(err).NotTo(HaveOccurred())
+			fm := filemap.NewFilemap()
+			Expect(iss.LoadFilesWithin(fm, root)).To(MatchError(filemap.ErrOutsideRoot), path)
+			Expect(fm.Files).To(BeEmpty())
+		}
+
+		iss, err := issue.Parse("", "`@pvc:./app/pvc.yaml`\nresize @pvc")
+		Expect(err).NotTo(HaveOccurred())
+		fm := filemap.NewFilemap()
+		Expect(iss.LoadFilesWithin(fm, root)).To(Succeed())
+		Expect(fm.Files["pvc"]).To(Equal(filemap.File{Name: "pvc", Path: filepath.Join("app", "pvc.yaml"), Content: "kind: PVC\n"}))
+	})
+})
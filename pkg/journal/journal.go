@@ -0,0 +1,301 @@
+// journal records every write to the repo files along with the contents it replaced,
+// so that the write can be listed and undone later.
+//
+// Contents are stored once under the blobs directory, named after their SHA-256 hash,
+// and every write is an entry referring to the contents of its files before and after.
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/runs"
+)
+
+const (
+	// HistoryDir Is the directory, relative to the repo root, in which writes are journaled.
+	HistoryDir = config.StateDir + "/history"
+	// BlobsDir Is the directory, relative to the history, in which contents are stored.
+	BlobsDir = "blobs"
+	// CommandUndo Is the command of the entries which undo another entry.
+	CommandUndo = "undo"
+)
+
+var (
+	// ErrNoEntries Is returned when the latest write was requested but none can be undone.
+	ErrNoEntries = errors.New("no writes which can be undone were found")
+	// ErrModified Is returned when a file was modified since the write being undone.
+	ErrModified = errors.New("files were modified since they were written")
+)
+
+// Change Is a file written to the repo. Hashes are empty when the file did not exist.
+type Change struct {
+	Path   string `json:"path"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Entry Is the record of the files written by a single command.
+type Entry struct {
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Command   string     `json:"command"`
+	Backend   ai.Backend `json:"backend,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	// Request Is the user's request in natural language.
+	Request string `json:"request,omitempty"`
+	// Undoes Is the ID of the entry which this entry undid, if any.
+	Undoes string `json:"undoes,omitempty"`
+	// UndoneBy Is the ID of the entry which undid this entry, if any.
+	UndoneBy string   `json:"undoneBy,omitempty"`
+	Files    []Change `json:"files"`
+}
+
+// NewEntry Returns a new entry for the given command. If no ID is given, one is derived
+// from the current time, in the same format as the IDs of runs.
+func NewEntry(id, command string) *Entry {
+	now := time.Now()
+	if id == "" {
+		id = now.Format(runs.IDFormat)
+	}
+	return &Entry{
+		ID:        id,
+		CreatedAt: now,
+		Command:   command,
+		Files:     []Change{},
+	}
+}
+
+// Hash Returns the name the content is stored under.
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Path Returns the path of the file the entry with the given ID is stored in.
+func Path(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// Save Writes the entry to the directory, creating the directory if necessary.
+func (e *Entry) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(Path(dir, e.ID), data, 0644)
+}
+
+// Load Reads the entry with the given ID from the directory.
+func Load(dir, id string) (*Entry, error) {
+	if err := runs.ValidateID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(Path(dir, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no write was recorded as %q", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{}
+	if err = json.Unmarshal(data, e); err != nil {
+		return nil, fmt.Errorf("could not parse entry %q: %w", id, err)
+	}
+	return e, nil
+}
+
+// List Returns every entry in the directory, oldest first.
+func List(dir string) ([]*Entry, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []*Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		e, loadErr := Load(dir, strings.TrimSuffix(file.Name(), ".json"))
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// Latest Returns the most recent entry which can be undone, i.e. which neither was
+// undone nor undoes another entry.
+func Latest(dir string) (*Entry, error) {
+	entries, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].UndoneBy == "" && entries[i].Undoes == "" {
+			return entries[i], nil
+		}
+	}
+	return nil, ErrNoEntries
+}
+
+// storeBlob Stores the content under the directory, returning its hash.
+func storeBlob(dir, content string) (string, error) {
+	hash := Hash(content)
+	path := filepath.Join(dir, BlobsDir, hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return hash, os.WriteFile(path, []byte(content), 0644)
+}
+
+// ReadBlob Returns the content stored under the hash.
+func ReadBlob(dir, hash string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, BlobsDir, hash))
+	if err != nil {
+		return "", fmt.Errorf("could not read the content %s: %w", hash, err)
+	}
+	return string(content), nil
+}
+
+// currentHash Returns the hash of the file on disk, which is empty if the file does not exist.
+func currentHash(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return Hash(string(content)), nil
+}
+
+// Write Writes the files of the filemap to the repo, recording the contents they
+// replace in the entry, which is saved to the directory before any file is written.
+// The write is not made unless it can be undone.
+func Write(dir string, e *Entry, fm *filemap.Filemap) error {
+	tags := make([]string, 0, len(fm.Files))
+	for tag := range fm.Files {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return fm.Files[tags[i]].Path < fm.Files[tags[j]].Path })
+
+	for _, tag := range tags {
+		file := fm.Files[tag]
+		change := Change{Path: filepath.Clean(file.Path)}
+		before, err := os.ReadFile(file.Path)
+		switch {
+		case err == nil:
+			if change.Before, err = storeBlob(dir, string(before)); err != nil {
+				return fmt.Errorf("could not record %s: %w", file.Path, err)
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+		if change.After, err = storeBlob(dir, file.Content); err != nil {
+			return fmt.Errorf("could not record %s: %w", file.Path, err)
+		}
+		e.Files = append(e.Files, change)
+	}
+	if err := e.Save(dir); err != nil {
+		return fmt.Errorf("could not record the write: %w", err)
+	}
+	log.Printf("recorded write %s\n", e.ID)
+	return fm.WriteUpdatesToFiles()
+}
+
+// Undo Restores the files written by the entry with the given ID, or by the latest
+// entry if the ID is empty, then records the restore as a new entry which is returned.
+// Nothing is restored if any of the files was modified since it was written. Files
+// which already hold their previous contents are left alone, and files which did not
+// exist before are removed.
+func Undo(dir, id string) (*Entry, error) {
+	var e *Entry
+	var err error
+	if id == "" {
+		e, err = Latest(dir)
+	} else {
+		e, err = Load(dir, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.UndoneBy != "" {
+		return nil, fmt.Errorf("write %s was already undone by %s", e.ID, e.UndoneBy)
+	}
+
+	undo := NewEntry("", CommandUndo)
+	undo.Undoes = e.ID
+	var modified []string
+	for _, change := range e.Files {
+		current, hashErr := currentHash(change.Path)
+		if hashErr != nil {
+			return nil, hashErr
+		}
+		switch current {
+		case change.Before:
+			continue
+		case change.After:
+			undo.Files = append(undo.Files, Change{Path: change.Path, Before: current, After: change.Before})
+		default:
+			modified = append(modified, change.Path)
+		}
+	}
+	if len(modified) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrModified, strings.Join(modified, ", "))
+	}
+
+	// the contents are read before restoring anything, so a missing blob leaves the files untouched
+	contents := make([]string, len(undo.Files))
+	for i, change := range undo.Files {
+		if change.After == "" {
+			continue
+		}
+		if contents[i], err = ReadBlob(dir, change.After); err != nil {
+			return nil, err
+		}
+	}
+	if err = undo.Save(dir); err != nil {
+		return nil, fmt.Errorf("could not record the undo: %w", err)
+	}
+	for i, change := range undo.Files {
+		log.Printf("restoring %q\n", change.Path)
+		if change.After == "" {
+			err = os.Remove(change.Path)
+		} else {
+			err = os.WriteFile(change.Path, []byte(contents[i]), 0644)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	e.UndoneBy = undo.ID
+	if err = e.Save(dir); err != nil {
+		return nil, err
+	}
+	return undo, nil
+}
@@ -0,0 +1,110 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/journal"
+)
+
+var _ = Describe("Journal", func() {
+	var (
+		repo, dir string
+		fm        *filemap.Filemap
+	)
+
+	read := func(path string) string {
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(content)
+	}
+
+	BeforeEach(func() {
+		repo = GinkgoT().TempDir()
+		dir = filepath.Join(repo, journal.HistoryDir)
+		Expect(os.WriteFile(filepath.Join(repo, "pvc.yaml"), []byte("storage: 10Gi\n"), 0o600)).To(Succeed())
+		fm = filemap.NewFilemap()
+		fm.Files["pvc"] = filemap.File{Path: filepath.Join(repo, "pvc.yaml"), Content: "storage: 100Gi\n"}
+		fm.Files["pod"] = filemap.File{Path: filepath.Join(repo, "app", "pod.yaml"), Content: "kind: Pod\n"}
+	})
+
+	It("reports when nothing can be undone", func() {
+		_, err := journal.Undo(dir, "")
+		Expect(err).To(MatchError(journal.ErrNoEntries))
+		entries, err := journal.List(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("records the contents a write replaces, and restores them", func() {
+		entry := journal.NewEntry("20230101-000000.000000", "edit")
+		entry.Request = "increase the PVC size"
+		Expect(journal.Write(dir, entry, fm)).To(Succeed())
+		Expect(read(filepath.Join(repo, "pvc.yaml"))).To(Equal("storage: 100Gi\n"))
+
+		entries, err := journal.List(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Request).To(Equal("increase the PVC size"))
+		Expect(entries[0].Files).To(Equal([]journal.Change{
+			{Path: filepath.Join(repo, "app", "pod.yaml"), After: journal.Hash("kind: Pod\n")},
+			{Path: filepath.Join(repo, "pvc.yaml"), Before: journal.Hash("storage: 10Gi\n"),
+				After: journal.Hash("storage: 100Gi\n")},
+		}))
+		before, err := journal.ReadBlob(dir, entries[0].Files[1].Before)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(before).To(Equal("storage: 10Gi\n"))
+
+		undo, err := journal.Undo(dir, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(undo.Command).To(Equal(journal.CommandUndo))
+		Expect(undo.Undoes).To(Equal(entry.ID))
+		Expect(undo.Files).To(HaveLen(2))
+		Expect(read(filepath.Join(repo, "pvc.yaml"))).To(Equal("storage: 10Gi\n"))
+		Expect(filepath.Join(repo, "app", "pod.yaml")).NotTo(BeAnExistingFile())
+
+		undone, err := journal.Load(dir, entry.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(undone.UndoneBy).To(Equal(undo.ID))
+		_, err = journal.Undo(dir, entry.ID)
+		Expect(err).To(MatchError(ContainSubstring("already undone")))
+		_, err = journal.Undo(dir, "")
+		Expect(err).To(MatchError(journal.ErrNoEntries))
+	})
+
+	It("refuses to undo a write when its files were modified since", func() {
+		entry := journal.NewEntry("", "generate")
+		Expect(journal.Write(dir, entry, fm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repo, "pvc.yaml"), []byte("storage: 1Ti\n"), 0o600)).To(Succeed())
+
+		_, err := journal.Undo(dir, entry.ID)
+		Expect(err).To(MatchError(journal.ErrModified))
+		Expect(err).To(MatchError(ContainSubstring("pvc.yaml")))
+		Expect(filepath.Join(repo, "app", "pod.yaml")).To(BeAnExistingFile())
+	})
+
+	It("leaves the files which already hold their previous contents", func() {
+		entry := journal.NewEntry("", "edit")
+		Expect(journal.Write(dir, entry, fm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(repo, "pvc.yaml"), []byte("storage: 10Gi\n"), 0o600)).To(Succeed())
+
+		undo, err := journal.Undo(dir, entry.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(undo.Files).To(Equal([]journal.Change{
+			{Path: filepath.Join(repo, "app", "pod.yaml"), Before: journal.Hash("kind: Pod\n")},
+		}))
+	})
+
+	It("fails to undo unknown writes", func() {
+		_, err := journal.Undo(dir, "missing")
+		Expect(err).To(MatchError(ContainSubstring(`no write was recorded as "missing"`)))
+
+		// the ID is not a path, so other files cannot be read as entries
+		_, err = journal.Undo(dir, "../../pvc")
+		Expect(err).To(MatchError(ContainSubstring("invalid ID")))
+	})
+})
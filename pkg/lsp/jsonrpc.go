@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// Codes of the errors returned to the client.
+const (
+	CodeParseError     = -32700
+	CodeInvalidParams  = -32602
+	CodeMethodNotFound = -32601
+	CodeInternalError  = -32603
+	// CodeServerNotInitialized Is returned for requests sent before initialize.
+	CodeServerNotInitialized = -32002
+	// CodeRequestFailed Is returned when a request was valid but could not be handled.
+	CodeRequestFailed = -32803
+)
+
+// ErrClosed Is returned while waiting for a response when the connection is closed.
+var ErrClosed = errors.New("the connection is closed")
+
+// ResponseError Is the error of a failed request.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error Returns the error's message.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Message Is a JSON-RPC message: a request when it has both an ID and a method,
+// a notification when it only has a method, and a response otherwise.
+type Message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// Conn Reads and writes the messages of the protocol, each preceded by a Content-Length
+// header, and matches the responses to the requests it sent.
+type Conn struct {
+	reader *bufio.Reader
+	writer io.Writer
+	// mu Guards the writer and the fields below.
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]chan *Message
+	closed  bool
+}
+
+// NewConn Returns a connection reading from r and writing to w.
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{reader: bufio.NewReader(r), writer: w, pending: make(map[string]chan *Message)}
+}
+
+// Read Returns the next message, handing responses to the calls waiting for them.
+// Messages which are not valid JSON are answered with a parse error, while failing to
+// read a message or its header closes the connection.
+func (c *Conn) Read() (*Message, error) {
+	for {
+		msg, err := c.read()
+		var parseErr *ResponseError
+		if errors.As(err, &parseErr) && parseErr.Code == CodeParseError {
+			// the message was framed by its header, so the next one can still be read
+			null := json.RawMessage("null")
+			err = c.write(&Message{ID: &null, Error: parseErr})
+			if err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+		if msg.Method != "" || msg.ID == nil {
+			return msg, nil
+		}
+		c.mu.Lock()
+		response, ok := c.pending[string(*msg.ID)]
+		delete(c.pending, string(*msg.ID))
+		c.mu.Unlock()
+		if ok {
+			response <- msg
+		}
+	}
+}
+
+// read Reads a message from its header and content.
+func (c *Conn) read() (*Message, error) {
+	header, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	content := make([]byte, length)
+	if _, err = io.ReadFull(c.reader, content); err != nil {
+		return nil, err
+	}
+	var msg Message
+	if err = json.Unmarshal(content, &msg); err != nil {
+		return nil, &ResponseError{Code: CodeParseError, Message: err.Error()}
+	}
+	return &msg, nil
+}
+
+// close Releases the calls waiting for a response.
+func (c *Conn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for id, response := range c.pending {
+		close(response)
+		delete(c.pending, id)
+	}
+}
+
+// Notify Sends a notification.
+func (c *Conn) Notify(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&Message{Method: method, Params: raw})
+}
+
+// Call Sends a request, and decodes the result of its response into result. Read
+// has to be called meanwhile for the response to be received.
+func (c *Conn) Call(method string, params, result interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	response := make(chan *Message, 1)
+	c.pending[string(id)] = response
+	c.mu.Unlock()
+
+	if err = c.write(&Message{ID: &id, Method: method, Params: raw}); err != nil {
+		return err
+	}
+	msg, ok := <-response
+	switch {
+	case !ok:
+		return ErrClosed
+	case msg.Error != nil:
+		return msg.Error
+	case msg.Result == nil || result == nil:
+		return nil
+	default:
+		return json.Unmarshal(*msg.Result, result)
+	}
+}
+
+// Reply Sends the response to a request, with its result unless err is not nil.
+func (c *Conn) Reply(id *json.RawMessage, result interface{}, err error) error {
+	msg := &Message{ID: id}
+	if err != nil {
+		var responseErr *ResponseError
+		if !errors.As(err, &responseErr) {
+			responseErr = &ResponseError{Code: CodeRequestFailed, Message: err.Error()}
+		}
+		msg.Error = responseErr
+		return c.write(msg)
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	msg.Result = (*json.RawMessage)(&raw)
+	return c.write(msg)
+}
+
+// write Writes the message with its header.
+func (c *Conn) write(msg *Message) error {
+	msg.JSONRPC = "2.0"
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		return fmt.Errorf("could not write the message: %w", err)
+	}
+	return nil
+}
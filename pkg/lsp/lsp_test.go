@@ -0,0 +1,256 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/lsp"
+)
+
+const deployment = `# deploy.yaml
+kind: Deployment
+# increase the replicas to 3
+spec:
+  replicas: 1
+`
+
+var _ = Describe("Positions", func() {
+	It("converts UTF-16 positions into offsets", func() {
+		text := "a: é😀b\nc"
+		Expect(lsp.Offset(text, lsp.Position{Line: 0, Character: 4})).To(Equal(len("a: é")))
+		Expect(lsp.Offset(text, lsp.Position{Line: 0, Character: 6})).To(Equal(len("a: é😀")))
+		Expect(lsp.Offset(text, lsp.Position{Line: 0, Character: 99})).To(Equal(len("a: é😀b")))
+		Expect(lsp.Offset(text, lsp.Position{Line: 1, Character: 1})).To(Equal(len(text)))
+		Expect(lsp.Offset(text, lsp.Position{Line: 5})).To(Equal(len(text)))
+	})
+
+	It("finds the end of a text", func() {
+		Expect(lsp.EndPosition("a\nb😀")).To(Equal(lsp.Position{Line: 1, Character: 3}))
+		Expect(lsp.EndPosition("a\n")).To(Equal(lsp.Position{Line: 1, Character: 0}))
+	})
+
+	It("finds the request in the selection or the comment at the cursor", func() {
+		Expect(lsp.SelectedRequest(deployment, lsp.Range{
+			Start: lsp.Position{Line: 2, Character: 3},
+			End:   lsp.Position{Line: 2, Character: 3},
+		})).To(Equal("increase the replicas to 3"))
+		Expect(lsp.SelectedRequest(deployment, lsp.Range{
+			Start: lsp.Position{Line: 1},
+			End:   lsp.Position{Line: 1},
+		})).To(BeEmpty())
+		Expect(lsp.SelectedRequest("  // use nginx\n  // with 2 replicas\n", lsp.Range{
+			End: lsp.Position{Line: 2},
+		})).To(Equal("use nginx\nwith 2 replicas"))
+	})
+})
+
+var _ = Describe("Server", func() {
+	var (
+		root    string
+		uri     string
+		actions chan *lsp.Action
+		result  *filemap.Filemap
+		runErr  error
+		client  *lsp.Conn
+		edits   chan lsp.ApplyWorkspaceEditParams
+		notes   chan *lsp.Message
+		served  chan error
+		writer  *io.PipeWriter
+	)
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		uri = lsp.PathToURI(filepath.Join(root, "app", "deploy.yaml"))
+		Expect(os.MkdirAll(filepath.Join(root, "app"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "app", "service.yaml"), []byte("kind: Service\n"), 0o600)).To(Succeed())
+		ran := make(chan *lsp.Action, 1)
+		actions = ran
+		result, runErr = nil, nil
+		run := func(_ context.Context, action *lsp.Action) (*filemap.Filemap, error) {
+			ran <- action
+			return result, runErr
+		}
+
+		serverIn, clientOut := io.Pipe()
+		clientIn, serverOut := io.Pipe()
+		writer = clientOut
+		server := lsp.NewServer(serverIn, serverOut, root, run)
+		done := make(chan error, 1)
+		served = done
+		go func() {
+			done <- server.Serve(context.Background())
+			_ = serverOut.Close()
+		}()
+
+		// the client applies every edit, and collects the notifications
+		conn := lsp.NewConn(clientIn, clientOut)
+		applied := make(chan lsp.ApplyWorkspaceEditParams, 1)
+		notified := make(chan *lsp.Message, 1)
+		client, edits, notes = conn, applied, notified
+		go func() {
+			for {
+				msg, err := conn.Read()
+				if err != nil {
+					return
+				}
+				if msg.ID == nil {
+					notified <- msg
+					continue
+				}
+				var params lsp.ApplyWorkspaceEditParams
+				_ = json.Unmarshal(msg.Params, &params)
+				applied <- params
+				_ = conn.Reply(msg.ID, lsp.ApplyWorkspaceEditResult{Applied: true}, nil)
+			}
+		}()
+
+		var initialized lsp.InitializeResult
+		Expect(client.Call(lsp.MethodInitialize, lsp.InitializeParams{RootURI: lsp.PathToURI(root)}, &initialized)).
+			To(Succeed())
+		Expect(initialized.Capabilities.ExecuteCommandProvider.Commands).
+			To(ConsistOf(lsp.CommandEdit, lsp.CommandGenerate))
+		Expect(client.Notify(lsp.MethodInitialized, struct{}{})).To(Succeed())
+		Expect(client.Notify(lsp.MethodDidOpen, lsp.DidOpenTextDocumentParams{
+			TextDocument: lsp.TextDocumentItem{URI: uri, LanguageID: "yaml", Version: 1, Text: deployment},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = writer.Close()
+	})
+
+	// codeActions Returns the actions offered at the line.
+	codeActions := func(line int) []lsp.CodeAction {
+		var actions []lsp.CodeAction
+		Expect(client.Call(lsp.MethodCodeAction, lsp.CodeActionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+			Range:        lsp.Range{Start: lsp.Position{Line: line}, End: lsp.Position{Line: line}},
+		}, &actions)).To(Succeed())
+		return actions
+	}
+
+	// execute Runs the action's command.
+	execute := func(action lsp.CodeAction) error {
+		return client.Call(lsp.MethodExecuteCommand, lsp.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: action.Command.Arguments,
+		}, nil)
+	}
+
+	It("offers actions for the comment at the cursor", func() {
+		actions := codeActions(2)
+		Expect(actions).To(HaveLen(2))
+		Expect(actions[0].Title).To(Equal(lsp.TitleEdit))
+		Expect(actions[0].Command.Arguments).To(Equal([]lsp.CommandArgs{{URI: uri, Request: "increase the replicas to 3"}}))
+		Expect(actions[1].Title).To(Equal(lsp.TitleGenerate))
+
+		Expect(codeActions(4)).To(BeEmpty())
+	})
+
+	It("edits the open buffer", func() {
+		changed := strings.Replace(deployment, "kind: Deployment", "kind: Deployment\nmetadata: {}", 1)
+		Expect(client.Notify(lsp.MethodDidChange, lsp.DidChangeTextDocumentParams{
+			TextDocument:   lsp.VersionedTextDocumentIdentifier{URI: uri, Version: ptr(2)},
+			ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: changed}},
+		})).To(Succeed())
+		result = &filemap.Filemap{Files: map[string]filemap.File{
+			"deploy.yaml":  {Path: filepath.Join("app", "deploy.yaml"), Content: "replicas: 3\n"},
+			"service.yaml": {Path: filepath.Join("app", "service.yaml"), Content: "kind: Service\n"},
+			"v1:app/service.yaml": {
+				Path: filepath.Join("app", "service.yaml"), Content: "kind: OldService\n", Rev: "v1",
+			},
+		}}
+
+		Expect(execute(codeActions(3)[0])).To(Succeed())
+		action := <-actions
+		Expect(action.Command).To(Equal(lsp.CommandEdit))
+		Expect(action.File).To(Equal(filemap.File{
+			Name:    "deploy.yaml",
+			Path:    filepath.Join("app", "deploy.yaml"),
+			Content: changed,
+		}))
+
+		// the unchanged service is left alone, and so is its version at v1
+		edit := <-edits
+		Expect(edit.Edit.DocumentChanges).To(HaveLen(1))
+		raw, err := json.Marshal(edit.Edit.DocumentChanges[0])
+		Expect(err).NotTo(HaveOccurred())
+		var change lsp.TextDocumentEdit
+		Expect(json.Unmarshal(raw, &change)).To(Succeed())
+		Expect(change.TextDocument).To(Equal(lsp.VersionedTextDocumentIdentifier{URI: uri, Version: ptr(2)}))
+		Expect(change.Edits).To(Equal([]lsp.TextEdit{{
+			Range:   lsp.Range{End: lsp.EndPosition(changed)},
+			NewText: "replicas: 3\n",
+		}}))
+	})
+
+	It("creates the generated files", func() {
+		result = &filemap.Filemap{Files: map[string]filemap.File{
+			"app/hpa.yaml": {Path: "app/hpa.yaml", Content: "kind: HorizontalPodAutoscaler\n"},
+		}}
+		Expect(execute(codeActions(2)[1])).To(Succeed())
+		Expect((<-actions).Command).To(Equal(lsp.CommandGenerate))
+
+		edit := <-edits
+		Expect(edit.Edit.DocumentChanges).To(HaveLen(2))
+		raw, err := json.Marshal(edit.Edit.DocumentChanges)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(raw)).To(ContainSubstring(`"kind":"create"`))
+		Expect(string(raw)).To(ContainSubstring(lsp.PathToURI(filepath.Join(root, "app", "hpa.yaml"))))
+		Expect(string(raw)).To(ContainSubstring("HorizontalPodAutoscaler"))
+	})
+
+	It("reports failures and missing changes", func() {
+		runErr = errors.New("the backend is down")
+		err := execute(codeActions(2)[0])
+		var responseErr *lsp.ResponseError
+		Expect(errors.As(err, &responseErr)).To(BeTrue())
+		Expect(responseErr.Message).To(ContainSubstring("the backend is down"))
+		<-actions
+
+		runErr = nil
+		result = &filemap.Filemap{Files: map[string]filemap.File{
+			"deploy.yaml": {Path: "app/deploy.yaml", Content: deployment},
+		}}
+		Expect(execute(codeActions(2)[0])).To(Succeed())
+		<-actions
+		note := <-notes
+		Expect(note.Method).To(Equal(lsp.MethodShowMessage))
+
+		err = client.Call(lsp.MethodExecuteCommand, lsp.ExecuteCommandParams{Command: "unknown"}, nil)
+		Expect(errors.As(err, &responseErr)).To(BeTrue())
+		Expect(responseErr.Code).To(Equal(lsp.CodeInvalidParams))
+	})
+
+	It("answers messages which are not JSON with a parse error, and goes on", func() {
+		_, err := io.WriteString(writer, "Content-Length: 9\r\n\r\n{\"id\": 1,")
+		Expect(err).NotTo(HaveOccurred())
+		var reply *lsp.Message
+		Eventually(notes).Should(Receive(&reply))
+		Expect(reply.ID).To(BeNil())
+		Expect(reply.Error).NotTo(BeNil())
+		Expect(reply.Error.Code).To(Equal(lsp.CodeParseError))
+
+		Expect(codeActions(2)).NotTo(BeEmpty())
+		Consistently(served).ShouldNot(Receive())
+	})
+
+	It("shuts down and exits", func() {
+		Expect(client.Call(lsp.MethodShutdown, nil, nil)).To(Succeed())
+		Expect(client.Notify(lsp.MethodExit, nil)).To(Succeed())
+		Expect(<-served).To(Succeed())
+	})
+})
+
+func ptr(i int) *int {
+	return &i
+}
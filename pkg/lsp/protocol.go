@@ -0,0 +1,256 @@
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Methods of the protocol handled or sent by the server.
+const (
+	MethodInitialize     = "initialize"
+	MethodInitialized    = "initialized"
+	MethodShutdown       = "shutdown"
+	MethodExit           = "exit"
+	MethodDidOpen        = "textDocument/didOpen"
+	MethodDidChange      = "textDocument/didChange"
+	MethodDidClose       = "textDocument/didClose"
+	MethodCodeAction     = "textDocument/codeAction"
+	MethodExecuteCommand = "workspace/executeCommand"
+	MethodApplyEdit      = "workspace/applyEdit"
+	MethodShowMessage    = "window/showMessage"
+)
+
+// Kinds of the code actions offered by the server.
+const (
+	KindRefactorRewrite = "refactor.rewrite"
+	KindSource          = "source"
+)
+
+// TextDocumentSyncFull Asks the client to send the whole document on every change.
+const TextDocumentSyncFull = 1
+
+// Types of the messages shown to the user.
+const (
+	MessageError   = 1
+	MessageWarning = 2
+	MessageInfo    = 3
+)
+
+// Position Is a position in a document, whose character counts UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range Is a range of a document, whose end is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TextDocumentIdentifier Identifies a document.
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier Identifies a version of a document. Version is nil
+// for documents which are not open in the client.
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+// TextDocumentItem Is a document opened in the client.
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// InitializeParams Are the parameters of the initialize request.
+type InitializeParams struct {
+	RootURI string `json:"rootUri,omitempty"`
+}
+
+// InitializeResult Is the result of the initialize request.
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
+
+// ServerCapabilities Are the features of the protocol which the server provides.
+type ServerCapabilities struct {
+	TextDocumentSync       int                   `json:"textDocumentSync"`
+	CodeActionProvider     CodeActionOptions     `json:"codeActionProvider"`
+	ExecuteCommandProvider ExecuteCommandOptions `json:"executeCommandProvider"`
+}
+
+// CodeActionOptions Lists the kinds of the code actions offered by the server.
+type CodeActionOptions struct {
+	CodeActionKinds []string `json:"codeActionKinds"`
+}
+
+// ExecuteCommandOptions Lists the commands which the server executes.
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
+}
+
+// ServerInfo Names the server.
+type ServerInfo struct {
+	Name string `json:"name"`
+}
+
+// DidOpenTextDocumentParams Are the parameters of the didOpen notification.
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// DidChangeTextDocumentParams Are the parameters of the didChange notification. Since
+// the server asks for full syncs, the last change holds the whole document.
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// TextDocumentContentChangeEvent Is a change of a document.
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+// DidCloseTextDocumentParams Are the parameters of the didClose notification.
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// CodeActionParams Are the parameters of the codeAction request.
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// CodeAction Is an action offered to the user, which runs its command when picked.
+type CodeAction struct {
+	Title   string   `json:"title"`
+	Kind    string   `json:"kind"`
+	Command *Command `json:"command"`
+}
+
+// Command Is a command for the server to execute.
+type Command struct {
+	Title     string        `json:"title"`
+	Command   string        `json:"command"`
+	Arguments []CommandArgs `json:"arguments"`
+}
+
+// CommandArgs Are the arguments of the server's commands.
+type CommandArgs struct {
+	// URI Is the document the command runs on.
+	URI string `json:"uri"`
+	// Request Is the change requested in natural language.
+	Request string `json:"request"`
+}
+
+// ExecuteCommandParams Are the parameters of the executeCommand request.
+type ExecuteCommandParams struct {
+	Command   string        `json:"command"`
+	Arguments []CommandArgs `json:"arguments"`
+}
+
+// WorkspaceEdit Changes documents of the workspace. Every change is either a
+// TextDocumentEdit or a CreateFile, which are applied in order.
+type WorkspaceEdit struct {
+	DocumentChanges []interface{} `json:"documentChanges"`
+}
+
+// TextDocumentEdit Edits a document.
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// TextEdit Replaces a range of a document.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// CreateFile Creates a file, which is left alone when it already exists.
+type CreateFile struct {
+	Kind    string            `json:"kind"`
+	URI     string            `json:"uri"`
+	Options CreateFileOptions `json:"options"`
+}
+
+// CreateFileOptions Are the options of a CreateFile.
+type CreateFileOptions struct {
+	IgnoreIfExists bool `json:"ignoreIfExists"`
+}
+
+// ApplyWorkspaceEditParams Are the parameters of the applyEdit request.
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+// ApplyWorkspaceEditResult Is the result of the applyEdit request.
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// ShowMessageParams Are the parameters of the showMessage notification.
+type ShowMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+// Offset Returns the byte offset of the position in the text. Positions past the
+// end of their line are clamped to it, and positions past the last line to the end of the text.
+func Offset(text string, pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+	for units := 0; units < pos.Character && offset < len(text); {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if r == '\n' {
+			break
+		}
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+// EndPosition Returns the position of the end of the text.
+func EndPosition(text string) Position {
+	line := strings.Count(text, "\n")
+	last := text[strings.LastIndexByte(text, '\n')+1:]
+	return Position{Line: line, Character: len(utf16.Encode([]rune(last)))}
+}
+
+// URIToPath Returns the path of a file URI.
+func URIToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid URI %q: %w", uri, err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("only file URIs are supported, got %q", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// PathToURI Returns the file URI of an absolute path.
+func PathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,408 @@
+// lsp implements the Language Server Protocol over stdio, so that editors can offer
+// copilot-ops' edit and generate commands as code actions on the open buffer. The
+// changes are sent back to the editor as workspace edits, which it applies to its buffers.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+// Commands executed by the server, which its code actions run.
+const (
+	CommandEdit     = "copilot-ops.edit"
+	CommandGenerate = "copilot-ops.generate"
+)
+
+// Titles of the code actions.
+const (
+	TitleEdit     = "Edit with copilot-ops"
+	TitleGenerate = "Generate related manifest"
+)
+
+// commentPrefixes Start the comments which are removed from the selected request.
+const commentPrefixes = "#/-;*"
+
+// Action Is a command to run on a document of the workspace.
+type Action struct {
+	// Command Is CommandEdit or CommandGenerate.
+	Command string
+	// Request Is the change requested in natural language.
+	Request string
+	// File Is the document, as shown in the editor, with its path relative to the root of the workspace.
+	File filemap.File
+}
+
+// Runner Runs the action, returning the files as they should be after it. The paths
+// of the files are relative to the root of the workspace, and files which are unchanged are ignored.
+type Runner func(ctx context.Context, action *Action) (*filemap.Filemap, error)
+
+// document Is a document opened in the client.
+type document struct {
+	version int
+	text    string
+}
+
+// Server Serves the protocol on a connection, running the actions which the user picks.
+type Server struct {
+	conn *Conn
+	root string
+	run  Runner
+	// mu Guards the fields below.
+	mu          sync.Mutex
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer Returns a server for the workspace at root, reading requests from r and writing to w.
+func NewServer(r io.Reader, w io.Writer, root string, run Runner) *Server {
+	return &Server{conn: NewConn(r, w), root: root, run: run, docs: make(map[string]*document)}
+}
+
+// Serve Handles the messages of the client until it exits, or the context is done.
+// Notifications are handled in order, while requests are handled concurrently since
+// running an action can take a while.
+func (s *Server) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	// the requests still waiting for the client are released before waiting for them
+	defer s.conn.close()
+
+	messages := make(chan *Message)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := s.conn.Read()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var msg *Message
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case msg = <-messages:
+		}
+
+		if msg.ID == nil {
+			if msg.Method == MethodExit {
+				if !s.isShutdown() {
+					return errors.New("the client exited without shutting down")
+				}
+				return nil
+			}
+			s.notification(msg)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := s.request(ctx, msg)
+			if err = s.conn.Reply(msg.ID, result, err); err != nil {
+				log.Printf("could not reply to %s: %s\n", msg.Method, err)
+			}
+		}()
+	}
+}
+
+// isShutdown Returns true once the client asked the server to shut down.
+func (s *Server) isShutdown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdown
+}
+
+// notification Handles a notification, which gets no response.
+func (s *Server) notification(msg *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch msg.Method {
+	case MethodDidOpen:
+		var params DidOpenTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.docs[params.TextDocument.URI] = &document{
+				version: params.TextDocument.Version,
+				text:    params.TextDocument.Text,
+			}
+		}
+	case MethodDidChange:
+		var params DidChangeTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			doc := &document{text: params.ContentChanges[len(params.ContentChanges)-1].Text}
+			if params.TextDocument.Version != nil {
+				doc.version = *params.TextDocument.Version
+			}
+			s.docs[params.TextDocument.URI] = doc
+		}
+	case MethodDidClose:
+		var params DidCloseTextDocumentParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			delete(s.docs, params.TextDocument.URI)
+		}
+	}
+}
+
+// request Returns the result of a request.
+func (s *Server) request(ctx context.Context, msg *Message) (interface{}, error) {
+	// null Is the result of the requests which only succeed or fail
+	null := json.RawMessage("null")
+	s.mu.Lock()
+	initialized, shutdown := s.initialized, s.shutdown
+	s.mu.Unlock()
+	switch {
+	case msg.Method == MethodInitialize:
+		return s.initialize(msg.Params)
+	case !initialized:
+		return nil, &ResponseError{Code: CodeServerNotInitialized, Message: "the server is not initialized"}
+	case shutdown:
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: "the server is shutting down"}
+	}
+
+	switch msg.Method {
+	case MethodShutdown:
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		return null, nil
+	case MethodCodeAction:
+		var params CodeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return s.codeActions(&params), nil
+	case MethodExecuteCommand:
+		var params ExecuteCommandParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		if err := s.execute(ctx, &params); err != nil {
+			return nil, err
+		}
+		return null, nil
+	default:
+		return nil, &ResponseError{Code: CodeMethodNotFound, Message: "unsupported method " + msg.Method}
+	}
+}
+
+// initialize Returns the capabilities of the server.
+func (s *Server) initialize(raw json.RawMessage) (*InitializeResult, error) {
+	var params InitializeParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	if params.RootURI != "" {
+		if root, err := URIToPath(params.RootURI); err == nil && filepath.Clean(root) != filepath.Clean(s.root) {
+			log.Printf("the workspace is %s, but files are loaded relative to %s\n", root, s.root)
+		}
+	}
+	s.mu.Lock()
+	s.initialized = true
+	s.mu.Unlock()
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       TextDocumentSyncFull,
+			CodeActionProvider:     CodeActionOptions{CodeActionKinds: []string{KindRefactorRewrite, KindSource}},
+			ExecuteCommandProvider: ExecuteCommandOptions{Commands: []string{CommandEdit, CommandGenerate}},
+		},
+		ServerInfo: ServerInfo{Name: "copilot-ops"},
+	}, nil
+}
+
+// codeActions Returns the actions offered for the range, whose request is the selected
+// text, or the comment on the line of the cursor. Nothing is offered without a request.
+func (s *Server) codeActions(params *CodeActionParams) []CodeAction {
+	s.mu.Lock()
+	doc, ok := s.docs[params.TextDocument.URI]
+	s.mu.Unlock()
+	if !ok {
+		return []CodeAction{}
+	}
+
+	request := SelectedRequest(doc.text, params.Range)
+	if request == "" {
+		return []CodeAction{}
+	}
+	args := []CommandArgs{{URI: params.TextDocument.URI, Request: request}}
+	return []CodeAction{
+		{
+			Title:   TitleEdit,
+			Kind:    KindRefactorRewrite,
+			Command: &Command{Title: TitleEdit, Command: CommandEdit, Arguments: args},
+		},
+		{
+			Title:   TitleGenerate,
+			Kind:    KindSource,
+			Command: &Command{Title: TitleGenerate, Command: CommandGenerate, Arguments: args},
+		},
+	}
+}
+
+// SelectedRequest Returns the request found in the range of the text: the selected
+// text when the range is not empty, or else the comment on the range's line, without
+// the markers of the comments.
+func SelectedRequest(text string, r Range) string {
+	start, end := Offset(text, r.Start), Offset(text, r.End)
+	var lines []string
+	if start < end {
+		lines = strings.Split(text[start:end], "\n")
+	} else {
+		lineStart := Offset(text, Position{Line: r.Start.Line})
+		line := text[lineStart:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || !strings.ContainsRune(commentPrefixes, rune(trimmed[0])) {
+			return ""
+		}
+		lines = []string{line}
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), commentPrefixes))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// execute Runs the command on its document, and asks the client to apply the changes.
+func (s *Server) execute(ctx context.Context, params *ExecuteCommandParams) error {
+	if params.Command != CommandEdit && params.Command != CommandGenerate {
+		return &ResponseError{Code: CodeInvalidParams, Message: "unknown command " + params.Command}
+	}
+	if len(params.Arguments) != 1 || params.Arguments[0].Request == "" {
+		return &ResponseError{Code: CodeInvalidParams, Message: "the command takes a document and a request"}
+	}
+	args := params.Arguments[0]
+	path, err := URIToPath(args.URI)
+	if err != nil {
+		return &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	text, _, err := s.text(args.URI, path)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", args.URI, err)
+	}
+
+	action := &Action{
+		Command: params.Command,
+		Request: args.Request,
+		File:    filemap.File{Name: filepath.Base(path), Path: s.relative(path), Content: text},
+	}
+	log.Printf("running %s on %s: %q\n", action.Command, action.File.Path, action.Request)
+	result, err := s.run(ctx, action)
+	if err != nil {
+		return err
+	}
+	edit, err := s.workspaceEdit(result)
+	if err != nil {
+		return err
+	}
+	if len(edit.DocumentChanges) == 0 {
+		return s.conn.Notify(MethodShowMessage, &ShowMessageParams{
+			Type:    MessageInfo,
+			Message: "copilot-ops proposed no changes",
+		})
+	}
+
+	var applied ApplyWorkspaceEditResult
+	apply := &ApplyWorkspaceEditParams{Label: "copilot-ops: " + action.Request, Edit: *edit}
+	if err = s.conn.Call(MethodApplyEdit, apply, &applied); err != nil {
+		return fmt.Errorf("could not apply the changes: %w", err)
+	}
+	if !applied.Applied {
+		return fmt.Errorf("the changes were not applied: %s", applied.FailureReason)
+	}
+	return nil
+}
+
+// workspaceEdit Returns the edit replacing every changed file with its new content,
+// and creating the new files. The files read at a git revision are left alone, since
+// their content is not the one of the workspace.
+func (s *Server) workspaceEdit(fm *filemap.Filemap) (*WorkspaceEdit, error) {
+	edit := &WorkspaceEdit{DocumentChanges: []interface{}{}}
+	for _, tag := range fm.Tags() {
+		file := fm.Files[tag]
+		if file.Rev != "" {
+			continue
+		}
+		if file.Path == "" {
+			file.Path = tag
+		}
+		path := file.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.root, path)
+		}
+		uri := PathToURI(path)
+		text, version, err := s.text(uri, path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			edit.DocumentChanges = append(edit.DocumentChanges,
+				CreateFile{Kind: "create", URI: uri, Options: CreateFileOptions{IgnoreIfExists: true}},
+				TextDocumentEdit{
+					TextDocument: VersionedTextDocumentIdentifier{URI: uri},
+					Edits:        []TextEdit{{NewText: file.Content}},
+				})
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("could not read %s: %w", file.Path, err)
+		case text == file.Content:
+			continue
+		}
+		edit.DocumentChanges = append(edit.DocumentChanges, TextDocumentEdit{
+			TextDocument: VersionedTextDocumentIdentifier{URI: uri, Version: version},
+			Edits:        []TextEdit{{Range: Range{End: EndPosition(text)}, NewText: file.Content}},
+		})
+	}
+	return edit, nil
+}
+
+// text Returns the text of the document as shown in the client, along with its version,
+// or the content of its file when it is not open.
+func (s *Server) text(uri, path string) (string, *int, error) {
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	s.mu.Unlock()
+	if ok {
+		version := doc.version
+		return doc.text, &version, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(content), nil, nil
+}
+
+// relative Returns the path relative to the root, unless it is outside of it.
+func (s *Server) relative(path string) string {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
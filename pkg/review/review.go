@@ -0,0 +1,208 @@
+// review asks a model to review a set of files, producing structured findings
+// which can be printed, exported as SARIF, and applied as fixes.
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/utils"
+)
+
+// Define the severities a finding can have, matching the levels used by SARIF.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+)
+
+// lineNumberFormat Is used to number the lines of each file sent for review.
+const lineNumberFormat = "%4d| %s"
+
+// SystemPrompt Instructs the model to answer with findings matching the Report type.
+const SystemPrompt = `You are an experienced site reliability engineer reviewing changes to a devops repository
+containing Kubernetes manifests, Kustomize overlays, Helm charts and other configuration.
+Look for bugs, security issues, reliability problems and deviations from best practices.
+Every line of the files is prefixed with its line number followed by '| ', which is not part of the content.
+
+Respond only with a JSON object of the following form, without any other text:
+
+{
+  "findings": [
+    {
+      "file": "tag of the file",
+      "line": 12,
+      "endLine": 14,
+      "severity": "error|warning|note",
+      "rule": "short-kebab-case-rule-id",
+      "message": "what is wrong and why it matters",
+      "fix": "the complete replacement for lines line through endLine, without line numbers"
+    }
+  ]
+}
+
+Omit "fix" when no change can be suggested. Respond with {"findings": []} if everything looks fine.`
+
+// Report Is the result of a review.
+type Report struct {
+	Findings []Finding `json:"findings"`
+}
+
+// Finding Is a single problem found during a review.
+type Finding struct {
+	// File Is the tag of the file in the filemap which was reviewed.
+	File string `json:"file"`
+	// Path Is the path of the file on disk.
+	Path string `json:"path"`
+	// Line Is the first line the finding refers to, starting at 1.
+	Line int `json:"line"`
+	// EndLine Is the last line the finding refers to.
+	EndLine  int    `json:"endLine,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+	// Fix Is the suggested replacement for the lines Line through EndLine.
+	Fix *string `json:"fix,omitempty"`
+}
+
+// PrepareMessages Builds the conversation which asks the model to review the files,
+// numbering their lines so that findings can point at them.
+func PrepareMessages(fm *filemap.Filemap) []ai.Message {
+	numbered := filemap.NewFilemap()
+	for tag, file := range fm.Files {
+		lines := strings.Split(strings.TrimSuffix(file.Content, "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = fmt.Sprintf(lineNumberFormat, i+1, line)
+		}
+		numbered.Files[tag] = filemap.File{Name: file.Name, Path: file.Path, Content: strings.Join(lines, "\n")}
+	}
+	content := fmt.Sprintf(
+		"Review the following files. Each file begins with a '# %stagname' line, and files are separated by '%s'.\n\n%s",
+		filemap.FileTagPrefix, filemap.FileDelimeter, numbered.EncodeToInputText(),
+	)
+	return []ai.Message{
+		{Role: ai.RoleSystem, Content: SystemPrompt},
+		{Role: ai.RoleUser, Content: content},
+	}
+}
+
+// Parse Decodes the findings contained in the model's answer, resolving the
+// tag of every finding to the path of the reviewed file.
+func Parse(answer string, fm *filemap.Filemap) (*Report, error) {
+	object, err := utils.ExtractJSON(answer)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	if err = json.Unmarshal([]byte(object), report); err != nil {
+		return nil, fmt.Errorf("could not parse review: %w", err)
+	}
+	for i := range report.Findings {
+		f := &report.Findings[i]
+		f.File = strings.TrimPrefix(f.File, filemap.FileTagPrefix)
+		f.Severity = NormalizeSeverity(f.Severity)
+		if f.EndLine < f.Line {
+			f.EndLine = f.Line
+		}
+		if file, ok := fm.Files[f.File]; ok {
+			f.Path = file.Path
+		} else if f.Path == "" {
+			f.Path = f.File
+		}
+	}
+	report.Sort()
+	return report, nil
+}
+
+// NormalizeSeverity Maps the various ways a model may describe a severity onto the supported severities.
+func NormalizeSeverity(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case SeverityError, "critical", "high", "blocker":
+		return SeverityError
+	case SeverityNote, "info", "low", "suggestion":
+		return SeverityNote
+	default:
+		return SeverityWarning
+	}
+}
+
+// Sort Orders the findings by path and line.
+func (r *Report) Sort() {
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+}
+
+// Text Renders the findings for humans, one finding per paragraph.
+func (r *Report) Text() string {
+	if len(r.Findings) == 0 {
+		return "no findings\n"
+	}
+	var b strings.Builder
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "%s:%d: %s [%s] %s\n", f.Path, f.Line, f.Severity, f.Rule, f.Message)
+		if f.Fix != nil {
+			b.WriteString("  suggested fix:\n")
+			for _, line := range strings.Split(strings.TrimSuffix(*f.Fix, "\n"), "\n") {
+				b.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n%d finding(s)\n", len(r.Findings))
+	return b.String()
+}
+
+// ApplyFixes Applies the suggested fixes to the files they refer to, returning a
+// filemap with only the files that were changed. Fixes which overlap a fix that
+// has already been applied to the same file are skipped.
+func ApplyFixes(fm *filemap.Filemap, findings []Finding) (*filemap.Filemap, error) {
+	byTag := make(map[string][]Finding)
+	for _, f := range findings {
+		if f.Fix == nil {
+			continue
+		}
+		if _, ok := fm.Files[f.File]; !ok {
+			return nil, fmt.Errorf("finding refers to unknown file %q", f.File)
+		}
+		byTag[f.File] = append(byTag[f.File], f)
+	}
+
+	fixed := filemap.NewFilemap()
+	for tag, fileFindings := range byTag {
+		// apply from the bottom up so that earlier line numbers stay valid
+		sort.SliceStable(fileFindings, func(i, j int) bool {
+			return fileFindings[i].Line > fileFindings[j].Line
+		})
+		file := fm.Files[tag]
+		hasTrailingNewline := strings.HasSuffix(file.Content, "\n")
+		lines := strings.Split(strings.TrimSuffix(file.Content, "\n"), "\n")
+		applied := len(lines) + 1
+		for _, f := range fileFindings {
+			if f.Line < 1 || f.EndLine > len(lines) || f.EndLine >= applied {
+				continue
+			}
+			replacement := strings.Split(strings.TrimSuffix(*f.Fix, "\n"), "\n")
+			if *f.Fix == "" {
+				replacement = nil
+			}
+			tail := append(replacement, lines[f.EndLine:]...)
+			lines = append(lines[:f.Line-1], tail...)
+			applied = f.Line
+		}
+		content := strings.Join(lines, "\n")
+		if hasTrailingNewline {
+			content += "\n"
+		}
+		file.Content = content
+		fixed.Files[tag] = file
+	}
+	return fixed, nil
+}
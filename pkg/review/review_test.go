@@ -0,0 +1,130 @@
+package review_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/review"
+)
+
+var _ = Describe("Review", func() {
+	const (
+		pvc = `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: mysql
+spec:
+  resources:
+    requests:
+      storage: 1Gi
+`
+		answer = "```json\n" + `{"findings": [
+  {"file": "@pvc", "line": 8, "severity": "low", "rule": "small-volume",
+   "message": "1Gi is too small for MySQL", "fix": "      storage: 20Gi"},
+  {"file": "pvc", "line": 3, "endLine": 4, "severity": "HIGH", "rule": "missing-namespace",
+   "message": "the PVC has no namespace", "fix": "metadata:\n  name: mysql\n  namespace: db"},
+  {"file": "pvc", "line": 1, "severity": "medium", "rule": "note", "message": "looks fine otherwise"}
+]}` + "\n```"
+	)
+
+	var fm *filemap.Filemap
+
+	BeforeEach(func() {
+		fm = filemap.NewFilemap()
+		fm.Files["pvc"] = filemap.File{Path: "./app/pvc.yaml", Content: pvc}
+	})
+
+	It("numbers the lines sent for review", func() {
+		messages := review.PrepareMessages(fm)
+		Expect(messages[1].Content).To(ContainSubstring("# @pvc"))
+		Expect(messages[1].Content).To(ContainSubstring("   8|       storage: 1Gi"))
+	})
+
+	It("parses and sorts findings", func() {
+		report, err := review.Parse(answer, fm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Findings).To(HaveLen(3))
+		Expect(report.Findings[0].Line).To(Equal(1))
+		Expect(report.Findings[0].Severity).To(Equal(review.SeverityWarning))
+		Expect(report.Findings[1].Severity).To(Equal(review.SeverityError))
+		Expect(report.Findings[2].File).To(Equal("pvc"))
+		Expect(report.Findings[2].Path).To(Equal("./app/pvc.yaml"))
+		Expect(report.Findings[2].EndLine).To(Equal(8))
+		Expect(report.Text()).To(ContainSubstring("./app/pvc.yaml:3: error [missing-namespace]"))
+	})
+
+	It("exports findings as SARIF", func() {
+		report, err := review.Parse(answer, fm)
+		Expect(err).NotTo(HaveOccurred())
+		sarif := report.SARIF()
+		Expect(sarif.Version).To(Equal(review.SARIFVersion))
+		Expect(sarif.Runs[0].Tool.Driver.Rules).To(HaveLen(3))
+		result := sarif.Runs[0].Results[1]
+		Expect(result.Level).To(Equal(review.SeverityError))
+		Expect(result.Locations[0].PhysicalLocation.ArtifactLocation.URI).To(Equal("app/pvc.yaml"))
+		Expect(result.Fixes[0].ArtifactChanges[0].Replacements[0].DeletedRegion.EndLine).To(Equal(4))
+
+		out, err := sarif.JSON()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ContainSubstring(`"$schema": "` + review.SARIFSchema + `"`))
+	})
+
+	It("gives findings without a rule the default rule in SARIF", func() {
+		report, err := review.Parse("```json\n"+`{"findings": [
+  {"file": "pvc", "line": 1, "severity": "low", "message": "no rule"},
+  {"file": "pvc", "line": 2, "severity": "low", "rule": " ", "message": "blank rule"}
+]}`+"\n```", fm)
+		Expect(err).NotTo(HaveOccurred())
+		sarif := report.SARIF()
+		Expect(sarif.Runs[0].Results).To(HaveLen(2))
+		for _, result := range sarif.Runs[0].Results {
+			Expect(result.RuleID).To(Equal(review.DefaultRuleID))
+		}
+		Expect(sarif.Runs[0].Tool.Driver.Rules).To(Equal([]review.SARIFRule{{
+			ID: review.DefaultRuleID, ShortDescription: review.SARIFMessage{Text: review.DefaultRuleDescription},
+		}}))
+	})
+
+	It("leaves the region out of the SARIF of findings without a line", func() {
+		report, err := review.Parse("```json\n"+`{"findings": [
+  {"file": "pvc", "line": 0, "severity": "low", "rule": "size", "message": "whole file", "fix": "kind: Pod"}
+]}`+"\n```", fm)
+		Expect(err).NotTo(HaveOccurred())
+		sarif := report.SARIF()
+		result := sarif.Runs[0].Results[0]
+		Expect(result.Locations[0].PhysicalLocation.Region).To(BeNil())
+		Expect(result.Fixes).To(BeEmpty())
+
+		out, err := sarif.JSON()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).NotTo(ContainSubstring("startLine"))
+	})
+
+	It("applies the suggested fixes", func() {
+		report, err := review.Parse(answer, fm)
+		Expect(err).NotTo(HaveOccurred())
+		fixed, err := review.ApplyFixes(fm, report.Findings)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fixed.Files).To(HaveLen(1))
+		Expect(fixed.Files["pvc"].Path).To(Equal("./app/pvc.yaml"))
+		Expect(fixed.Files["pvc"].Content).To(Equal(`apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: mysql
+  namespace: db
+spec:
+  resources:
+    requests:
+      storage: 20Gi
+`))
+		// the original files are left untouched
+		Expect(fm.Files["pvc"].Content).To(Equal(pvc))
+	})
+
+	It("refuses fixes for unknown files", func() {
+		fix := "x"
+		_, err := review.ApplyFixes(fm, []review.Finding{{File: "other", Line: 1, EndLine: 1, Fix: &fix}})
+		Expect(err).To(HaveOccurred())
+	})
+})
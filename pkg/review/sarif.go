@@ -0,0 +1,181 @@
+package review
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Define the values which identify copilot-ops in SARIF logs.
+const (
+	SARIFVersion = "2.1.0"
+	SARIFSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	ToolName     = "copilot-ops"
+	ToolURI      = "https://github.com/redhat-et/copilot-ops"
+	// DefaultRuleID Identifies the findings which the model did not give a rule for,
+	// since SARIF requires every result to name one.
+	DefaultRuleID = ToolName + "/finding"
+	// DefaultRuleDescription Describes the rule of the findings without one.
+	DefaultRuleDescription = "Finding reported by " + ToolName
+)
+
+// SARIFLog Is the root object of a SARIF file, containing only the
+// properties needed to report the findings of a review.
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun Is a single invocation of the reviewing tool.
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+// SARIFTool Describes the tool which produced the results.
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver Describes the tool's name and the rules it reports on.
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule Is a rule which at least one result was reported for.
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+// SARIFMessage Is a plain-text message.
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult Is a single finding.
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+	Fixes     []SARIFFix      `json:"fixes,omitempty"`
+}
+
+// SARIFLocation Points at the lines of a file a result refers to.
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation Is a region within a file, or the whole file when Region is nil.
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation Identifies a file by its URI relative to the repo root.
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion Is a range of lines.
+type SARIFRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+// SARIFFix Is a suggested fix for a result.
+type SARIFFix struct {
+	Description     SARIFMessage          `json:"description"`
+	ArtifactChanges []SARIFArtifactChange `json:"artifactChanges"`
+}
+
+// SARIFArtifactChange Describes the replacements made to a single file.
+type SARIFArtifactChange struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Replacements     []SARIFReplacement    `json:"replacements"`
+}
+
+// SARIFReplacement Replaces a region of a file with new content.
+type SARIFReplacement struct {
+	DeletedRegion   SARIFRegion  `json:"deletedRegion"`
+	InsertedContent SARIFMessage `json:"insertedContent"`
+}
+
+// SARIF Converts the report into a SARIF log which can be uploaded to code scanning.
+func (r *Report) SARIF() *SARIFLog {
+	rules := make(map[string]string)
+	results := make([]SARIFResult, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		ruleID := f.Rule
+		if strings.TrimSpace(ruleID) == "" {
+			ruleID = DefaultRuleID
+			rules[ruleID] = DefaultRuleDescription
+		}
+		if _, ok := rules[ruleID]; !ok {
+			rules[ruleID] = f.Message
+		}
+		location := SARIFArtifactLocation{URI: artifactURI(f.Path)}
+		// SARIF lines start at 1, so findings without a line refer to the whole file
+		var region *SARIFRegion
+		if f.Line >= 1 {
+			region = &SARIFRegion{StartLine: f.Line, EndLine: f.EndLine}
+		}
+		result := SARIFResult{
+			RuleID:  ruleID,
+			Level:   f.Severity,
+			Message: SARIFMessage{Text: f.Message},
+			Locations: []SARIFLocation{{
+				PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: location, Region: region},
+			}},
+		}
+		// a fix must name the lines it replaces, as ApplyFixes also requires
+		if f.Fix != nil && region != nil {
+			inserted := *f.Fix
+			if inserted != "" && !strings.HasSuffix(inserted, "\n") {
+				inserted += "\n"
+			}
+			result.Fixes = []SARIFFix{{
+				Description: SARIFMessage{Text: "Suggested by " + ToolName},
+				ArtifactChanges: []SARIFArtifactChange{{
+					ArtifactLocation: location,
+					Replacements: []SARIFReplacement{{
+						DeletedRegion:   *region,
+						InsertedContent: SARIFMessage{Text: inserted},
+					}},
+				}},
+			}}
+		}
+		results = append(results, result)
+	}
+
+	driver := SARIFDriver{Name: ToolName, InformationURI: ToolURI, Rules: []SARIFRule{}}
+	for id, description := range rules {
+		driver.Rules = append(driver.Rules, SARIFRule{ID: id, ShortDescription: SARIFMessage{Text: description}})
+	}
+	sort.Slice(driver.Rules, func(i, j int) bool { return driver.Rules[i].ID < driver.Rules[j].ID })
+
+	return &SARIFLog{
+		Schema:  SARIFSchema,
+		Version: SARIFVersion,
+		Runs:    []SARIFRun{{Tool: SARIFTool{Driver: driver}, Results: results}},
+	}
+}
+
+// JSON Encodes the SARIF log as indented JSON.
+func (l *SARIFLog) JSON() (string, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// artifactURI Returns the path as a URI relative to the repo root.
+func artifactURI(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+}
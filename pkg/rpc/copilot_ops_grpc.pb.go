@@ -0,0 +1,226 @@
+// The gRPC service of copilot-ops, served by `copilot-ops serve --grpc-listen`.
+// Its messages mirror the JSON bodies of the HTTP API described in pkg/api/openapi.yaml.
+//
+// Regenerate the Go code with `make proto` after changing this file.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: copilot_ops.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CopilotOps_Generate_FullMethodName = "/copilotops.v1.CopilotOps/Generate"
+	CopilotOps_Edit_FullMethodName     = "/copilotops.v1.CopilotOps/Edit"
+	CopilotOps_Ask_FullMethodName      = "/copilotops.v1.CopilotOps/Ask"
+)
+
+// CopilotOpsClient is the client API for CopilotOps service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CopilotOpsClient interface {
+	// Generate generates the files described by the request, using its files as context.
+	Generate(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// Edit edits the files of the request. Every completion which could be decoded is a
+	// result, the best one first, and the others are reported as warnings.
+	Edit(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error)
+	// Ask answers the question, with the files of the request as context. The answer is
+	// streamed as the backend generates it.
+	Ask(ctx context.Context, in *Request, opts ...grpc.CallOption) (CopilotOps_AskClient, error)
+}
+
+type copilotOpsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCopilotOpsClient(cc grpc.ClientConnInterface) CopilotOpsClient {
+	return &copilotOpsClient{cc}
+}
+
+func (c *copilotOpsClient) Generate(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, CopilotOps_Generate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *copilotOpsClient) Edit(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, CopilotOps_Edit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *copilotOpsClient) Ask(ctx context.Context, in *Request, opts ...grpc.CallOption) (CopilotOps_AskClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CopilotOps_ServiceDesc.Streams[0], CopilotOps_Ask_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &copilotOpsAskClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CopilotOps_AskClient interface {
+	Recv() (*AskResponse, error)
+	grpc.ClientStream
+}
+
+type copilotOpsAskClient struct {
+	grpc.ClientStream
+}
+
+func (x *copilotOpsAskClient) Recv() (*AskResponse, error) {
+	m := new(AskResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CopilotOpsServer is the server API for CopilotOps service.
+// All implementations must embed UnimplementedCopilotOpsServer
+// for forward compatibility
+type CopilotOpsServer interface {
+	// Generate generates the files described by the request, using its files as context.
+	Generate(context.Context, *Request) (*Result, error)
+	// Edit edits the files of the request. Every completion which could be decoded is a
+	// result, the best one first, and the others are reported as warnings.
+	Edit(context.Context, *Request) (*Result, error)
+	// Ask answers the question, with the files of the request as context. The answer is
+	// streamed as the backend generates it.
+	Ask(*Request, CopilotOps_AskServer) error
+	mustEmbedUnimplementedCopilotOpsServer()
+}
+
+// UnimplementedCopilotOpsServer must be embedded to have forward compatible implementations.
+type UnimplementedCopilotOpsServer struct {
+}
+
+func (UnimplementedCopilotOpsServer) Generate(context.Context, *Request) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (UnimplementedCopilotOpsServer) Edit(context.Context, *Request) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Edit not implemented")
+}
+func (UnimplementedCopilotOpsServer) Ask(*Request, CopilotOps_AskServer) error {
+	return status.Errorf(codes.Unimplemented, "method Ask not implemented")
+}
+func (UnimplementedCopilotOpsServer) mustEmbedUnimplementedCopilotOpsServer() {}
+
+// UnsafeCopilotOpsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CopilotOpsServer will
+// result in compilation errors.
+type UnsafeCopilotOpsServer interface {
+	mustEmbedUnimplementedCopilotOpsServer()
+}
+
+func RegisterCopilotOpsServer(s grpc.ServiceRegistrar, srv CopilotOpsServer) {
+	s.RegisterService(&CopilotOps_ServiceDesc, srv)
+}
+
+func _CopilotOps_Generate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CopilotOpsServer).Generate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CopilotOps_Generate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CopilotOpsServer).Generate(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CopilotOps_Edit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CopilotOpsServer).Edit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CopilotOps_Edit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CopilotOpsServer).Edit(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CopilotOps_Ask_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Request)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CopilotOpsServer).Ask(m, &copilotOpsAskServer{stream})
+}
+
+type CopilotOps_AskServer interface {
+	Send(*AskResponse) error
+	grpc.ServerStream
+}
+
+type copilotOpsAskServer struct {
+	grpc.ServerStream
+}
+
+func (x *copilotOpsAskServer) Send(m *AskResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// CopilotOps_ServiceDesc is the grpc.ServiceDesc for CopilotOps service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CopilotOps_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "copilotops.v1.CopilotOps",
+	HandlerType: (*CopilotOpsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Generate",
+			Handler:    _CopilotOps_Generate_Handler,
+		},
+		{
+			MethodName: "Edit",
+			Handler:    _CopilotOps_Edit_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Ask",
+			Handler:       _CopilotOps_Ask_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "copilot_ops.proto",
+}
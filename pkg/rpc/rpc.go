@@ -0,0 +1,2 @@
+// rpc holds the code generated from copilot_ops.proto, which defines the gRPC service of copilot-ops.
+package rpc
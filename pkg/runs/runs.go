@@ -0,0 +1,147 @@
+// runs records the requests made through edit and generate, so that a
+// previous result can be refined by a follow-up request.
+package runs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+const (
+	// RunsDir Is the directory, relative to the repo root, in which runs are saved.
+	RunsDir = config.StateDir + "/runs"
+	// IDFormat Is the time layout used to name runs, which keeps them sortable by creation time.
+	IDFormat = "20060102-150405.000000"
+)
+
+// ErrNoRuns Is returned when a previous run was requested but none has been recorded.
+var ErrNoRuns = errors.New("no previous runs were found")
+
+// IDPattern Matches the IDs runs may have, which keeps their files within the runs directory.
+var IDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Run Is the record of a single request made to an AI backend.
+type Run struct {
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Command   string     `json:"command"`
+	Backend   ai.Backend `json:"backend"`
+	Model     string     `json:"model,omitempty"`
+	// Parent Is the ID of the run which this run refines, if any.
+	Parent string `json:"parent,omitempty"`
+	// Request Is the user's request in natural language.
+	Request string `json:"request"`
+	// Prompt Is the prompt or instruction which was sent to the backend.
+	Prompt string `json:"prompt"`
+	// Input Is the encoded files sent along with an edit instruction.
+	Input string `json:"input,omitempty"`
+	// Responses Are the raw responses returned by the backend.
+	Responses []string `json:"responses"`
+	// Before Holds the files as they were loaded, before the request was made.
+	Before *filemap.Filemap `json:"before"`
+	// After Holds the files as they were decoded from the response.
+	After *filemap.Filemap `json:"after"`
+}
+
+// NewRun Returns a new run for the given command, identified by the current time.
+func NewRun(command string) *Run {
+	now := time.Now()
+	return &Run{
+		ID:        now.Format(IDFormat),
+		CreatedAt: now,
+		Command:   command,
+	}
+}
+
+// Path Returns the path of the file the run with the given ID is stored in.
+func Path(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// Save Writes the run to the directory, creating the directory if necessary.
+func (r *Run) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(Path(dir, r.ID), data, 0644)
+}
+
+// ValidateID Returns an error if the ID is not one a run may have. The IDs of the
+// entries of the journal, which are those of the runs that wrote them, are the same.
+func ValidateID(id string) error {
+	if !IDPattern.MatchString(id) || id == "." || id == ".." {
+		return fmt.Errorf("invalid ID %q: use only letters, digits, '.', '_' and '-'", id)
+	}
+	return nil
+}
+
+// Load Reads the run with the given ID from the directory.
+func Load(dir, id string) (*Run, error) {
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(Path(dir, id))
+	if err != nil {
+		return nil, err
+	}
+	r := &Run{}
+	if err = json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("could not parse run %q: %w", id, err)
+	}
+	return r, nil
+}
+
+// List Returns the IDs of all runs in the directory, oldest first.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Latest Returns the most recently recorded run in the directory.
+func Latest(dir string) (*Run, error) {
+	ids, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoRuns
+	}
+	return Load(dir, ids[len(ids)-1])
+}
+
+// LoadOrLatest Returns the run with the given ID, or the latest run if the ID is empty.
+func LoadOrLatest(dir, id string) (*Run, error) {
+	if id == "" {
+		return Latest(dir)
+	}
+	return Load(dir, id)
+}
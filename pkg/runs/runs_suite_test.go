@@ -0,0 +1,13 @@
+package runs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRuns(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Runs Suite")
+}
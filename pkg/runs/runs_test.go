@@ -0,0 +1,65 @@
+package runs_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/runs"
+)
+
+var _ = Describe("Runs", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("reports when there are no runs", func() {
+		_, err := runs.Latest(dir)
+		Expect(err).To(MatchError(runs.ErrNoRuns))
+		ids, err := runs.List(dir + "/missing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids).To(BeEmpty())
+	})
+
+	It("saves runs and finds the latest one", func() {
+		first := runs.NewRun("edit")
+		first.ID = "20230101-000000.000000"
+		first.Request = "increase the PVC size"
+		first.After = filemap.NewFilemap()
+		first.After.Files["pvc"] = filemap.File{Path: "app/pvc.yaml", Content: "storage: 100Gi"}
+		Expect(first.Save(dir)).To(Succeed())
+
+		second := runs.NewRun("refine")
+		second.ID = "20230102-000000.000000"
+		second.Parent = first.ID
+		Expect(second.Save(dir)).To(Succeed())
+
+		ids, err := runs.List(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids).To(Equal([]string{first.ID, second.ID}))
+
+		latest, err := runs.LoadOrLatest(dir, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(latest.Parent).To(Equal(first.ID))
+
+		loaded, err := runs.LoadOrLatest(dir, first.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Request).To(Equal("increase the PVC size"))
+		Expect(loaded.After.Files).To(HaveKeyWithValue("pvc", first.After.Files["pvc"]))
+	})
+
+	It("refuses IDs which would leave the directory", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "secret.json"), []byte(`{"request": "leaked"}`), 0o600)).To(Succeed())
+		runsDir := filepath.Join(dir, "runs")
+		for _, id := range []string{"../secret", "..", "a/b", ""} {
+			_, err := runs.Load(runsDir, id)
+			Expect(err).To(MatchError(ContainSubstring("invalid ID")), id)
+		}
+		Expect(runs.ValidateID("20230101-000000.000000")).To(Succeed())
+	})
+})
@@ -0,0 +1,317 @@
+// scaffold scans a repo for the kinds of configuration copilot-ops works with,
+// and renders a commented config file with a fileset for each of them.
+package scaffold
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Kind Is the type of configuration found in a directory.
+type Kind string
+
+// Define the kinds of directories which are detected.
+const (
+	KindKustomize Kind = "kustomize"
+	KindHelm      Kind = "helm"
+	KindTerraform Kind = "terraform"
+	KindManifests Kind = "manifests"
+)
+
+// Define the names of the files created by the scaffold.
+const (
+	LocalConfigFile = config.ConfigFileLocal + ".yaml"
+	GitignoreFile   = ".gitignore"
+)
+
+// skippedDirs Are never scanned, in addition to hidden directories.
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// Detection Is a directory holding configuration which is proposed as a fileset.
+type Detection struct {
+	Name string
+	Kind Kind
+	// Dir Is the directory relative to the scanned root, using forward slashes.
+	Dir   string
+	Globs []string
+}
+
+// Scan Walks the repo at root, detecting kustomizations, Helm charts, Terraform modules
+// and folders of plain Kubernetes manifests. Helm charts are not scanned any further,
+// since their subdirectories belong to the chart.
+func Scan(root string) ([]Detection, error) {
+	var detections []Detection
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()]) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		detection, ok, err := detectDir(p, filepath.ToSlash(rel))
+		if err != nil || !ok {
+			return err
+		}
+		detections = append(detections, detection)
+		if detection.Kind == KindHelm {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	nameDetections(detections)
+	return detections, nil
+}
+
+// detectDir Classifies a single directory by the files it directly contains.
+func detectDir(dir, rel string) (Detection, bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Detection{}, false, err
+	}
+	files := make(map[string]bool)
+	extensions := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		files[entry.Name()] = true
+		extensions[strings.ToLower(filepath.Ext(entry.Name()))] = true
+	}
+
+	d := Detection{Dir: rel}
+	switch {
+	case files["Chart.yaml"]:
+		d.Kind = KindHelm
+		d.Globs = []string{join(rel, "Chart.yaml"), join(rel, "values*.yaml"), join(rel, "templates/*")}
+	case files["kustomization.yaml"] || files["kustomization.yml"] || files["Kustomization"]:
+		d.Kind = KindKustomize
+		d.Globs = yamlGlobs(rel, extensions)
+		if files["Kustomization"] {
+			d.Globs = append(d.Globs, join(rel, "Kustomization"))
+		}
+	case extensions[".tf"]:
+		d.Kind = KindTerraform
+		d.Globs = []string{join(rel, "*.tf")}
+		if extensions[".tfvars"] {
+			d.Globs = append(d.Globs, join(rel, "*.tfvars"))
+		}
+	default:
+		manifests, err := hasManifests(dir, entries)
+		if err != nil || !manifests {
+			return Detection{}, false, err
+		}
+		d.Kind = KindManifests
+		d.Globs = yamlGlobs(rel, extensions)
+	}
+	return d, true, nil
+}
+
+// hasManifests Reports whether any YAML file in the directory looks like a Kubernetes manifest.
+func hasManifests(dir string, entries []fs.DirEntry) (bool, error) {
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return false, err
+		}
+		if strings.Contains(string(content), "apiVersion:") && strings.Contains(string(content), "kind:") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// yamlGlobs Returns a glob for each YAML extension used in the directory.
+func yamlGlobs(rel string, extensions map[string]bool) []string {
+	var globs []string
+	for _, ext := range []string{".yaml", ".yml"} {
+		if extensions[ext] {
+			globs = append(globs, join(rel, "*"+ext))
+		}
+	}
+	return globs
+}
+
+// join Joins a glob to a directory relative to the repo root.
+func join(rel, glob string) string {
+	if rel == "." {
+		return glob
+	}
+	return path.Join(rel, glob)
+}
+
+// nameDetections Names every detection after its directory, falling back to the
+// full path when several directories share the same name.
+func nameDetections(detections []Detection) {
+	counts := make(map[string]int)
+	for _, d := range detections {
+		counts[baseName(d.Dir)]++
+	}
+	for i := range detections {
+		name := baseName(detections[i].Dir)
+		if counts[name] > 1 {
+			name = strings.ReplaceAll(detections[i].Dir, "/", "-")
+		}
+		detections[i].Name = name
+	}
+}
+
+// baseName Returns the name of the directory, naming the repo root "root".
+func baseName(dir string) string {
+	if dir == "." {
+		return "root"
+	}
+	return path.Base(dir)
+}
+
+// Backend Is the backend detected from the environment.
+type Backend struct {
+	Backend ai.Backend
+	// Env Lists the variables which were found, without their values.
+	Env []string
+}
+
+// DetectBackend Picks a backend based on the environment variables which are set,
+// preferring an explicit COPILOT_OPS_BACKEND, then OpenAI, then Ollama.
+func DetectBackend(getenv func(string) string) Backend {
+	var found []string
+	for _, env := range []string{"COPILOT_OPS_BACKEND", "OPENAI_API_KEY", "OPENAI_ORG_ID", "OPENAI_URL", "OLLAMA_HOST", "OLLAMA_MODEL"} {
+		if getenv(env) != "" {
+			found = append(found, env)
+		}
+	}
+	b := Backend{Env: found}
+	switch {
+	case getenv("COPILOT_OPS_BACKEND") != "":
+		b.Backend = ai.Backend(getenv("COPILOT_OPS_BACKEND"))
+	case getenv("OPENAI_API_KEY") != "":
+		b.Backend = ai.GPT3
+	case getenv("OLLAMA_HOST") != "" || getenv("OLLAMA_MODEL") != "":
+		b.Backend = ai.OLLAMA
+	default:
+		b.Backend = ai.GPT3
+	}
+	return b
+}
+
+// Render Writes the commented contents of the config file for the detections.
+func Render(detections []Detection, backend Backend) string {
+	var b strings.Builder
+	b.WriteString("# copilot-ops configuration, generated by `copilot-ops init`.\n")
+	b.WriteString("# Secrets such as API keys belong in " + LocalConfigFile + ", which is ignored by git,\n")
+	b.WriteString("# or in environment variables.\n\n")
+
+	b.WriteString("# backend selects the AI backend: gpt-3 or ollama.\n")
+	if len(backend.Env) > 0 {
+		fmt.Fprintf(&b, "# Detected from the environment: %s.\n", strings.Join(backend.Env, ", "))
+	}
+	fmt.Fprintf(&b, "backend: %s\n\n", scalar(string(backend.Backend)))
+
+	if backend.Backend == ai.OLLAMA {
+		b.WriteString("# ollama configures a local Ollama-compatible server (OLLAMA_HOST and OLLAMA_MODEL also work).\n")
+		b.WriteString("# ollama:\n#   url: http://localhost:11434\n#   model: codellama\n\n")
+	} else {
+		b.WriteString("# openAI configures the OpenAI API; the API key is read from OPENAI_API_KEY or " + LocalConfigFile + ".\n")
+		b.WriteString("# openAI:\n#   url: https://api.openai.com/v1\n\n")
+	}
+
+	b.WriteString("# filesets name groups of files which can be sent along with a request using --fileset.\n")
+	if len(detections) == 0 {
+		b.WriteString("# No kustomizations, Helm charts, Terraform modules or manifests were found.\n")
+		b.WriteString("filesets: []\n")
+		return b.String()
+	}
+	b.WriteString("filesets:\n")
+	for _, d := range detections {
+		fmt.Fprintf(&b, "  # %s in %s\n", describe(d.Kind), d.Dir)
+		fmt.Fprintf(&b, "  - name: %s\n    files:\n", scalar(d.Name))
+		for _, glob := range d.Globs {
+			fmt.Fprintf(&b, "      - %s\n", scalar(glob))
+		}
+	}
+	return b.String()
+}
+
+// RenderLocal Writes the contents of the local config file, which holds secrets.
+func RenderLocal(backend Backend) string {
+	var b strings.Builder
+	b.WriteString("# Local copilot-ops configuration, merged on top of " + config.ConfigFile + ".\n")
+	b.WriteString("# This file is ignored by git, so it is the place for secrets.\n")
+	if backend.Backend == ai.OLLAMA {
+		b.WriteString("# ollama:\n#   url: http://localhost:11434\n")
+		return b.String()
+	}
+	b.WriteString("# openAI:\n#   apiKey: sk-...\n#   orgID: org-...\n")
+	return b.String()
+}
+
+// describe Returns a human readable description of the kind.
+func describe(kind Kind) string {
+	switch kind {
+	case KindKustomize:
+		return "Kustomization"
+	case KindHelm:
+		return "Helm chart"
+	case KindTerraform:
+		return "Terraform module"
+	case KindManifests:
+		return "Kubernetes manifests"
+	default:
+		return string(kind)
+	}
+}
+
+// scalar Formats the value as a YAML scalar, quoting it only when necessary.
+func scalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// EnsureGitignore Adds the entry to the .gitignore file in dir, creating the file
+// if needed. It returns false if the entry was already present.
+func EnsureGitignore(dir, entry string) (bool, error) {
+	p := filepath.Join(dir, GitignoreFile)
+	content, err := os.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == entry || line == "/"+entry {
+			return false, nil
+		}
+	}
+	text := string(content)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	text += entry + "\n"
+	return true, os.WriteFile(p, []byte(text), 0644)
+}
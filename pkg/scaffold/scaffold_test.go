@@ -0,0 +1,105 @@
+package scaffold_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/scaffold"
+)
+
+const manifest = "apiVersion: v1\nkind: ConfigMap\n"
+
+// env Returns a getenv function backed by the given map.
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+var _ = Describe("Scaffold", func() {
+	var root string
+
+	// write Creates the file below root, along with its directories.
+	write := func(name, content string) {
+		p := filepath.Join(root, name)
+		Expect(os.MkdirAll(filepath.Dir(p), 0755)).To(Succeed())
+		Expect(os.WriteFile(p, []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		write("apps/web/base/kustomization.yaml", "resources: [deployment.yaml]\n")
+		write("apps/web/base/deployment.yaml", manifest)
+		write("apps/api/base/kustomization.yml", "resources: []\n")
+		write("charts/db/Chart.yaml", "name: db\n")
+		write("charts/db/templates/statefulset.yaml", manifest)
+		write("charts/db/templates/nested/kustomization.yaml", "resources: []\n")
+		write("infra/network/main.tf", "resource \"x\" \"y\" {}\n")
+		write("infra/network/prod.tfvars", "a = 1\n")
+		write("manifests/pod.yml", manifest)
+		write("docs/notes.yaml", "title: not a manifest\n")
+		write(".github/workflows/ci.yaml", manifest)
+		write("node_modules/pkg/pod.yaml", manifest)
+	})
+
+	It("detects every kind of configuration", func() {
+		detections, err := scaffold.Scan(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(detections).To(Equal([]scaffold.Detection{
+			{Name: "apps-api-base", Kind: scaffold.KindKustomize, Dir: "apps/api/base", Globs: []string{"apps/api/base/*.yml"}},
+			{Name: "apps-web-base", Kind: scaffold.KindKustomize, Dir: "apps/web/base", Globs: []string{"apps/web/base/*.yaml"}},
+			{Name: "db", Kind: scaffold.KindHelm, Dir: "charts/db", Globs: []string{
+				"charts/db/Chart.yaml", "charts/db/values*.yaml", "charts/db/templates/*",
+			}},
+			{Name: "network", Kind: scaffold.KindTerraform, Dir: "infra/network", Globs: []string{
+				"infra/network/*.tf", "infra/network/*.tfvars",
+			}},
+			{Name: "manifests", Kind: scaffold.KindManifests, Dir: "manifests", Globs: []string{"manifests/*.yml"}},
+		}))
+	})
+
+	It("detects the backend from the environment", func() {
+		Expect(scaffold.DetectBackend(env(nil)).Backend).To(Equal(ai.GPT3))
+		Expect(scaffold.DetectBackend(env(map[string]string{"OLLAMA_HOST": "gpu:11434"}))).To(Equal(scaffold.Backend{
+			Backend: ai.OLLAMA, Env: []string{"OLLAMA_HOST"},
+		}))
+		b := scaffold.DetectBackend(env(map[string]string{"OPENAI_API_KEY": "sk-secret", "COPILOT_OPS_BACKEND": "ollama"}))
+		Expect(b.Backend).To(Equal(ai.OLLAMA))
+		Expect(b.Env).To(Equal([]string{"COPILOT_OPS_BACKEND", "OPENAI_API_KEY"}))
+	})
+
+	It("renders a config which loads the detected filesets", func() {
+		detections, err := scaffold.Scan(root)
+		Expect(err).NotTo(HaveOccurred())
+		backend := scaffold.DetectBackend(env(map[string]string{"OPENAI_API_KEY": "sk-secret"}))
+		rendered := scaffold.Render(detections, backend)
+		Expect(rendered).To(ContainSubstring("# Helm chart in charts/db\n"))
+		Expect(rendered).To(ContainSubstring("OPENAI_API_KEY"))
+		Expect(rendered).NotTo(ContainSubstring("sk-secret"))
+
+		var conf config.Config
+		Expect(yaml.Unmarshal([]byte(rendered), &conf)).To(Succeed())
+		Expect(conf.Backend).To(Equal(ai.GPT3))
+		Expect(conf.Filesets).To(HaveLen(len(detections)))
+		Expect(conf.FindFileset("db").Files).To(ContainElement("charts/db/templates/*"))
+	})
+
+	It("renders an empty list of filesets for empty repos", func() {
+		var conf config.Config
+		Expect(yaml.Unmarshal([]byte(scaffold.Render(nil, scaffold.Backend{Backend: ai.GPT3})), &conf)).To(Succeed())
+		Expect(conf.Filesets).To(BeEmpty())
+	})
+
+	It("adds entries to .gitignore only once", func() {
+		write(".gitignore", "/bin")
+		Expect(scaffold.EnsureGitignore(root, scaffold.LocalConfigFile)).To(BeTrue())
+		Expect(scaffold.EnsureGitignore(root, scaffold.LocalConfigFile)).To(BeFalse())
+		content, err := os.ReadFile(filepath.Join(root, scaffold.GitignoreFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("/bin\n" + scaffold.LocalConfigFile + "\n"))
+	})
+})
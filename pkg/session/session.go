@@ -0,0 +1,130 @@
+// session persists the conversations held through `copilot-ops chat`
+// so that they can be resumed later.
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/cmd/config"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+)
+
+const (
+	// SessionsDir Is the directory, relative to the repo root, in which sessions are saved.
+	SessionsDir = config.StateDir + "/sessions"
+	// IDFormat Is the time layout used to name new sessions.
+	IDFormat = "20060102-150405"
+)
+
+// IDPattern Matches the IDs sessions may have, which keeps their files within the sessions directory.
+var IDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Session Is a conversation with a chat model along with the files attached to it.
+type Session struct {
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	Backend   ai.Backend `json:"backend"`
+	Model     string     `json:"model,omitempty"`
+	// SystemPrompt Is sent ahead of the conversation on every turn.
+	SystemPrompt string `json:"systemPrompt"`
+	// Messages Holds the conversation in the order it took place.
+	Messages []ai.Message `json:"messages"`
+	// Files Are the files attached to the conversation.
+	Files *filemap.Filemap `json:"files"`
+}
+
+// NewSession Returns an empty session. If no ID is given, one is derived from the current time.
+func NewSession(id string) *Session {
+	now := time.Now()
+	if id == "" {
+		id = now.Format(IDFormat)
+	}
+	return &Session{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Files:     filemap.NewFilemap(),
+	}
+}
+
+// Path Returns the path of the file the session with the given ID is stored in.
+func Path(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// ValidateID Returns an error if the ID is not one a session may have.
+func ValidateID(id string) error {
+	if !IDPattern.MatchString(id) || id == "." || id == ".." {
+		return fmt.Errorf("invalid session %q: use only letters, digits, '.', '_' and '-'", id)
+	}
+	return nil
+}
+
+// Load Reads the session with the given ID from the directory.
+func Load(dir, id string) (*Session, error) {
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(Path(dir, id))
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("could not parse session %q: %w", id, err)
+	}
+	if s.Files == nil || s.Files.Files == nil {
+		s.Files = filemap.NewFilemap()
+	}
+	return s, nil
+}
+
+// LoadOrCreate Resumes the session with the given ID if it exists, otherwise a new one is created.
+func LoadOrCreate(dir, id string) (*Session, error) {
+	if id == "" {
+		return NewSession(""), nil
+	}
+	s, err := Load(dir, id)
+	if os.IsNotExist(err) {
+		return NewSession(id), nil
+	}
+	return s, err
+}
+
+// Save Writes the session to the directory, creating the directory if necessary.
+func (s *Session) Save(dir string) error {
+	if err := ValidateID(s.ID); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(Path(dir, s.ID), data, 0644)
+}
+
+// AddMessage Appends a message to the conversation.
+func (s *Session) AddMessage(role, content string) {
+	s.Messages = append(s.Messages, ai.Message{Role: role, Content: content})
+}
+
+// LastAnswer Returns the most recent reply from the model, or an empty string if there is none.
+func (s *Session) LastAnswer() string {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == ai.RoleAssistant {
+			return s.Messages[i].Content
+		}
+	}
+	return ""
+}
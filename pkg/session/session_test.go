@@ -0,0 +1,60 @@
+package session_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/ai"
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/session"
+)
+
+var _ = Describe("Session", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("creates new sessions with an ID", func() {
+		s := session.NewSession("")
+		Expect(s.ID).NotTo(BeEmpty())
+		Expect(s.Files).NotTo(BeNil())
+		Expect(s.LastAnswer()).To(BeEmpty())
+	})
+
+	It("saves and resumes sessions", func() {
+		s := session.NewSession("ops")
+		s.Backend = ai.OLLAMA
+		s.AddMessage(ai.RoleUser, "hello")
+		s.AddMessage(ai.RoleAssistant, "hi there")
+		s.Files.Files["pvc"] = filemap.File{Path: "app/pvc.yaml", Content: "kind: PersistentVolumeClaim"}
+		Expect(s.Save(dir)).To(Succeed())
+
+		resumed, err := session.LoadOrCreate(dir, "ops")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Backend).To(Equal(ai.OLLAMA))
+		Expect(resumed.Messages).To(Equal(s.Messages))
+		Expect(resumed.LastAnswer()).To(Equal("hi there"))
+		Expect(resumed.Files.Files).To(HaveKeyWithValue("pvc", s.Files.Files["pvc"]))
+	})
+
+	It("creates a session when the ID does not exist yet", func() {
+		s, err := session.LoadOrCreate(dir, "missing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.ID).To(Equal("missing"))
+		Expect(s.Messages).To(BeEmpty())
+
+		_, err = session.Load(dir, "missing")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("refuses IDs which would leave the sessions directory", func() {
+		for _, id := range []string{"../../x", "a/b", ".."} {
+			_, err := session.LoadOrCreate(dir, id)
+			Expect(err).To(MatchError(ContainSubstring("invalid session")), id)
+			Expect(session.NewSession(id).Save(dir)).To(MatchError(ContainSubstring("invalid session")), id)
+		}
+		Expect(session.ValidateID("ops-2024_01.v2")).To(Succeed())
+	})
+})
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StripCodeFences Removes the Markdown code fences (```yaml ... ```) which chat
+// models like to wrap around their output, leaving the fenced content in place.
+func StripCodeFences(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// ExtractJSON Returns the outermost JSON object contained in the content, ignoring
+// any prose or code fences the model has added around it.
+func ExtractJSON(content string) (string, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start == -1 || end < start {
+		return "", fmt.Errorf("no JSON object found in content")
+	}
+	return content[start : end+1], nil
+}
+
+// CharsPerToken Is the average number of characters in a token for English text and code,
+// as a rule of thumb for OpenAI's tokenizers.
+const CharsPerToken = 4
+
+// EstimateTokens Returns a rough estimate of the number of tokens the text will use.
+func EstimateTokens(text string) int {
+	return (len(text) + CharsPerToken - 1) / CharsPerToken
+}
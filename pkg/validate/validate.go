@@ -0,0 +1,88 @@
+// validate checks that the files proposed by a model can be parsed, so that
+// broken output can be detected before it is written to the repo.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"gopkg.in/yaml.v3"
+)
+
+// Result Is the outcome of validating a single file.
+type Result struct {
+	Path string `json:"path"`
+	// Error Describes why the file is invalid, and is empty for valid files.
+	Error string `json:"error,omitempty"`
+}
+
+// Valid Reports whether the file passed validation.
+func (r Result) Valid() bool {
+	return r.Error == ""
+}
+
+// Supported Reports whether files at the given path can be validated, which
+// are YAML and JSON files, as well as files without an extension.
+func Supported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json", "":
+		return true
+	default:
+		return false
+	}
+}
+
+// File Checks that every document in the content is valid YAML, which includes JSON.
+func File(content string) error {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for i := 1; ; i++ {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("document %d: %w", i, err)
+		}
+	}
+}
+
+// Filemap Validates every supported file in the filemap, ordered by path.
+func Filemap(fm *filemap.Filemap) []Result {
+	var results []Result
+	for tag, file := range fm.Files {
+		path := file.Path
+		if path == "" {
+			path = tag
+		}
+		if !Supported(path) {
+			continue
+		}
+		result := Result{Path: path}
+		if err := File(file.Content); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	return results
+}
+
+// PassRate Returns the fraction of results which are valid, which is 1 when there are no results.
+func PassRate(results []Result) float64 {
+	if len(results) == 0 {
+		return 1
+	}
+	passed := 0
+	for _, r := range results {
+		if r.Valid() {
+			passed++
+		}
+	}
+	return float64(passed) / float64(len(results))
+}
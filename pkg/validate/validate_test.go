@@ -0,0 +1,38 @@
+package validate_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/filemap"
+	"github.com/redhat-et/copilot-ops/pkg/validate"
+)
+
+var _ = Describe("Validate", func() {
+	It("accepts multi-document YAML and JSON", func() {
+		Expect(validate.File("kind: Pod\n---\nkind: Service\n")).To(Succeed())
+		Expect(validate.File(`{"kind": "Pod"}`)).To(Succeed())
+		Expect(validate.File("")).To(Succeed())
+	})
+
+	It("reports the invalid document", func() {
+		err := validate.File("kind: Pod\n---\nkind: [Service\n")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("document 2:"))
+	})
+
+	It("validates the supported files of a filemap", func() {
+		fm := filemap.NewFilemap()
+		fm.Files["pod"] = filemap.File{Path: "pod.yaml", Content: "kind: Pod\n"}
+		fm.Files["bad"] = filemap.File{Path: "bad.yml", Content: "a: b: c\n"}
+		fm.Files["script"] = filemap.File{Path: "run.sh", Content: "echo: [\n"}
+
+		results := validate.Filemap(fm)
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].Path).To(Equal("bad.yml"))
+		Expect(results[0].Valid()).To(BeFalse())
+		Expect(results[1]).To(Equal(validate.Result{Path: "pod.yaml"}))
+		Expect(validate.PassRate(results)).To(Equal(0.5))
+		Expect(validate.PassRate(nil)).To(Equal(1.0))
+	})
+})
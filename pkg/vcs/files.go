@@ -0,0 +1,231 @@
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// Repo Is the git repo containing a directory. Paths given to and returned by
+// its methods are relative to that directory, while git paths are relative to
+// the root of the working tree and slash-separated.
+type Repo struct {
+	repo *git.Repository
+	wt   *git.Worktree
+	// dir Is the absolute path of the directory, with symlinks resolved like the root.
+	dir string
+}
+
+// Open Opens the git repo containing dir.
+func Open(dir string) (*Repo, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("could not open the git repo of %s: %w", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, err
+	}
+	return &Repo{repo: repo, wt: wt, dir: abs}, nil
+}
+
+// local Returns the path of the git path relative to the directory, or false
+// when the path is outside of the directory.
+func (r *Repo) local(gitPath string) (string, bool) {
+	rel, err := filepath.Rel(r.dir, filepath.Join(r.wt.Filesystem.Root(), filepath.FromSlash(gitPath)))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// Rel Returns the path of a file, relative to the working directory or absolute,
+// relative to the directory as the paths returned by the repo's methods are.
+func (r *Repo) Rel(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	// the directory is resolved, so the path is resolved too before comparing them
+	if dir, evalErr := filepath.EvalSymlinks(filepath.Dir(abs)); evalErr == nil {
+		abs = filepath.Join(dir, filepath.Base(abs))
+	}
+	return filepath.Rel(r.dir, abs)
+}
+
+// localFiles Returns the git paths which are inside of the directory and exist in
+// the working tree, relative to the directory and sorted.
+func (r *Repo) localFiles(gitPaths map[string]bool) []string {
+	paths := make([]string, 0, len(gitPaths))
+	for gitPath := range gitPaths {
+		p, ok := r.local(gitPath)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(r.dir, p)); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Tracked Returns the paths of the files in the index, i.e. which git tracks.
+func (r *Repo) Tracked() (map[string]bool, error) {
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the git index: %w", err)
+	}
+	tracked := make(map[string]bool, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		if p, ok := r.local(entry.Name); ok {
+			tracked[p] = true
+		}
+	}
+	return tracked, nil
+}
+
+// Staged Returns the paths of the files with staged changes, except deleted ones.
+func (r *Repo) Staged() ([]string, error) {
+	status, err := r.wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the status of the git repo: %w", err)
+	}
+	staged := make(map[string]bool)
+	for gitPath, file := range status {
+		switch file.Staging {
+		case git.Added, git.Modified, git.Renamed, git.Copied:
+			staged[gitPath] = true
+		case git.Unmodified, git.Untracked, git.Deleted, git.UpdatedButUnmerged:
+		}
+	}
+	return r.localFiles(staged), nil
+}
+
+// ChangedSince Returns the paths of the files a pull request from the working tree
+// into rev would touch: the files changed by the commits since HEAD forked from rev,
+// along with the files which are modified in the index or the working tree. Deleted
+// and untracked files are left out.
+func (r *Repo) ChangedSince(rev string) ([]string, error) {
+	base, err := r.commit(rev)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.commit(plumbing.HEAD.String())
+	if err != nil {
+		return nil, err
+	}
+	if bases, mergeErr := head.MergeBase(base); mergeErr == nil && len(bases) > 0 {
+		base = bases[0]
+	}
+	baseTree, err := base.Tree()
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := head.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(baseTree, headTree)
+	if err != nil {
+		return nil, fmt.Errorf("could not compare %s to HEAD: %w", rev, err)
+	}
+
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		action, actionErr := change.Action()
+		if actionErr != nil {
+			return nil, actionErr
+		}
+		if action != merkletrie.Delete {
+			changed[change.To.Name] = true
+		}
+	}
+	status, err := r.wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the status of the git repo: %w", err)
+	}
+	for gitPath, file := range status {
+		if file.Worktree != git.Untracked && (file.Staging != git.Unmodified || file.Worktree != git.Unmodified) {
+			changed[gitPath] = true
+		}
+	}
+	return r.localFiles(changed), nil
+}
+
+// ReadRef Returns the content of the files matching the glob in the revision, given
+// as REV:GLOB, by their paths. The glob is relative to the directory, and matches
+// the same names as filepath.Match does.
+func (r *Repo) ReadRef(spec string) (map[string]string, error) {
+	rev, glob, ok := strings.Cut(spec, ":")
+	if !ok || rev == "" || glob == "" {
+		return nil, fmt.Errorf("invalid ref %q, expected REV:GLOB", spec)
+	}
+	commit, err := r.commit(rev)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := filepath.Rel(r.wt.Filesystem.Root(), r.dir)
+	if err != nil {
+		return nil, err
+	}
+	pattern := path.Join(filepath.ToSlash(prefix), filepath.ToSlash(glob))
+	if _, err = path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid ref %q: %w", spec, err)
+	}
+
+	contents := make(map[string]string)
+	err = tree.Files().ForEach(func(file *object.File) error {
+		if matched, _ := path.Match(pattern, file.Name); !matched {
+			return nil
+		}
+		p, inside := r.local(file.Name)
+		if !inside {
+			return nil
+		}
+		content, contentErr := file.Contents()
+		if contentErr != nil {
+			return fmt.Errorf("could not read %s at %s: %w", file.Name, rev, contentErr)
+		}
+		contents[p] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no files match %s at %s", glob, rev)
+	}
+	return contents, nil
+}
+
+// commit Returns the commit the revision resolves to.
+func (r *Repo) commit(rev string) (*object.Commit, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %q: %w", rev, err)
+	}
+	return r.repo.CommitObject(*hash)
+}
@@ -0,0 +1,123 @@
+package vcs_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/vcs"
+)
+
+var _ = Describe("Selecting files", func() {
+	var (
+		dir  string
+		repo *git.Repository
+		wt   *git.Worktree
+	)
+
+	write := func(path, content string) {
+		path = filepath.Join(dir, path)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	}
+
+	commit := func(message string, paths ...string) {
+		for _, path := range paths {
+			_, err := wt.Add(path)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := wt.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "dev", Email: "dev@example.com", When: time.Now()},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = filepath.EvalSymlinks(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).NotTo(HaveOccurred())
+		wt, err = repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+
+		write("app/pvc.yaml", "storage: 10Gi\n")
+		write("app/pod.yaml", "kind: Pod\n")
+		write("app/old.yaml", "kind: ConfigMap\n")
+		write("docs/README.md", "# docs\n")
+		commit("Add the app", "app", "docs")
+		head, err := repo.Head()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = repo.CreateTag("v1.0", head.Hash(), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(wt.Checkout(&git.CheckoutOptions{
+			Branch: plumbing.NewBranchReferenceName("feature"), Create: true,
+		})).To(Succeed())
+		write("app/pvc.yaml", "storage: 100Gi\n")
+		write("docs/README.md", "# more docs\n")
+		_, err = wt.Remove("app/old.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		commit("Resize the PVC", "app/pvc.yaml", "docs/README.md")
+
+		write("app/service.yaml", "kind: Service\n")
+		_, err = wt.Add("app/service.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		write("app/pod.yaml", "kind: Pod\nmetadata: {}\n")
+		write("app/untracked.yaml", "kind: Secret\n")
+	})
+
+	It("lists the files a pull request would touch, relative to the directory", func() {
+		r, err := vcs.Open(filepath.Join(dir, "app"))
+		Expect(err).NotTo(HaveOccurred())
+
+		changed, err := r.ChangedSince("master")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(Equal([]string{"pod.yaml", "pvc.yaml", "service.yaml"}))
+
+		staged, err := r.Staged()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(staged).To(Equal([]string{"service.yaml"}))
+
+		tracked, err := r.Tracked()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracked).To(Equal(map[string]bool{"pod.yaml": true, "pvc.yaml": true, "service.yaml": true}))
+		rel, err := r.Rel(filepath.Join(dir, "app", "pvc.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracked).To(HaveKey(rel))
+
+		_, err = r.ChangedSince("missing")
+		Expect(err).To(MatchError(ContainSubstring(`could not resolve "missing"`)))
+	})
+
+	It("reads the files matching a glob at a revision", func() {
+		r, err := vcs.Open(filepath.Join(dir, "app"))
+		Expect(err).NotTo(HaveOccurred())
+
+		contents, err := r.ReadRef("v1.0:*.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(Equal(map[string]string{
+			"old.yaml": "kind: ConfigMap\n",
+			"pod.yaml": "kind: Pod\n",
+			"pvc.yaml": "storage: 10Gi\n",
+		}))
+
+		r, err = vcs.Open(dir)
+		Expect(err).NotTo(HaveOccurred())
+		contents, err = r.ReadRef("HEAD:app/p*.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contents).To(HaveKeyWithValue(filepath.Join("app", "pvc.yaml"), "storage: 100Gi\n"))
+		Expect(contents).To(HaveLen(2))
+
+		_, err = r.ReadRef("v1.0")
+		Expect(err).To(MatchError(ContainSubstring("expected REV:GLOB")))
+		_, err = r.ReadRef("v1.0:*.json")
+		Expect(err).To(MatchError(ContainSubstring("no files match")))
+	})
+})
@@ -0,0 +1,164 @@
+// vcs commits the files written by copilot-ops to the git repo they belong to.
+// It uses go-git, so no git binary needs to be installed.
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Name and email which sign commits when the git config names no user.
+const (
+	DefaultAuthorName  = "copilot-ops"
+	DefaultAuthorEmail = "copilot-ops@users.noreply.github.com"
+)
+
+// CommitOptions Describes the commit of the files which were written.
+type CommitOptions struct {
+	// Branch Is created from HEAD and checked out before committing, unless it is empty.
+	Branch string
+	// Message Is the commit message.
+	Message string
+	// Paths Are the files to stage and commit, relative to the working directory.
+	Paths []string
+}
+
+// Commit Stages exactly the given paths in the git repo containing dir, and commits
+// them, optionally on a new branch. The working tree is left untouched when switching
+// branches. It refuses to commit when other changes are already staged, since they
+// would end up in the commit. The hash of the commit is returned.
+func Commit(dir string, opts CommitOptions) (string, error) {
+	r, err := Open(dir)
+	if err != nil {
+		return "", err
+	}
+	repo, wt := r.repo, r.wt
+	paths, err := relativePaths(wt.Filesystem.Root(), opts.Paths)
+	if err != nil {
+		return "", err
+	}
+	if err = checkStaged(wt, paths); err != nil {
+		return "", err
+	}
+
+	if opts.Branch != "" {
+		if err = createBranch(repo, wt, opts.Branch); err != nil {
+			return "", err
+		}
+		log.Printf("switched to the new branch %q\n", opts.Branch)
+	}
+	for _, path := range paths {
+		if _, statErr := os.Stat(filepath.Join(wt.Filesystem.Root(), path)); errors.Is(statErr, fs.ErrNotExist) {
+			_, err = wt.Remove(path)
+		} else {
+			_, err = wt.Add(path)
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not stage %s: %w", path, err)
+		}
+	}
+
+	if err = checkChanged(wt, paths); err != nil {
+		return "", err
+	}
+
+	commit := &git.CommitOptions{}
+	if err = commit.Validate(repo); err != nil {
+		commit.Author = &object.Signature{Name: DefaultAuthorName, Email: DefaultAuthorEmail, When: time.Now()}
+	}
+	hash, err := wt.Commit(opts.Message, commit)
+	if err != nil {
+		return "", fmt.Errorf("could not commit: %w", err)
+	}
+	return hash.String(), nil
+}
+
+// relativePaths Returns the paths relative to the root of the working tree, in the
+// slash-separated form git uses, and fails for paths outside of it.
+func relativePaths(root string, paths []string) ([]string, error) {
+	relative := make([]string, 0, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		// the root is resolved by go-git, so the path is resolved too before comparing them
+		if dir, evalErr := filepath.EvalSymlinks(filepath.Dir(abs)); evalErr == nil {
+			abs = filepath.Join(dir, filepath.Base(abs))
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is outside of the git repo at %s", path, root)
+		}
+		relative = append(relative, filepath.ToSlash(rel))
+	}
+	sort.Strings(relative)
+	return relative, nil
+}
+
+// checkStaged Returns an error when files other than the given paths have staged changes.
+func checkStaged(wt *git.Worktree, paths []string) error {
+	status, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("could not read the status of the git repo: %w", err)
+	}
+	ours := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		ours[path] = true
+	}
+	var staged []string
+	for path, file := range status {
+		if !ours[path] && file.Staging != git.Unmodified && file.Staging != git.Untracked {
+			staged = append(staged, path)
+		}
+	}
+	if len(staged) > 0 {
+		sort.Strings(staged)
+		return fmt.Errorf("other changes are staged, commit or unstage them first: %s", strings.Join(staged, ", "))
+	}
+	return nil
+}
+
+// checkChanged Returns an error when none of the staged paths differs from HEAD,
+// since the commit would be empty.
+func checkChanged(wt *git.Worktree, paths []string) error {
+	status, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("could not read the status of the git repo: %w", err)
+	}
+	for _, path := range paths {
+		// files which are unchanged are missing from the status
+		if file, ok := status[path]; ok && file.Staging != git.Unmodified {
+			return nil
+		}
+	}
+	return errors.New("nothing to commit, the written files are unchanged")
+}
+
+// createBranch Creates the branch from HEAD and checks it out, keeping the index and
+// the working tree as they are. In a repo without commits, the branch becomes the one
+// the first commit is made on.
+func createBranch(repo *git.Repository, wt *git.Worktree, name string) error {
+	ref := plumbing.NewBranchReferenceName(name)
+	if _, err := repo.Reference(ref, false); err == nil {
+		return fmt.Errorf("the branch %q already exists", name)
+	}
+	if _, err := repo.Head(); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, ref))
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: ref, Create: true, Keep: true}); err != nil {
+		return fmt.Errorf("could not create the branch %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package vcs_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-et/copilot-ops/pkg/vcs"
+)
+
+var _ = Describe("VCS", func() {
+	var (
+		dir  string
+		repo *git.Repository
+		wt   *git.Worktree
+	)
+
+	write := func(path, content string) {
+		path = filepath.Join(dir, path)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = filepath.EvalSymlinks(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).NotTo(HaveOccurred())
+		wt, err = repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+
+		write("app/pvc.yaml", "storage: 10Gi\n")
+		write("app/pod.yaml", "kind: Pod\n")
+		_, err = wt.Add("app")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = wt.Commit("Add the app", &git.CommitOptions{
+			Author: &object.Signature{Name: "dev", Email: "dev@example.com", When: time.Now()},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	committed := func(hash string) *object.Commit {
+		commit, err := repo.CommitObject(plumbing.NewHash(hash))
+		Expect(err).NotTo(HaveOccurred())
+		return commit
+	}
+
+	It("commits exactly the given files on a new branch", func() {
+		write("app/pvc.yaml", "storage: 100Gi\n")
+		write("app/service.yaml", "kind: Service\n")
+		write("app/pod.yaml", "kind: Pod\nmetadata: {}\n")
+
+		hash, err := vcs.Commit(filepath.Join(dir, "app"), vcs.CommitOptions{
+			Branch:  "copilot-ops/pvc",
+			Message: "Increase the size to 100Gi",
+			Paths:   []string{filepath.Join(dir, "app", "pvc.yaml"), filepath.Join(dir, "app", "service.yaml")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		head, err := repo.Head()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(head.Name().Short()).To(Equal("copilot-ops/pvc"))
+		Expect(head.Hash().String()).To(Equal(hash))
+
+		commit := committed(hash)
+		Expect(commit.Message).To(Equal("Increase the size to 100Gi"))
+		stats, err := commit.Stats()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(HaveLen(2))
+		Expect([]string{stats[0].Name, stats[1].Name}).To(ConsistOf("app/pvc.yaml", "app/service.yaml"))
+
+		// the file which was not written stays modified in the working tree
+		status, err := wt.Status()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.File("app/pod.yaml").Worktree).To(Equal(git.Modified))
+		Expect(status.IsClean()).To(BeFalse())
+	})
+
+	It("commits on the current branch, signed by the configured user or a default one", func() {
+		write("app/pvc.yaml", "storage: 100Gi\n")
+		hash, err := vcs.Commit(dir, vcs.CommitOptions{
+			Message: "Increase the size",
+			Paths:   []string{filepath.Join(dir, "app", "pvc.yaml")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(committed(hash).Author.Name).NotTo(BeEmpty())
+
+		head, err := repo.Head()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(head.Name()).To(Equal(plumbing.NewBranchReferenceName("master")))
+	})
+
+	It("refuses to commit other staged changes, existing branches, and unchanged files", func() {
+		write("app/pod.yaml", "kind: Pod\nmetadata: {}\n")
+		_, err := wt.Add("app/pod.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		write("app/pvc.yaml", "storage: 100Gi\n")
+		pvc := filepath.Join(dir, "app", "pvc.yaml")
+
+		_, err = vcs.Commit(dir, vcs.CommitOptions{Message: "m", Paths: []string{pvc}})
+		Expect(err).To(MatchError(ContainSubstring("other changes are staged")))
+		Expect(err).To(MatchError(ContainSubstring("app/pod.yaml")))
+
+		pod := filepath.Join(dir, "app", "pod.yaml")
+		_, err = vcs.Commit(dir, vcs.CommitOptions{Branch: "master", Message: "m", Paths: []string{pvc, pod}})
+		Expect(err).To(MatchError(ContainSubstring("already exists")))
+
+		_, err = vcs.Commit(dir, vcs.CommitOptions{Message: "m", Paths: []string{"/etc/hosts"}})
+		Expect(err).To(MatchError(ContainSubstring("outside of the git repo")))
+
+		write("app/pod.yaml", "kind: Pod\n")
+		write("app/pvc.yaml", "storage: 10Gi\n")
+		_, err = vcs.Commit(dir, vcs.CommitOptions{Message: "m", Paths: []string{pvc, pod}})
+		Expect(err).To(MatchError(ContainSubstring("nothing to commit")))
+	})
+})